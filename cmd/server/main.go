@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -12,8 +13,10 @@ import (
 	"go-vibe-friend/internal/api"
 	"go-vibe-friend/internal/config"
 	"go-vibe-friend/internal/models"
+	"go-vibe-friend/internal/service"
 	"go-vibe-friend/internal/store"
 	"go-vibe-friend/internal/utils"
+	"go-vibe-friend/internal/worker"
 
 	"github.com/gin-gonic/gin"
 	"github.com/minio/minio-go/v7"
@@ -34,6 +37,18 @@ func main() {
 	// Set Gin mode
 	gin.SetMode(cfg.Server.Mode)
 
+	// Load JWT signing keys
+	keySet, err := utils.LoadKeySet(cfg.JWT)
+	if errors.Is(err, utils.ErrNoJWTKeys) && cfg.Server.Mode != gin.ReleaseMode {
+		logger.Warn("No JWT keys configured, generating a temporary signing key; tokens will not survive a restart")
+		keySet, err = utils.NewEphemeralKeySet(cfg.JWT)
+	}
+	if err != nil {
+		logger.Fatal(fmt.Sprintf("Failed to load JWT keys: %v", err))
+	}
+	utils.SetKeySet(keySet)
+	logger.Info(fmt.Sprintf("JWT tokens signed with key %s (%s)", keySet.SigningKeyID(), keySet.SigningAlgorithm()))
+
 	// Initialize store (database + Redis)
 	storeManager, err := store.NewStore(cfg)
 	if err != nil {
@@ -63,8 +78,50 @@ func main() {
 		logger.Fatal(fmt.Sprintf("Failed to create default admin: %v", err))
 	}
 
+	// Initialize job service and background workers. Services used by both the
+	// workers and the API handlers are created once here and shared
+	jobEvents := service.NewJobEventService(storeManager.Notify)
+	concurrencyService := service.NewConcurrencyService(storeManager.Concurrency, storeManager.Job)
+	jobService := service.NewJobService(storeManager.Job, storeManager.GetJobQueue(), jobEvents, concurrencyService)
+	registry := worker.NewRegistry()
+	exportStorage := service.NewExportStorage(storeManager.Export, minioClient, cfg)
+	exportService := service.NewExportService(storeManager.User, storeManager.Job, storeManager.File, storeManager.Email, storeManager.Permission, jobService, exportStorage)
+	worker.RegisterMaintenanceHandlers(registry, storeManager, exportService)
+	worker.RegisterExportHandlers(registry, exportService)
+	emailService := service.NewEmailService(storeManager.Email, "", "", "", "", "", "")
+	importService := service.NewUserImportService(storeManager.User, storeManager.Permission, emailService, jobService, minioClient, cfg)
+	worker.RegisterImportHandlers(registry, importService)
+	fileService := service.NewFileService(storeManager.File, minioClient, cfg)
+	personalDataService := service.NewPersonalDataService(storeManager.User, storeManager.Profile, storeManager.GetSessionStore(), storeManager.File, storeManager.Email, storeManager.Job, fileService, exportStorage, jobService)
+	worker.RegisterPersonalDataHandlers(registry, personalDataService)
+
+	var workerPool *worker.Pool
+	if !cfg.Worker.Enabled {
+		logger.Info("Job workers disabled by configuration")
+	} else {
+		workerPool = worker.NewPool(storeManager.GetJobQueue(), jobService, fileService, registry, cfg.Worker)
+		workerPool.Start()
+		logger.Info(fmt.Sprintf("Job workers started for job types: %v", registry.JobTypes()))
+	}
+
+	// Initialize recurring job schedules
+	scheduleService := service.NewScheduleService(storeManager.Recurring, jobService)
+	if err := scheduleService.EnsureSchedules(worker.MaintenanceSchedules()); err != nil {
+		logger.Error(fmt.Sprintf("Failed to create default schedules: %v", err))
+	}
+
+	var scheduler *worker.Scheduler
+	if cfg.Scheduler.Enabled {
+		scheduler = worker.NewScheduler(scheduleService, storeManager.Lock, cfg.Scheduler)
+		scheduler.Start()
+		logger.Info("Recurring job scheduler started")
+	} else {
+		logger.Info("Recurring job scheduler disabled by configuration")
+	}
+
 	// Setup router with store and config
-	router := api.SetupRouter(storeManager, cfg, minioClient)
+	router := api.SetupRouter(storeManager, cfg, minioClient, jobService, scheduleService, concurrencyService,
+		fileService, emailService, exportService, importService, personalDataService)
 
 	// Create HTTP server
 	server := &http.Server{
@@ -94,6 +151,19 @@ func main() {
 		logger.Fatal(fmt.Sprintf("Server forced to shutdown: %v", err))
 	}
 
+	if scheduler != nil {
+		scheduler.Stop()
+	}
+
+	// Stop job workers, letting running jobs finish within the shutdown timeout
+	if workerPool != nil {
+		workerCtx, workerCancel := context.WithTimeout(context.Background(), cfg.Worker.ShutdownTimeout)
+		defer workerCancel()
+		if err := workerPool.Stop(workerCtx); err != nil {
+			logger.Error(fmt.Sprintf("Job workers forced to stop: %v", err))
+		}
+	}
+
 	logger.Info("Server exited")
 }
 
@@ -1,6 +1,8 @@
 package admin
 
 import (
+	"errors"
+	"io"
 	"net/http"
 
 	"go-vibe-friend/internal/service"
@@ -9,15 +11,25 @@ import (
 )
 
 type AuthHandler struct {
-	authService *service.AuthService
+	authService      *service.AuthService
+	twoFactorService *service.TwoFactorService
+	webAuthnService  *service.WebAuthnService
 }
 
-func NewAuthHandler(authService *service.AuthService) *AuthHandler {
+func NewAuthHandler(authService *service.AuthService, twoFactorService *service.TwoFactorService, webAuthnService *service.WebAuthnService) *AuthHandler {
 	return &AuthHandler{
-		authService: authService,
+		authService:      authService,
+		twoFactorService: twoFactorService,
+		webAuthnService:  webAuthnService,
 	}
 }
 
+// TwoFactorLoginRequest is the second login step, code is a TOTP code or a recovery code
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+}
+
 func (h *AuthHandler) Register(c *gin.Context) {
 	var req service.RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -31,6 +43,21 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
+	// The token is withheld when the new user's role requires two-factor authentication
+	challenge, err := h.twoFactorService.BeginLogin(&response.User)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start two-factor login"})
+		return
+	}
+	if challenge != nil {
+		c.JSON(http.StatusCreated, gin.H{
+			"user":                response.User,
+			"two_factor_required": true,
+			"challenge":           challenge,
+		})
+		return
+	}
+
 	c.JSON(http.StatusCreated, response)
 }
 
@@ -41,11 +68,156 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	response, err := h.authService.Login(&req)
+	user, err := h.authService.ValidateUser(req.Email, req.Password)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
 	}
+	if user.Status != "active" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "User account is disabled"})
+		return
+	}
+
+	// Users with two-factor authentication get a challenge instead of a token
+	challenge, err := h.twoFactorService.BeginLogin(user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start two-factor login"})
+		return
+	}
+	if challenge != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"two_factor_required": true,
+			"challenge":           challenge,
+		})
+		return
+	}
+
+	response, err := h.authService.LoginUser(user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// LoginTwoFactor completes a login with the challenge token and a TOTP or recovery code
+func (h *AuthHandler) LoginTwoFactor(c *gin.Context) {
+	var req TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, recoveryCodes, err := h.twoFactorService.CompleteLogin(req.ChallengeToken, req.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidTwoFactorCode):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid two-factor code"})
+		case errors.Is(err, service.ErrInvalidChallenge), errors.Is(err, service.ErrTwoFactorNotEnrolled):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Login challenge is invalid or expired, please log in again"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify two-factor code"})
+		}
+		return
+	}
+	// The account may have been disabled while the challenge was pending
+	if user.Status != "active" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "User account is disabled"})
+		return
+	}
+
+	response, err := h.authService.LoginUser(user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	response.RecoveryCodes = recoveryCodes
+
+	c.JSON(http.StatusOK, response)
+}
+
+// SetupTwoFactor generates a TOTP secret during login for users whose role
+// requires two-factor authentication but who have not enrolled yet
+func (h *AuthHandler) SetupTwoFactor(c *gin.Context) {
+	var req struct {
+		ChallengeToken string `json:"challenge_token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	enrollment, err := h.twoFactorService.EnrollWithChallenge(req.ChallengeToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidChallenge):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Login challenge is invalid or expired, please log in again"})
+		case errors.Is(err, service.ErrTwoFactorAlreadyEnabled):
+			c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate two-factor secret"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// BeginPasskeyLogin returns the options for navigator.credentials.get. The
+// email is optional; without it the browser offers the discoverable passkeys.
+func (h *AuthHandler) BeginPasskeyLogin(c *gin.Context) {
+	var req struct {
+		Email string `json:"email" binding:"omitempty,email"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	options, err := h.webAuthnService.BeginLogin(req.Email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start passkey login"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"publicKey": options})
+}
+
+// FinishPasskeyLogin verifies the passkey assertion and issues a token
+func (h *AuthHandler) FinishPasskeyLogin(c *gin.Context) {
+	var req struct {
+		Credential service.AssertionCredential `json:"credential"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.webAuthnService.FinishLogin(&req.Credential, c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidWebAuthnChallenge):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Login challenge is invalid or expired, please try again"})
+		case errors.Is(err, service.ErrWebAuthnVerificationFailed):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Passkey verification failed"})
+		case errors.Is(err, service.ErrPasskeyCloned):
+			c.JSON(http.StatusForbidden, gin.H{"error": "This passkey may have been cloned and has been disabled"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify passkey"})
+		}
+		return
+	}
+	if user.Status != "active" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "User account is disabled"})
+		return
+	}
+
+	response, err := h.authService.LoginUser(user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
 	c.JSON(http.StatusOK, response)
 }
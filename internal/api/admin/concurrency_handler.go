@@ -0,0 +1,82 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"go-vibe-friend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ConcurrencyHandler struct {
+	concurrencyService *service.ConcurrencyService
+}
+
+func NewConcurrencyHandler(concurrencyService *service.ConcurrencyService) *ConcurrencyHandler {
+	return &ConcurrencyHandler{
+		concurrencyService: concurrencyService,
+	}
+}
+
+// GetConcurrency 获取任务并发上限以及每个用户和任务类型当前运行中的任务数量
+func (h *ConcurrencyHandler) GetConcurrency(c *gin.Context) {
+	limits, err := h.concurrencyService.ListLimits()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch concurrency limits"})
+		return
+	}
+
+	usage, err := h.concurrencyService.Usage()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch running jobs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"limits":  limits,
+		"running": usage,
+	})
+}
+
+// SetConcurrencyLimit 创建或更新某个用户或任务类型的并发上限
+func (h *ConcurrencyHandler) SetConcurrencyLimit(c *gin.Context) {
+	var req service.ConcurrencyLimitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	limit, err := h.concurrencyService.SetLimit(&req)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidConcurrencyLimit) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save concurrency limit"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"limit": limit})
+}
+
+// DeleteConcurrencyLimit 删除并发上限，恢复为默认值或不限制
+func (h *ConcurrencyHandler) DeleteConcurrencyLimit(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit ID"})
+		return
+	}
+
+	if err := h.concurrencyService.DeleteLimit(uint(id)); err != nil {
+		if errors.Is(err, service.ErrConcurrencyLimitNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Concurrency limit not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete concurrency limit"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Concurrency limit deleted successfully"})
+}
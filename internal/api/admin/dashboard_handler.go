@@ -7,6 +7,7 @@ import (
 	"strconv"
 	"time"
 
+	"go-vibe-friend/internal/service"
 	"go-vibe-friend/internal/store"
 
 	"github.com/gin-gonic/gin"
@@ -14,18 +15,20 @@ import (
 )
 
 type DashboardHandler struct {
-	userStore *store.UserStore
-	jobStore  *store.JobStore
-	db        *gorm.DB
-	startTime time.Time
+	userStore  *store.UserStore
+	jobStore   *store.JobStore
+	jobService *service.JobService
+	db         *gorm.DB
+	startTime  time.Time
 }
 
-func NewDashboardHandler(userStore *store.UserStore, jobStore *store.JobStore, db *gorm.DB) *DashboardHandler {
+func NewDashboardHandler(userStore *store.UserStore, jobStore *store.JobStore, jobService *service.JobService, db *gorm.DB) *DashboardHandler {
 	return &DashboardHandler{
-		userStore: userStore,
-		jobStore:  jobStore,
-		db:        db,
-		startTime: time.Now(),
+		userStore:  userStore,
+		jobStore:   jobStore,
+		jobService: jobService,
+		db:         db,
+		startTime:  time.Now(),
 	}
 }
 
@@ -249,9 +252,11 @@ func (h *DashboardHandler) GetSystemHealth(c *gin.Context) {
 	memoryTotalMB := float64(mem.Sys) / 1024 / 1024
 	memoryUsagePercent := (memoryUsedMB / memoryTotalMB) * 100
 
-	// 获取队列深度（待处理的Job数量）
+	// 获取队列深度（所有队列中等待执行的任务数量），队列不可用时使用待处理的Job数量
 	queueDepth := int64(0)
-	if depth, err := h.jobStore.GetJobCountByStatus("pending"); err == nil {
+	if depth, err := h.jobService.QueueDepth(); err == nil {
+		queueDepth = depth
+	} else if depth, err := h.jobStore.GetJobCountByStatus("pending"); err == nil {
 		queueDepth = depth
 	}
 
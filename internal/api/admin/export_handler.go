@@ -1,6 +1,8 @@
 package admin
 
 import (
+	"errors"
+	"log"
 	"net/http"
 	"strconv"
 
@@ -19,7 +21,7 @@ func NewExportHandler(exportService *service.ExportService) *ExportHandler {
 	}
 }
 
-// ExportData 导出数据
+// ExportData 提交后台导出任务，立即返回任务ID
 func (h *ExportHandler) ExportData(c *gin.Context) {
 	var req service.ExportRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -29,86 +31,194 @@ func (h *ExportHandler) ExportData(c *gin.Context) {
 		return
 	}
 
-	// 验证数据类型
-	validTypes := []string{"users", "jobs", "files", "emails", "permissions", "system_report"}
-	validType := false
-	for _, t := range validTypes {
-		if req.DataType == t {
-			validType = true
-			break
-		}
+	h.submitExport(c, &req, "导出任务已提交")
+}
+
+// submitExport 提交导出任务并返回 202 和任务状态地址
+func (h *ExportHandler) submitExport(c *gin.Context, req *service.ExportRequest, message string) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
 	}
-	if !validType {
+
+	job, err := h.exportService.SubmitExport(userID.(uint), req)
+	if err != nil {
+		respondExportError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":    message,
+		"job_id":     job.ID,
+		"status":     job.Status,
+		"status_url": "/api/admin/export/jobs/" + strconv.FormatUint(uint64(job.ID), 10),
+	})
+}
+
+// StreamExport 将导出直接作为响应体返回，不生成文件，适合大数据量导出
+func (h *ExportHandler) StreamExport(c *gin.Context) {
+	var req service.ExportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid data type",
+			"error": "Invalid request parameters",
 		})
 		return
 	}
+	if err := service.ValidateExportRequest(&req); err != nil {
+		respondExportError(c, err)
+		return
+	}
+
+	c.Header("Content-Type", service.ExportContentType(req.Format))
+	c.Header("Content-Disposition", "attachment; filename=\""+service.ExportFileName(&req)+"\"")
+	c.Status(http.StatusOK)
 
-	// 验证格式
-	validFormats := []string{"csv", "json"}
-	validFormat := false
-	for _, f := range validFormats {
-		if req.Format == f {
-			validFormat = true
-			break
+	count, err := h.exportService.StreamExport(c.Request.Context(), c.Writer, &req)
+	if err != nil {
+		if !c.Writer.Written() {
+			// 还未写出任何内容，去掉附件相关的头，按 JSON 返回错误
+			c.Writer.Header().Del("Content-Type")
+			c.Writer.Header().Del("Content-Disposition")
+			respondExportError(c, err)
+			return
 		}
+		// 响应已开始发送，只能断开连接，让客户端知道文件不完整
+		log.Printf("Streaming %s export failed after %d bytes: %v", req.DataType, c.Writer.Size(), err)
+		c.Abort()
+		if conn, _, hijackErr := c.Writer.Hijack(); hijackErr == nil {
+			conn.Close()
+		}
+		return
 	}
-	if !validFormat {
+	log.Printf("Streamed %d %s records as %s", count, req.DataType, req.Format)
+}
+
+// respondExportError 将导出错误转换为响应
+func respondExportError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidExportType):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid data type",
+		})
+	case errors.Is(err, service.ErrInvalidExportFormat):
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "Invalid format",
 		})
-		return
+	case errors.Is(err, service.ErrInvalidExportFilter),
+		errors.Is(err, service.ErrInvalidExportColumn),
+		errors.Is(err, service.ErrInvalidDateRange):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+	case errors.Is(err, service.ErrQueueUnavailable):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Job queue is not available"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
 	}
+}
 
-	// 执行导出
-	result, err := h.exportService.ExportData(&req)
+// GetExportJob 查询导出任务的状态和进度，完成后返回下载链接
+func (h *ExportHandler) GetExportJob(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": err.Error(),
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid job ID",
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message":      "导出成功",
-		"file_name":    result.FileName,
-		"file_size":    result.FileSize,
-		"record_count": result.RecordCount,
-		"created_at":   result.CreatedAt,
-		"expires_at":   result.ExpiresAt,
-		"download_url": "/api/admin/export/download/" + result.FileName,
-	})
+	job, result, err := h.exportService.GetExportJob(uint(id))
+	if err != nil {
+		if errors.Is(err, service.ErrJobNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Export job not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get export job"})
+		return
+	}
+
+	response := gin.H{
+		"job_id":   job.ID,
+		"status":   job.Status,
+		"progress": job.Progress,
+		"error":    job.ErrorMsg,
+	}
+	if result != nil {
+		response["file_name"] = result.FileName
+		response["file_size"] = result.FileSize
+		response["record_count"] = result.RecordCount
+		response["created_at"] = result.CreatedAt
+		response["expires_at"] = result.ExpiresAt
+		response["export_id"] = result.ExportID
+		response["download_url"] = "/api/admin/export/download/" + strconv.FormatUint(uint64(result.ExportID), 10)
+	}
+	c.JSON(http.StatusOK, response)
 }
 
-// DownloadExport 下载导出文件
+// DownloadExport 获取导出文件的限时下载链接，链接直接从对象存储下载文件
 func (h *ExportHandler) DownloadExport(c *gin.Context) {
-	fileName := c.Param("filename")
-	if fileName == "" {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "文件名不能为空",
+			"error": "Invalid export ID",
 		})
 		return
 	}
 
-	filePath, err := h.exportService.GetExportFile(fileName)
+	file, link, linkExpiresAt, err := h.exportService.GetExportDownload(c.Request.Context(), uint(id))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "文件不存在或已过期",
-		})
+		switch {
+		case errors.Is(err, service.ErrExportNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "文件不存在或已过期"})
+		case errors.Is(err, service.ErrExportExpired):
+			c.JSON(http.StatusGone, gin.H{"error": "文件已过期"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
 		return
 	}
 
-	// 设置响应头
-	c.Header("Content-Type", "application/octet-stream")
-	c.Header("Content-Disposition", "attachment; filename=\""+fileName+"\"")
-	c.Header("Content-Transfer-Encoding", "binary")
+	c.JSON(http.StatusOK, gin.H{
+		"export_id":               file.ID,
+		"file_name":               file.FileName,
+		"file_size":               file.FileSize,
+		"expires_at":              file.ExpiresAt,
+		"download_url":            link,
+		"download_url_expires_at": linkExpiresAt,
+	})
+}
+
+// ListExports 分页获取导出文件记录
+func (h *ExportHandler) ListExports(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	files, total, err := h.exportService.ListExports(limit, (page-1)*limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to list exports",
+		})
+		return
+	}
 
-	// 返回文件
-	c.File(filePath)
+	c.JSON(http.StatusOK, gin.H{
+		"exports": files,
+		"total":   total,
+		"page":    page,
+		"limit":   limit,
+	})
 }
 
-// ExportUserData 导出用户数据
+// ExportUserData 提交用户数据导出任务
 func (h *ExportHandler) ExportUserData(c *gin.Context) {
 	userIDStr := c.Param("id")
 	userID, err := strconv.ParseUint(userIDStr, 10, 32)
@@ -130,27 +240,10 @@ func (h *ExportHandler) ExportUserData(c *gin.Context) {
 		UserID:   &uid,
 	}
 
-	// 执行导出
-	result, err := h.exportService.ExportData(req)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": err.Error(),
-		})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"message":      "用户数据导出成功",
-		"file_name":    result.FileName,
-		"file_size":    result.FileSize,
-		"record_count": result.RecordCount,
-		"created_at":   result.CreatedAt,
-		"expires_at":   result.ExpiresAt,
-		"download_url": "/api/admin/export/download/" + result.FileName,
-	})
+	h.submitExport(c, req, "用户数据导出任务已提交")
 }
 
-// ExportSystemReport 导出系统报告
+// ExportSystemReport 提交系统报告导出任务
 func (h *ExportHandler) ExportSystemReport(c *gin.Context) {
 	format := c.DefaultQuery("format", "json")
 
@@ -159,24 +252,7 @@ func (h *ExportHandler) ExportSystemReport(c *gin.Context) {
 		Format:   format,
 	}
 
-	// 执行导出
-	result, err := h.exportService.ExportData(req)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": err.Error(),
-		})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"message":      "系统报告导出成功",
-		"file_name":    result.FileName,
-		"file_size":    result.FileSize,
-		"record_count": result.RecordCount,
-		"created_at":   result.CreatedAt,
-		"expires_at":   result.ExpiresAt,
-		"download_url": "/api/admin/export/download/" + result.FileName,
-	})
+	h.submitExport(c, req, "系统报告导出任务已提交")
 }
 
 // GetExportTypes 获取支持的导出类型
@@ -186,40 +262,51 @@ func (h *ExportHandler) GetExportTypes(c *gin.Context) {
 			"type":        "users",
 			"name":        "用户数据",
 			"description": "导出用户基本信息",
-			"formats":     []string{"csv", "json"},
+			"formats":     []string{"csv", "json", "jsonl", "xlsx"},
 		},
 		{
 			"type":        "jobs",
 			"name":        "任务数据",
 			"description": "导出生成任务信息",
-			"formats":     []string{"csv", "json"},
+			"formats":     []string{"csv", "json", "jsonl", "xlsx"},
 		},
 		{
 			"type":        "files",
 			"name":        "文件数据",
 			"description": "导出文件管理信息",
-			"formats":     []string{"csv", "json"},
+			"formats":     []string{"csv", "json", "jsonl", "xlsx"},
 		},
 		{
 			"type":        "emails",
 			"name":        "邮件数据",
 			"description": "导出邮件发送日志",
-			"formats":     []string{"csv", "json"},
+			"formats":     []string{"csv", "json", "jsonl", "xlsx"},
 		},
 		{
 			"type":        "permissions",
 			"name":        "权限数据",
 			"description": "导出权限配置信息",
-			"formats":     []string{"json"},
+			"formats":     []string{"json", "jsonl"},
 		},
 		{
 			"type":        "system_report",
 			"name":        "系统报告",
 			"description": "导出系统整体统计报告",
-			"formats":     []string{"json"},
+			"formats":     []string{"json", "xlsx"},
 		},
 	}
 
+	// 可选的列和筛选字段
+	for _, t := range types {
+		dataType := t["type"].(string)
+		if columns := service.ExportColumns(dataType); columns != nil {
+			t["columns"] = columns
+		}
+		if filters := service.ExportFilters(dataType); filters != nil {
+			t["filters"] = filters
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"export_types": types,
 	})
@@ -258,15 +345,17 @@ func (h *ExportHandler) GetExportTemplates(c *gin.Context) {
 
 // CleanupExpiredExports 清理过期的导出文件
 func (h *ExportHandler) CleanupExpiredExports(c *gin.Context) {
-	err := h.exportService.CleanupExpiredExports()
+	removed, err := h.exportService.CleanupExpiredExports(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "清理失败: " + err.Error(),
+			"error":   "清理失败: " + err.Error(),
+			"removed": removed,
 		})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "过期文件清理完成",
+		"removed": removed,
 	})
 }
\ No newline at end of file
@@ -0,0 +1,178 @@
+package admin
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"go-vibe-friend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxImportFileSize 导入文件的最大大小
+const maxImportFileSize = 20 << 20
+
+type ImportHandler struct {
+	importService *service.UserImportService
+}
+
+func NewImportHandler(importService *service.UserImportService) *ImportHandler {
+	return &ImportHandler{
+		importService: importService,
+	}
+}
+
+// ImportUsers 从 CSV、JSON 或 JSON Lines 文件批量导入用户。
+// 表单字段：file 导入文件，format 文件格式（默认按扩展名判断），
+// dry_run 只校验不导入，send_invitations 导入后发送邀请邮件。
+// 较大的文件作为后台任务处理，返回 202 和任务状态地址
+func (h *ImportHandler) ImportUsers(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Import file is required",
+		})
+		return
+	}
+	if fileHeader.Size > maxImportFileSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error": "Import file is too large",
+		})
+		return
+	}
+
+	opts := &service.UserImportOptions{
+		Format: formValue(c, "format"),
+	}
+	if opts.Format == "" {
+		opts.Format = service.ImportFormatFromFileName(fileHeader.Filename)
+	}
+	if opts.DryRun, err = formBool(c, "dry_run"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dry_run"})
+		return
+	}
+	if opts.SendInvitations, err = formBool(c, "send_invitations"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid send_invitations"})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read import file"})
+		return
+	}
+	data, err := io.ReadAll(io.LimitReader(file, maxImportFileSize))
+	file.Close()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read import file"})
+		return
+	}
+
+	report, job, err := h.importService.ImportUsers(userID.(uint), opts, data)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidImportFormat):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid format",
+			})
+		case errors.Is(err, service.ErrInvalidImportFile):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+		case errors.Is(err, service.ErrQueueUnavailable):
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Job queue is not available"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+		}
+		return
+	}
+
+	if job != nil {
+		c.JSON(http.StatusAccepted, gin.H{
+			"message":    "导入任务已提交",
+			"job_id":     job.ID,
+			"status":     job.Status,
+			"status_url": "/api/admin/import/jobs/" + strconv.FormatUint(uint64(job.ID), 10),
+		})
+		return
+	}
+
+	if report.Invalid > 0 && !report.DryRun {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":  "Import contains invalid rows, no users were imported",
+			"report": report,
+		})
+		return
+	}
+
+	message := "用户导入完成"
+	if report.DryRun {
+		message = "导入校验完成"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"report":  report,
+	})
+}
+
+// GetImportJob 查询导入任务的状态和进度，完成后返回导入结果
+func (h *ImportHandler) GetImportJob(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid job ID",
+		})
+		return
+	}
+
+	job, report, err := h.importService.GetImportJob(uint(id))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrJobNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Import job not found"})
+		case errors.Is(err, service.ErrQueueUnavailable):
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Job queue is not available"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get import job"})
+		}
+		return
+	}
+
+	response := gin.H{
+		"job_id":   job.ID,
+		"status":   job.Status,
+		"progress": job.Progress,
+		"error":    job.ErrorMsg,
+	}
+	if report != nil {
+		response["report"] = report
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// formValue 读取表单字段，表单中没有时读取查询参数
+func formValue(c *gin.Context, key string) string {
+	if value, ok := c.GetPostForm(key); ok {
+		return value
+	}
+	return c.Query(key)
+}
+
+// formBool 读取布尔类型的表单字段，未提供时为 false
+func formBool(c *gin.Context, key string) (bool, error) {
+	value := formValue(c, key)
+	if value == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
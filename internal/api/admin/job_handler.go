@@ -1,29 +1,42 @@
 package admin
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
+	"go-vibe-friend/internal/api/sse"
 	"go-vibe-friend/internal/models"
+	"go-vibe-friend/internal/service"
 	"go-vibe-friend/internal/store"
 
 	"github.com/gin-gonic/gin"
 )
 
 type JobHandler struct {
-	jobStore *store.JobStore
+	jobStore   *store.JobStore
+	jobService *service.JobService
 }
 
-func NewJobHandler(jobStore *store.JobStore) *JobHandler {
+func NewJobHandler(jobStore *store.JobStore, jobService *service.JobService) *JobHandler {
 	return &JobHandler{
-		jobStore: jobStore,
+		jobStore:   jobStore,
+		jobService: jobService,
 	}
 }
 
 type CreateJobRequest struct {
-	Title       string `json:"title" binding:"required"`
-	Description string `json:"description"`
-	JobType     string `json:"job_type" binding:"required"`
+	Title        string                 `json:"title" binding:"required"`
+	Description  string                 `json:"description"`
+	JobType      string                 `json:"job_type" binding:"required"`
+	Queue        string                 `json:"queue"`
+	Priority     int                    `json:"priority" binding:"min=0,max=10"`
+	MaxRetries   int                    `json:"max_retries" binding:"min=0"`
+	Payload      map[string]interface{} `json:"payload"`
+	RunAt        *time.Time             `json:"run_at"`                        // 计划执行时间（RFC3339）
+	DelaySeconds int                    `json:"delay_seconds" binding:"min=0"` // 延迟执行秒数，与 run_at 二选一
+	DependsOn    []uint                 `json:"depends_on"`                    // 依赖的任务ID，全部成功完成后才会执行
 }
 
 func (h *JobHandler) CreateJob(c *gin.Context) {
@@ -33,22 +46,43 @@ func (h *JobHandler) CreateJob(c *gin.Context) {
 		return
 	}
 
+	if req.RunAt != nil && req.DelaySeconds > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "run_at and delay_seconds cannot be used together"})
+		return
+	}
+	runAt := req.RunAt
+	if req.DelaySeconds > 0 {
+		t := time.Now().Add(time.Duration(req.DelaySeconds) * time.Second)
+		runAt = &t
+	}
+
 	userID, exists := c.Get("user_id")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
 	}
 
-	job := &models.Job{
+	job, err := h.jobService.SubmitJob(&service.SubmitJobRequest{
 		UserID:      userID.(uint),
 		Title:       req.Title,
 		Description: req.Description,
-		Status:      "pending",
 		JobType:     req.JobType,
-		Progress:    0,
-	}
-
-	if err := h.jobStore.CreateJob(job); err != nil {
+		Queue:       req.Queue,
+		Priority:    req.Priority,
+		MaxRetries:  req.MaxRetries,
+		Payload:     req.Payload,
+		RunAt:       runAt,
+		DependsOn:   req.DependsOn,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrQueueUnavailable) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Job queue is not available"})
+			return
+		}
+		if errors.Is(err, service.ErrInvalidDependency) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create job"})
 		return
 	}
@@ -235,4 +269,88 @@ func (h *JobHandler) CreateSampleJobs(c *gin.Context) {
 		"message": "Sample jobs created",
 		"created": createdJobs,
 	})
-}
\ No newline at end of file
+}
+
+// CancelJob 取消等待中或运行中的任务
+func (h *JobHandler) CancelJob(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+		return
+	}
+
+	job, err := h.jobService.CancelJob(uint(id))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrJobNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		case errors.Is(err, service.ErrJobNotActive):
+			c.JSON(http.StatusConflict, gin.H{"error": "Job has already finished"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel job"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Job cancelled",
+		"job":     job,
+	})
+}
+
+// GetJobWorkflow 获取任务所在的依赖图及每个任务的状态
+func (h *JobHandler) GetJobWorkflow(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+		return
+	}
+
+	workflow, err := h.jobService.GetWorkflow(uint(id))
+	if err != nil {
+		if errors.Is(err, service.ErrJobNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch job workflow"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"workflow": workflow})
+}
+
+// StreamJobEvents 通过 Server-Sent Events 推送任务的状态、进度和结果，任务结束后关闭连接
+func (h *JobHandler) StreamJobEvents(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+		return
+	}
+
+	// 先订阅再读取当前状态，避免错过两者之间发生的变化
+	events, err := h.jobService.SubscribeJobEvents(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Job events are not available"})
+		return
+	}
+
+	job, err := h.jobStore.GetJobByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch job"})
+		return
+	}
+	if job == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+
+	current := service.NewJobEvent(job)
+	sse.SetHeaders(c)
+	c.SSEvent("job", current)
+	c.Writer.Flush()
+	if current.IsFinal() {
+		return
+	}
+
+	sse.Stream(c, "job", events, service.JobEvent.IsFinal)
+}
@@ -0,0 +1,188 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"go-vibe-friend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PasskeyHandler struct {
+	webAuthnService *service.WebAuthnService
+}
+
+func NewPasskeyHandler(webAuthnService *service.WebAuthnService) *PasskeyHandler {
+	return &PasskeyHandler{
+		webAuthnService: webAuthnService,
+	}
+}
+
+// ListPasskeys returns the current user's passkeys
+func (h *PasskeyHandler) ListPasskeys(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	h.listPasskeys(c, userID.(uint))
+}
+
+// BeginRegistration returns the options for navigator.credentials.create
+func (h *PasskeyHandler) BeginRegistration(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	options, err := h.webAuthnService.BeginRegistration(userID.(uint))
+	if err != nil {
+		if errors.Is(err, service.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start passkey registration"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"publicKey": options})
+}
+
+// FinishRegistration verifies the new credential and stores the passkey
+func (h *PasskeyHandler) FinishRegistration(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req struct {
+		Name       string                         `json:"name" binding:"max=64"`
+		Credential service.RegistrationCredential `json:"credential"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	passkey, err := h.webAuthnService.FinishRegistration(userID.(uint), req.Name, &req.Credential)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidWebAuthnChallenge):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Registration challenge is invalid or expired, please try again"})
+		case errors.Is(err, service.ErrWebAuthnVerificationFailed):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrPasskeyAlreadyRegistered):
+			c.JSON(http.StatusConflict, gin.H{"error": "Passkey is already registered"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save passkey"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, passkey)
+}
+
+// RenamePasskey renames one of the current user's passkeys
+func (h *PasskeyHandler) RenamePasskey(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid passkey ID"})
+		return
+	}
+
+	var req struct {
+		Name string `json:"name" binding:"required,max=64"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	passkey, err := h.webAuthnService.RenameCredential(userID.(uint), uint(id), req.Name)
+	if err != nil {
+		if errors.Is(err, service.ErrPasskeyNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Passkey not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rename passkey"})
+		return
+	}
+
+	c.JSON(http.StatusOK, passkey)
+}
+
+// DeletePasskey deletes one of the current user's passkeys
+func (h *PasskeyHandler) DeletePasskey(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid passkey ID"})
+		return
+	}
+
+	h.deletePasskey(c, userID.(uint), uint(id))
+}
+
+// ListUserPasskeys returns a user's passkeys
+func (h *PasskeyHandler) ListUserPasskeys(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	h.listPasskeys(c, uint(userID))
+}
+
+// DeleteUserPasskey deletes a user's passkey, e.g. a lost or cloned authenticator
+func (h *PasskeyHandler) DeleteUserPasskey(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("passkeyId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid passkey ID"})
+		return
+	}
+
+	h.deletePasskey(c, uint(userID), uint(id))
+}
+
+func (h *PasskeyHandler) listPasskeys(c *gin.Context, userID uint) {
+	passkeys, err := h.webAuthnService.ListCredentials(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list passkeys"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"passkeys": passkeys})
+}
+
+func (h *PasskeyHandler) deletePasskey(c *gin.Context, userID, id uint) {
+	if err := h.webAuthnService.DeleteCredential(userID, id); err != nil {
+		if errors.Is(err, service.ErrPasskeyNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Passkey not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete passkey"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Passkey deleted successfully"})
+}
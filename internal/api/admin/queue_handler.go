@@ -0,0 +1,226 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"go-vibe-friend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type QueueHandler struct {
+	jobService *service.JobService
+}
+
+func NewQueueHandler(jobService *service.JobService) *QueueHandler {
+	return &QueueHandler{
+		jobService: jobService,
+	}
+}
+
+// ListQueues 获取所有队列及其等待、执行中、延迟和死信任务数量
+func (h *QueueHandler) ListQueues(c *gin.Context) {
+	queues, err := h.jobService.ListQueues()
+	if err != nil {
+		respondQueueError(c, err, "Failed to fetch queues")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"queues": queues,
+		"count":  len(queues),
+	})
+}
+
+// GetQueue 获取单个队列的任务数量
+func (h *QueueHandler) GetQueue(c *gin.Context) {
+	queue, err := h.jobService.GetQueue(c.Param("name"))
+	if err != nil {
+		respondQueueError(c, err, "Failed to fetch queue")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"queue": queue})
+}
+
+// ListQueuedJobs 分页获取队列中等待执行的任务，state=delayed 时获取计划执行或等待重试的任务
+func (h *QueueHandler) ListQueuedJobs(c *gin.Context) {
+	queueName := c.Param("name")
+
+	state := c.DefaultQuery("state", "ready")
+	if state != "ready" && state != "delayed" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid state parameter, must be ready or delayed"})
+		return
+	}
+
+	limit, offset, ok := parseQueuePaging(c)
+	if !ok {
+		return
+	}
+
+	jobs, total, err := h.jobService.ListQueuedJobs(queueName, state == "delayed", limit, offset)
+	if err != nil {
+		respondQueueError(c, err, "Failed to fetch queued jobs")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"queue":  queueName,
+		"state":  state,
+		"jobs":   jobs,
+		"count":  len(jobs),
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// SetQueuedJobPriorityRequest 修改任务优先级请求
+type SetQueuedJobPriorityRequest struct {
+	Priority *int `json:"priority" binding:"required"`
+}
+
+// SetQueuedJobPriority 修改等待执行的任务的优先级
+func (h *QueueHandler) SetQueuedJobPriority(c *gin.Context) {
+	var req SetQueuedJobPriorityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	job, err := h.jobService.SetQueuedJobPriority(c.Param("name"), c.Param("id"), *req.Priority)
+	if err != nil {
+		respondQueueError(c, err, "Failed to change job priority")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Job priority updated",
+		"job":     job,
+	})
+}
+
+// RemoveQueuedJob 从队列中移除等待执行的任务
+func (h *QueueHandler) RemoveQueuedJob(c *gin.Context) {
+	if err := h.jobService.RemoveQueuedJob(c.Param("name"), c.Param("id")); err != nil {
+		respondQueueError(c, err, "Failed to remove queued job")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Queued job removed"})
+}
+
+// ListDeadJobs 获取队列的死信任务列表
+func (h *QueueHandler) ListDeadJobs(c *gin.Context) {
+	queueName := c.Param("name")
+
+	limit, offset, ok := parseQueuePaging(c)
+	if !ok {
+		return
+	}
+
+	jobs, total, err := h.jobService.ListDeadJobs(queueName, limit, offset)
+	if err != nil {
+		respondQueueError(c, err, "Failed to fetch dead jobs")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"queue":  queueName,
+		"jobs":   jobs,
+		"count":  len(jobs),
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// GetDeadJob 查看单个死信任务（包含错误和重试历史）
+func (h *QueueHandler) GetDeadJob(c *gin.Context) {
+	dead, err := h.jobService.GetDeadJob(c.Param("name"), c.Param("id"))
+	if err != nil {
+		respondQueueError(c, err, "Failed to fetch dead job")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"job": dead})
+}
+
+// ReplayDeadJob 重新执行死信任务
+func (h *QueueHandler) ReplayDeadJob(c *gin.Context) {
+	dead, err := h.jobService.ReplayDeadJob(c.Param("name"), c.Param("id"))
+	if err != nil {
+		respondQueueError(c, err, "Failed to replay dead job")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Dead job replayed",
+		"job":     dead,
+	})
+}
+
+// PurgeDeadJob 删除单个死信任务
+func (h *QueueHandler) PurgeDeadJob(c *gin.Context) {
+	if err := h.jobService.PurgeDeadJob(c.Param("name"), c.Param("id")); err != nil {
+		respondQueueError(c, err, "Failed to purge dead job")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Dead job purged"})
+}
+
+// PurgeDeadJobs 清空队列的所有死信任务
+func (h *QueueHandler) PurgeDeadJobs(c *gin.Context) {
+	purged, err := h.jobService.PurgeDeadJobs(c.Param("name"))
+	if err != nil {
+		respondQueueError(c, err, "Failed to purge dead jobs")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Dead jobs purged",
+		"purged":  purged,
+	})
+}
+
+// parseQueuePaging reads the limit and offset query parameters, responding
+// with 400 when they are invalid
+func parseQueuePaging(c *gin.Context) (int, int, bool) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit parameter"})
+		return 0, 0, false
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset parameter"})
+		return 0, 0, false
+	}
+
+	return limit, offset, true
+}
+
+// respondQueueError maps job service errors to HTTP responses
+func respondQueueError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, service.ErrQueueUnavailable):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Job queue is not available"})
+	case errors.Is(err, service.ErrDeadJobNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Dead job not found"})
+	case errors.Is(err, service.ErrQueuedJobNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Queued job not found"})
+	case errors.Is(err, service.ErrJobNotReplayable):
+		c.JSON(http.StatusConflict, gin.H{"error": "Only failed jobs can be replayed"})
+	case errors.Is(err, service.ErrInvalidPriority):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,152 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"go-vibe-friend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ScheduleHandler struct {
+	scheduleService *service.ScheduleService
+}
+
+func NewScheduleHandler(scheduleService *service.ScheduleService) *ScheduleHandler {
+	return &ScheduleHandler{
+		scheduleService: scheduleService,
+	}
+}
+
+// ListSchedules 获取周期任务列表
+func (h *ScheduleHandler) ListSchedules(c *gin.Context) {
+	schedules, err := h.scheduleService.ListSchedules()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch schedules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"schedules": schedules,
+		"count":     len(schedules),
+	})
+}
+
+// GetSchedule 获取周期任务详情
+func (h *ScheduleHandler) GetSchedule(c *gin.Context) {
+	id, ok := parseScheduleID(c)
+	if !ok {
+		return
+	}
+
+	schedule, err := h.scheduleService.GetSchedule(id)
+	if err != nil {
+		respondScheduleError(c, err, "Failed to fetch schedule")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"schedule": schedule})
+}
+
+// CreateSchedule 创建周期任务
+func (h *ScheduleHandler) CreateSchedule(c *gin.Context) {
+	var req service.ScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	schedule, err := h.scheduleService.CreateSchedule(&req, userID.(uint))
+	if err != nil {
+		respondScheduleError(c, err, "Failed to create schedule")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"schedule": schedule})
+}
+
+// UpdateSchedule 更新周期任务
+func (h *ScheduleHandler) UpdateSchedule(c *gin.Context) {
+	id, ok := parseScheduleID(c)
+	if !ok {
+		return
+	}
+
+	var req service.ScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	schedule, err := h.scheduleService.UpdateSchedule(id, &req)
+	if err != nil {
+		respondScheduleError(c, err, "Failed to update schedule")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"schedule": schedule})
+}
+
+// DeleteSchedule 删除周期任务
+func (h *ScheduleHandler) DeleteSchedule(c *gin.Context) {
+	id, ok := parseScheduleID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.scheduleService.DeleteSchedule(id); err != nil {
+		respondScheduleError(c, err, "Failed to delete schedule")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Schedule deleted successfully"})
+}
+
+// RunSchedule 立即触发一次周期任务
+func (h *ScheduleHandler) RunSchedule(c *gin.Context) {
+	id, ok := parseScheduleID(c)
+	if !ok {
+		return
+	}
+
+	job, err := h.scheduleService.RunScheduleNow(id)
+	if err != nil {
+		respondScheduleError(c, err, "Failed to run schedule")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"job": job})
+}
+
+func parseScheduleID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid schedule ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// respondScheduleError maps schedule service errors to HTTP responses
+func respondScheduleError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, service.ErrScheduleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Schedule not found"})
+	case errors.Is(err, service.ErrScheduleExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrQueueUnavailable):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Job queue is not available"})
+	case errors.Is(err, service.ErrInvalidSchedule):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -1,21 +1,26 @@
 package admin
 
 import (
+	"errors"
+	"log"
 	"net/http"
 	"strconv"
 
+	"go-vibe-friend/internal/service"
 	"go-vibe-friend/internal/store"
 
 	"github.com/gin-gonic/gin"
 )
 
 type UserHandler struct {
-	userStore *store.UserStore
+	userStore   *store.UserStore
+	authService *service.AuthService
 }
 
-func NewUserHandler(userStore *store.UserStore) *UserHandler {
+func NewUserHandler(userStore *store.UserStore, authService *service.AuthService) *UserHandler {
 	return &UserHandler{
-		userStore: userStore,
+		userStore:   userStore,
+		authService: authService,
 	}
 }
 
@@ -92,5 +97,42 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 		return
 	}
 
+	// Tokens of the deleted user must stop working right away
+	if err := h.authService.RevokeAllSessions(uint(id)); err != nil {
+		log.Printf("Failed to revoke sessions of deleted user %d: %v", id, err)
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"})
-}
\ No newline at end of file
+}
+
+// UpdateUserStatus 更新用户状态，停用或封禁后用户的会话和访问令牌立即失效
+func (h *UserHandler) UpdateUserStatus(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req struct {
+		Status string `json:"status" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.authService.UpdateUserStatus(uint(id), req.Status)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidUserStatus):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Status must be active, inactive or banned"})
+		case errors.Is(err, service.ErrUserNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user status"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"user": user})
+}
@@ -1,15 +1,19 @@
 package middleware
 
 import (
+	"log"
 	"net/http"
 	"strings"
 
+	"go-vibe-friend/internal/service"
 	"go-vibe-friend/internal/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
-func AuthMiddleware() gin.HandlerFunc {
+// AuthMiddleware validates the bearer token and rejects tokens that were
+// revoked by logout, session revocation or a ban before they expired
+func AuthMiddleware(tokenRevocationService *service.TokenRevocationService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -34,6 +38,20 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		revoked, err := tokenRevocationService.IsRevoked(claims)
+		if err != nil {
+			// Fail closed: a revoked token must not get through while the check is unavailable
+			log.Printf("Token revocation check failed: %v", err)
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Unable to verify token"})
+			c.Abort()
+			return
+		}
+		if revoked {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+			c.Abort()
+			return
+		}
+
 		// Set user info in context
 		c.Set("user_id", claims.UserID)
 		c.Set("username", claims.Username)
@@ -0,0 +1,216 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+
+	"go-vibe-friend/internal/service"
+	"go-vibe-friend/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// IdempotencyKeyHeader is the request header carrying the client's key
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotencyReplayedHeader marks responses replayed from a previous request
+	IdempotencyReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+	// maxIdempotentBodySize is the largest response body that is stored;
+	// larger responses are not replayed
+	maxIdempotentBodySize = 1 << 20
+)
+
+// Idempotency replays the first successful response for a repeated
+// Idempotency-Key from the same user on the same route, and rejects repeats
+// with a different request body. Failed requests release the key so they can
+// be retried. Requests without the header are passed through. Must run after
+// AuthMiddleware.
+func Idempotency(idempotencyService *service.IdempotencyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key is too long"})
+			c.Abort()
+			return
+		}
+
+		userID, ok := c.Get("user_id")
+		uid, isUint := userID.(uint)
+		if !ok || !isUint {
+			c.Next()
+			return
+		}
+
+		scoped := idempotencyService.ScopedKey(uid, c.Request.Method, c.FullPath(), key)
+		existing, err := idempotencyService.Reserve(scoped)
+		if err != nil {
+			// Serve the request without idempotency rather than failing it
+			log.Printf("Idempotency key storage unavailable: %v", err)
+			c.Next()
+			return
+		}
+
+		if existing != nil {
+			replayIdempotent(c, existing)
+			return
+		}
+
+		hasher := newBodyFingerprint(c.ContentType(), c.Request.Header.Get("Content-Type"))
+		body := io.TeeReader(c.Request.Body, hasher)
+		c.Request.Body = readCloser{Reader: body, Closer: c.Request.Body}
+
+		writer := &idempotencyWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+
+		c.Next()
+
+		// Hash whatever the handler did not read so the fingerprint covers the whole body
+		_, _ = io.Copy(io.Discard, body)
+
+		status := c.Writer.Status()
+		if status >= http.StatusBadRequest || writer.overflow {
+			if err := idempotencyService.Release(scoped); err != nil {
+				log.Printf("Failed to release idempotency key: %v", err)
+			}
+			return
+		}
+
+		record := &store.IdempotencyRecord{
+			Fingerprint: hasher.Sum(),
+			StatusCode:  status,
+			Header:      c.Writer.Header().Clone(),
+			Body:        writer.body.Bytes(),
+		}
+		if err := idempotencyService.Save(scoped, record); err != nil {
+			log.Printf("Failed to save idempotent response: %v", err)
+		}
+	}
+}
+
+// replayIdempotent answers a repeated key from the stored record
+func replayIdempotent(c *gin.Context, record *store.IdempotencyRecord) {
+	if record.InFlight() {
+		c.JSON(http.StatusConflict, gin.H{"error": "A request with this Idempotency-Key is still being processed"})
+		c.Abort()
+		return
+	}
+
+	hasher := newBodyFingerprint(c.ContentType(), c.Request.Header.Get("Content-Type"))
+	if _, err := io.Copy(hasher, c.Request.Body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+		c.Abort()
+		return
+	}
+	if hasher.Sum() != record.Fingerprint {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key was already used with a different request body"})
+		c.Abort()
+		return
+	}
+
+	header := c.Writer.Header()
+	for name, values := range record.Header {
+		header[name] = values
+	}
+	header.Set(IdempotencyReplayedHeader, "true")
+	c.Status(record.StatusCode)
+	_, _ = c.Writer.Write(record.Body)
+	c.Abort()
+}
+
+// bodyFingerprint hashes a request body as it is read. Multipart boundaries
+// are chosen at random by clients, so they are left out of the hash.
+type bodyFingerprint struct {
+	hash     hash.Hash
+	boundary []byte
+	pending  []byte
+}
+
+func newBodyFingerprint(mediaType, contentType string) *bodyFingerprint {
+	f := &bodyFingerprint{hash: sha256.New()}
+	f.hash.Write([]byte(mediaType + "\n"))
+	if strings.HasPrefix(mediaType, "multipart/") {
+		if _, params, err := mime.ParseMediaType(contentType); err == nil && params["boundary"] != "" {
+			f.boundary = []byte(params["boundary"])
+		}
+	}
+	return f
+}
+
+func (f *bodyFingerprint) Write(p []byte) (int, error) {
+	if f.boundary == nil {
+		return f.hash.Write(p)
+	}
+
+	data := append(f.pending, p...)
+	for {
+		i := bytes.Index(data, f.boundary)
+		if i < 0 {
+			break
+		}
+		f.hash.Write(data[:i])
+		data = data[i+len(f.boundary):]
+	}
+
+	// Hold back a tail that may be the start of a boundary split across writes
+	keep := len(f.boundary) - 1
+	if keep > len(data) {
+		keep = len(data)
+	}
+	f.hash.Write(data[:len(data)-keep])
+	f.pending = append([]byte(nil), data[len(data)-keep:]...)
+	return len(p), nil
+}
+
+// Sum returns the hex encoded fingerprint of everything written so far
+func (f *bodyFingerprint) Sum() string {
+	f.hash.Write(f.pending)
+	f.pending = nil
+	return hex.EncodeToString(f.hash.Sum(nil))
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// idempotencyWriter keeps a copy of the response body up to maxIdempotentBodySize
+type idempotencyWriter struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (w *idempotencyWriter) Write(data []byte) (int, error) {
+	w.record(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *idempotencyWriter) WriteString(s string) (int, error) {
+	w.record([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *idempotencyWriter) record(data []byte) {
+	if w.overflow {
+		return
+	}
+	if w.body.Len()+len(data) > maxIdempotentBodySize {
+		w.overflow = true
+		w.body.Reset()
+		return
+	}
+	w.body.Write(data)
+}
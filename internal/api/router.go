@@ -9,12 +9,14 @@ import (
 	"go-vibe-friend/internal/config"
 	"go-vibe-friend/internal/service"
 	"go-vibe-friend/internal/store"
+	"go-vibe-friend/internal/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/minio/minio-go/v7"
 )
 
-func SetupRouter(storeManager *store.Store, cfg *config.Config, minioClient *minio.Client) *gin.Engine {
+func SetupRouter(storeManager *store.Store, cfg *config.Config, minioClient *minio.Client, jobService *service.JobService, scheduleService *service.ScheduleService, concurrencyService *service.ConcurrencyService,
+	fileService *service.FileService, emailService *service.EmailService, exportService *service.ExportService, importService *service.UserImportService, personalDataService *service.PersonalDataService) *gin.Engine {
 	r := gin.New()
 
 	// Middleware
@@ -23,30 +25,42 @@ func SetupRouter(storeManager *store.Store, cfg *config.Config, minioClient *min
 	r.Use(middleware.CORS())
 
 	// Initialize stores and services using Store manager
-	authService := service.NewAuthService(storeManager.User, storeManager.GetSessionStore())
+	tokenRevocationService := service.NewTokenRevocationService(storeManager, cfg.JWT)
+	authService := service.NewAuthService(storeManager.User, storeManager.GetSessionStore(), tokenRevocationService)
 	profileService := service.NewProfileService(storeManager.User, storeManager.Profile)
-	fileService := service.NewFileService(storeManager.File, minioClient, cfg)
-	emailService := service.NewEmailService(storeManager.Email, "", "", "", "", "", "")
 	permissionService := service.NewPermissionService(storeManager.Permission, storeManager.User)
 	redisService := service.NewRedisService(storeManager)
+	idempotencyService := service.NewIdempotencyService(storeManager, cfg.Idempotency)
+	idempotency := middleware.Idempotency(idempotencyService)
+	twoFactorService := service.NewTwoFactorService(storeManager.TwoFactor, storeManager.User, storeManager.Permission, cfg)
+	webAuthnService := service.NewWebAuthnService(storeManager.WebAuthn, storeManager.User, cfg)
 	
 	// Initialize handlers
-	adminAuthHandler := admin.NewAuthHandler(authService)
-	userHandler := admin.NewUserHandler(storeManager.User)
-	jobHandler := admin.NewJobHandler(storeManager.Job)
-	dashboardHandler := admin.NewDashboardHandler(storeManager.User, storeManager.Job, storeManager.DB.DB)
+	adminAuthHandler := admin.NewAuthHandler(authService, twoFactorService, webAuthnService)
+	userHandler := admin.NewUserHandler(storeManager.User, authService)
+	jobHandler := admin.NewJobHandler(storeManager.Job, jobService)
+	queueHandler := admin.NewQueueHandler(jobService)
+	scheduleHandler := admin.NewScheduleHandler(scheduleService)
+	concurrencyHandler := admin.NewConcurrencyHandler(concurrencyService)
+	dashboardHandler := admin.NewDashboardHandler(storeManager.User, storeManager.Job, jobService, storeManager.DB.DB)
 	permissionHandler := admin.NewPermissionHandler(permissionService)
-	exportService := service.NewExportService(storeManager.User, storeManager.Job, storeManager.File, storeManager.Email, storeManager.Permission)
+	twoFactorHandler := admin.NewTwoFactorHandler(twoFactorService)
+	passkeyHandler := admin.NewPasskeyHandler(webAuthnService)
 	exportHandler := admin.NewExportHandler(exportService)
+	importHandler := admin.NewImportHandler(importService)
 	storageService := service.NewStorageService(minioClient, cfg)
 	storageHandler := admin.NewStorageHandler(storageService)
 	redisHandler := admin.NewRedisHandler(redisService)
 	
 	// VF handlers
-	vfAuthHandler := vf.NewAuthHandler(authService)
+	vfAuthHandler := vf.NewAuthHandler(authService, twoFactorService, webAuthnService)
 	vfProfileHandler := vf.NewProfileHandler(profileService)
 	vfFileHandler := vf.NewFileHandler(fileService)
 	vfEmailHandler := vf.NewEmailHandler(emailService, authService)
+	vfJobHandler := vf.NewJobHandler(jobService, fileService)
+	vfPersonalDataHandler := vf.NewPersonalDataHandler(personalDataService)
+	vfTwoFactorHandler := vf.NewTwoFactorHandler(twoFactorService)
+	vfPasskeyHandler := vf.NewPasskeyHandler(webAuthnService)
 
 	// Health check endpoint
 	r.GET("/health", func(c *gin.Context) {
@@ -56,6 +70,12 @@ func SetupRouter(storeManager *store.Store, cfg *config.Config, minioClient *min
 		})
 	})
 
+	// Public keys for verifying access tokens
+	r.GET("/.well-known/jwks.json", func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, utils.GetJWKS())
+	})
+
 	// API routes
 	api := r.Group("/api")
 	{
@@ -65,16 +85,30 @@ func SetupRouter(storeManager *store.Store, cfg *config.Config, minioClient *min
 			// Public auth routes
 			adminGroup.POST("/register", adminAuthHandler.Register)
 			adminGroup.POST("/login", adminAuthHandler.Login)
+			adminGroup.POST("/login/2fa", adminAuthHandler.LoginTwoFactor)
+			adminGroup.POST("/login/2fa/setup", adminAuthHandler.SetupTwoFactor)
+			adminGroup.POST("/login/passkey/begin", adminAuthHandler.BeginPasskeyLogin)
+			adminGroup.POST("/login/passkey/finish", adminAuthHandler.FinishPasskeyLogin)
 			
 			// Protected routes
 			protected := adminGroup.Group("/")
-			protected.Use(middleware.AuthMiddleware())
+			protected.Use(middleware.AuthMiddleware(tokenRevocationService))
 			{
 				// User management
 				protected.GET("/profile", adminAuthHandler.GetProfile)
+				protected.GET("/passkeys", passkeyHandler.ListPasskeys)
+				protected.POST("/passkeys/register/begin", passkeyHandler.BeginRegistration)
+				protected.POST("/passkeys/register/finish", passkeyHandler.FinishRegistration)
+				protected.PUT("/passkeys/:id", passkeyHandler.RenamePasskey)
+				protected.DELETE("/passkeys/:id", passkeyHandler.DeletePasskey)
 				protected.GET("/users", userHandler.ListUsers)
 				protected.GET("/users/:id", userHandler.GetUser)
 				protected.DELETE("/users/:id", userHandler.DeleteUser)
+				protected.PUT("/users/:id/status", userHandler.UpdateUserStatus)
+				protected.GET("/users/:id/2fa", twoFactorHandler.GetUserStatus)
+				protected.DELETE("/users/:id/2fa", twoFactorHandler.ResetUser)
+				protected.GET("/users/:id/passkeys", passkeyHandler.ListUserPasskeys)
+				protected.DELETE("/users/:id/passkeys/:passkeyId", passkeyHandler.DeleteUserPasskey)
 				
 				// Dashboard
 				protected.GET("/dashboard/stats", dashboardHandler.GetStats)
@@ -90,12 +124,40 @@ func SetupRouter(storeManager *store.Store, cfg *config.Config, minioClient *min
 				
 				// Job management
 				protected.GET("/jobs", jobHandler.ListJobs)
-				protected.POST("/jobs", jobHandler.CreateJob)
+				protected.POST("/jobs", idempotency, jobHandler.CreateJob)
 				protected.GET("/jobs/:id", jobHandler.GetJob)
+				protected.GET("/jobs/:id/events", jobHandler.StreamJobEvents)
+				protected.GET("/jobs/:id/workflow", jobHandler.GetJobWorkflow)
+				protected.POST("/jobs/:id/cancel", jobHandler.CancelJob)
 				protected.PUT("/jobs/:id", jobHandler.UpdateJob)
 				protected.DELETE("/jobs/:id", jobHandler.DeleteJob)
 				protected.POST("/jobs/sample", jobHandler.CreateSampleJobs)
 				
+				// Queue management
+				protected.GET("/queues", queueHandler.ListQueues)
+				protected.GET("/queues/:name", queueHandler.GetQueue)
+				protected.GET("/queues/:name/jobs", queueHandler.ListQueuedJobs)
+				protected.PUT("/queues/:name/jobs/:id/priority", queueHandler.SetQueuedJobPriority)
+				protected.DELETE("/queues/:name/jobs/:id", queueHandler.RemoveQueuedJob)
+				protected.GET("/queues/:name/dead", queueHandler.ListDeadJobs)
+				protected.DELETE("/queues/:name/dead", queueHandler.PurgeDeadJobs)
+				protected.GET("/queues/:name/dead/:id", queueHandler.GetDeadJob)
+				protected.POST("/queues/:name/dead/:id/replay", queueHandler.ReplayDeadJob)
+				protected.DELETE("/queues/:name/dead/:id", queueHandler.PurgeDeadJob)
+				
+				// Recurring job schedules
+				protected.GET("/schedules", scheduleHandler.ListSchedules)
+				protected.POST("/schedules", scheduleHandler.CreateSchedule)
+				protected.GET("/schedules/:id", scheduleHandler.GetSchedule)
+				protected.PUT("/schedules/:id", scheduleHandler.UpdateSchedule)
+				protected.DELETE("/schedules/:id", scheduleHandler.DeleteSchedule)
+				protected.POST("/schedules/:id/run", scheduleHandler.RunSchedule)
+				
+				// Job concurrency limits
+				protected.GET("/concurrency", concurrencyHandler.GetConcurrency)
+				protected.PUT("/concurrency/limits", concurrencyHandler.SetConcurrencyLimit)
+				protected.DELETE("/concurrency/limits/:id", concurrencyHandler.DeleteConcurrencyLimit)
+				
 				
 				// Permission management
 				protected.GET("/permissions", permissionHandler.GetPermissions)
@@ -119,16 +181,24 @@ func SetupRouter(storeManager *store.Store, cfg *config.Config, minioClient *min
 				protected.POST("/roles/assign-user", permissionHandler.AssignRoleToUser)
 				protected.POST("/roles/remove-user", permissionHandler.RemoveRoleFromUser)
 				protected.GET("/roles/users/:id", permissionHandler.GetUserRoles)
+				protected.PUT("/roles/:id/2fa", twoFactorHandler.SetRolePolicy)
 				
 				// Data export
-				protected.POST("/export", exportHandler.ExportData)
-				protected.GET("/export/download/:filename", exportHandler.DownloadExport)
+				protected.POST("/export", idempotency, exportHandler.ExportData)
+				protected.POST("/export/stream", exportHandler.StreamExport)
+				protected.GET("/export/jobs/:id", exportHandler.GetExportJob)
+				protected.GET("/export/files", exportHandler.ListExports)
+				protected.GET("/export/download/:id", exportHandler.DownloadExport)
 				protected.GET("/export/users/:id", exportHandler.ExportUserData)
 				protected.GET("/export/system-report", exportHandler.ExportSystemReport)
 				protected.GET("/export/types", exportHandler.GetExportTypes)
 				protected.GET("/export/templates", exportHandler.GetExportTemplates)
 				protected.POST("/export/cleanup", exportHandler.CleanupExpiredExports)
 				
+				// Data import
+				protected.POST("/import/users", idempotency, importHandler.ImportUsers)
+				protected.GET("/import/jobs/:id", importHandler.GetImportJob)
+				
 				// Storage management
 				protected.GET("/storage/objects", storageHandler.ListStorageObjects)
 				protected.GET("/storage/objects/download/*objectKey", storageHandler.DownloadStorageObject)
@@ -164,13 +234,17 @@ func SetupRouter(storeManager *store.Store, cfg *config.Config, minioClient *min
 			{
 				authGroup.POST("/register", vfAuthHandler.Register)
 				authGroup.POST("/login", vfAuthHandler.Login)
+				authGroup.POST("/login/2fa", vfAuthHandler.LoginTwoFactor)
+				authGroup.POST("/login/2fa/setup", vfAuthHandler.SetupTwoFactor)
+				authGroup.POST("/passkey/begin", vfAuthHandler.BeginPasskeyLogin)
+				authGroup.POST("/passkey/finish", vfAuthHandler.FinishPasskeyLogin)
 				authGroup.POST("/refresh", vfAuthHandler.Refresh)
 				authGroup.POST("/logout", vfAuthHandler.Logout)
 			}
 			
 			// 需要认证的接口
 			protected := vf.Group("/")
-			protected.Use(middleware.AuthMiddleware())
+			protected.Use(middleware.AuthMiddleware(tokenRevocationService))
 			{
 				// 个人中心
 				protected.GET("/profile", vfProfileHandler.GetProfile)
@@ -180,7 +254,7 @@ func SetupRouter(storeManager *store.Store, cfg *config.Config, minioClient *min
 				protected.GET("/users/:id/profile", vfProfileHandler.GetUserProfile)
 				
 				// 文件管理
-				protected.POST("/files/upload", vfFileHandler.UploadFile)
+				protected.POST("/files/upload", idempotency, vfFileHandler.UploadFile)
 				protected.POST("/files/avatar", vfFileHandler.UploadAvatar)
 				protected.GET("/files", vfFileHandler.GetFiles)
 				protected.GET("/files/stats", vfFileHandler.GetFileStats)
@@ -190,6 +264,31 @@ func SetupRouter(storeManager *store.Store, cfg *config.Config, minioClient *min
 				protected.POST("/email/send-verification", vfEmailHandler.SendVerificationEmail)
 				protected.GET("/email/status", vfEmailHandler.GetEmailStatus)
 				protected.GET("/email/logs", vfEmailHandler.GetEmailLogs)
+				
+				// 任务进度推送（SSE）
+				protected.GET("/jobs/events", vfJobHandler.StreamJobEvents)
+				
+				// 任务产物
+				protected.GET("/jobs/:id/artifacts", vfJobHandler.ListJobArtifacts)
+				protected.GET("/jobs/:id/artifacts/:fileId/download", vfJobHandler.DownloadJobArtifact)
+				
+				// 个人数据导出
+				protected.POST("/me/export", idempotency, vfPersonalDataHandler.RequestExport)
+				protected.GET("/me/export/:id", vfPersonalDataHandler.GetExport)
+				
+				// 两步验证
+				protected.GET("/me/2fa", vfTwoFactorHandler.GetStatus)
+				protected.POST("/me/2fa/enroll", vfTwoFactorHandler.Enroll)
+				protected.POST("/me/2fa/confirm", vfTwoFactorHandler.Confirm)
+				protected.POST("/me/2fa/recovery-codes", vfTwoFactorHandler.RegenerateRecoveryCodes)
+				protected.POST("/me/2fa/disable", vfTwoFactorHandler.Disable)
+				
+				// 通行密钥
+				protected.GET("/me/passkeys", vfPasskeyHandler.ListPasskeys)
+				protected.POST("/me/passkeys/register/begin", vfPasskeyHandler.BeginRegistration)
+				protected.POST("/me/passkeys/register/finish", vfPasskeyHandler.FinishRegistration)
+				protected.PUT("/me/passkeys/:id", vfPasskeyHandler.RenamePasskey)
+				protected.DELETE("/me/passkeys/:id", vfPasskeyHandler.DeletePasskey)
 			}
 			
 			// 公开的文件下载接口（支持公开文件）
@@ -0,0 +1,46 @@
+// Package sse contains the helpers shared by the Server-Sent Events endpoints
+// of the vf and admin APIs.
+package sse
+
+import (
+	"io"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HeartbeatInterval is how often a ping is sent on an idle stream so proxies
+// do not close the connection
+const HeartbeatInterval = 15 * time.Second
+
+// SetHeaders marks the response as an event stream and disables caching and
+// proxy buffering
+func SetHeaders(c *gin.Context) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+}
+
+// Stream sends every value received from events as an SSE event called name,
+// with heartbeats in between, until events is closed, the client disconnects
+// or last reports that the value just sent ends the stream. last may be nil.
+// SetHeaders must have been called before.
+func Stream[T any](c *gin.Context, name string, events <-chan T, last func(T) bool) {
+	heartbeat := time.NewTicker(HeartbeatInterval)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return false
+			}
+			c.SSEvent(name, event)
+			return last == nil || !last(event)
+		case <-heartbeat.C:
+			c.SSEvent("ping", time.Now().Unix())
+			return true
+		}
+	})
+}
@@ -1,6 +1,8 @@
 package vf
 
 import (
+	"errors"
+	"io"
 	"net/http"
 	"strings"
 
@@ -12,12 +14,16 @@ import (
 )
 
 type AuthHandler struct {
-	authService *service.AuthService
+	authService      *service.AuthService
+	twoFactorService *service.TwoFactorService
+	webAuthnService  *service.WebAuthnService
 }
 
-func NewAuthHandler(authService *service.AuthService) *AuthHandler {
+func NewAuthHandler(authService *service.AuthService, twoFactorService *service.TwoFactorService, webAuthnService *service.WebAuthnService) *AuthHandler {
 	return &AuthHandler{
-		authService: authService,
+		authService:      authService,
+		twoFactorService: twoFactorService,
+		webAuthnService:  webAuthnService,
 	}
 }
 
@@ -34,6 +40,27 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+// TwoFactorLoginRequest 两步登录第二步请求，code 为验证码或恢复码
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+}
+
+// TwoFactorSetupRequest 登录时登记两步验证的请求
+type TwoFactorSetupRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+}
+
+// PasskeyLoginBeginRequest 通行密钥登录请求，email 为空时由浏览器列出可用的通行密钥
+type PasskeyLoginBeginRequest struct {
+	Email string `json:"email" binding:"omitempty,email"`
+}
+
+// PasskeyLoginFinishRequest 通行密钥登录结果
+type PasskeyLoginFinishRequest struct {
+	Credential service.AssertionCredential `json:"credential"`
+}
+
 // RefreshRequest 刷新令牌请求
 type RefreshRequest struct {
 	RefreshToken string `json:"refresh_token" binding:"required"`
@@ -55,9 +82,10 @@ type LoginResponse struct {
 	Code    int    `json:"code"`
 	Message string `json:"message"`
 	Data    struct {
-		User         *models.User `json:"user"`
-		AccessToken  string       `json:"access_token"`
-		RefreshToken string       `json:"refresh_token"`
+		User          *models.User `json:"user"`
+		AccessToken   string       `json:"access_token"`
+		RefreshToken  string       `json:"refresh_token"`
+		RecoveryCodes []string     `json:"recovery_codes,omitempty"` // 登录时完成两步验证登记才返回
 	} `json:"data"`
 }
 
@@ -110,6 +138,29 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
+	// 默认角色要求两步验证时，新用户需先完成登记才能获得令牌
+	challenge, err := h.twoFactorService.BeginLogin(user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    5000,
+			"message": "两步验证初始化失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+	if challenge != nil {
+		c.JSON(http.StatusCreated, gin.H{
+			"code":    0,
+			"message": "注册成功，账号所属角色要求启用两步验证，请先完成设置",
+			"data": gin.H{
+				"user":                user,
+				"two_factor_required": true,
+				"challenge":           challenge,
+			},
+		})
+		return
+	}
+
 	// 生成令牌
 	accessToken, refreshToken, err := h.authService.GenerateTokens(user)
 	if err != nil {
@@ -180,48 +231,158 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	// 生成令牌
-	accessToken, refreshToken, err := h.authService.GenerateTokens(user)
+	// 启用两步验证的用户先返回登录质询
+	challenge, err := h.twoFactorService.BeginLogin(user)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    5000,
-			"message": "生成令牌失败",
+			"message": "两步验证初始化失败",
 			"error":   err.Error(),
 		})
 		return
 	}
+	if challenge != nil {
+		message := "请输入两步验证码"
+		if challenge.SetupRequired {
+			message = "账号所属角色要求启用两步验证，请先完成设置"
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"code":    0,
+			"message": message,
+			"data": gin.H{
+				"two_factor_required": true,
+				"challenge":           challenge,
+			},
+		})
+		return
+	}
 
-	// 创建会话
-	clientIP := c.ClientIP()
-	userAgent := c.GetHeader("User-Agent")
-	if err := h.authService.CreateSession(user.ID, refreshToken, clientIP, userAgent); err != nil {
+	h.completeLogin(c, user, nil)
+}
+
+// LoginTwoFactor 两步登录第二步，校验登录质询和验证码或恢复码后签发令牌
+func (h *AuthHandler) LoginTwoFactor(c *gin.Context) {
+	var req TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1001,
+			"message": "参数校验失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	user, recoveryCodes, err := h.twoFactorService.CompleteLogin(req.ChallengeToken, req.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidTwoFactorCode):
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"code":    1002,
+				"message": "验证码错误",
+			})
+		case errors.Is(err, service.ErrInvalidChallenge), errors.Is(err, service.ErrTwoFactorNotEnrolled):
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"code":    1002,
+				"message": "登录质询无效或已过期，请重新登录",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    5000,
+				"message": "两步验证失败",
+				"error":   err.Error(),
+			})
+		}
+		return
+	}
+
+	// 检查用户状态
+	if user.Status != "active" {
+		c.JSON(http.StatusForbidden, gin.H{
+			"code":    1003,
+			"message": "用户账号已被禁用",
+		})
+		return
+	}
+
+	h.completeLogin(c, user, recoveryCodes)
+}
+
+// SetupTwoFactor 角色要求两步验证但尚未登记的用户在登录时凭质询生成密钥
+func (h *AuthHandler) SetupTwoFactor(c *gin.Context) {
+	var req TwoFactorSetupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1001,
+			"message": "参数校验失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	enrollment, err := h.twoFactorService.EnrollWithChallenge(req.ChallengeToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidChallenge):
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"code":    1002,
+				"message": "登录质询无效或已过期，请重新登录",
+			})
+		case errors.Is(err, service.ErrTwoFactorAlreadyEnabled):
+			c.JSON(http.StatusConflict, gin.H{
+				"code":    1001,
+				"message": "两步验证已启用",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    5000,
+				"message": "生成两步验证密钥失败",
+				"error":   err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "请将密钥添加到认证器应用，并使用生成的验证码完成登录",
+		"data":    enrollment,
+	})
+}
+
+// BeginPasskeyLogin 开始通行密钥登录，返回 navigator.credentials.get 的参数
+func (h *AuthHandler) BeginPasskeyLogin(c *gin.Context) {
+	var req PasskeyLoginBeginRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1001,
+			"message": "参数校验失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	options, err := h.webAuthnService.BeginLogin(req.Email)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    5000,
-			"message": "创建会话失败",
+			"message": "生成登录质询失败",
 			"error":   err.Error(),
 		})
 		return
 	}
 
-	// 返回响应
-	c.JSON(http.StatusOK, LoginResponse{
-		Code:    0,
-		Message: "登录成功",
-		Data: struct {
-			User         *models.User `json:"user"`
-			AccessToken  string       `json:"access_token"`
-			RefreshToken string       `json:"refresh_token"`
-		}{
-			User:         user,
-			AccessToken:  accessToken,
-			RefreshToken: refreshToken,
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "请使用通行密钥完成登录",
+		"data": gin.H{
+			"publicKey": options,
 		},
 	})
 }
 
-// Refresh 刷新令牌
-func (h *AuthHandler) Refresh(c *gin.Context) {
-	var req RefreshRequest
+// FinishPasskeyLogin 校验通行密钥登录结果后签发令牌
+func (h *AuthHandler) FinishPasskeyLogin(c *gin.Context) {
+	var req PasskeyLoginFinishRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"code":    1001,
@@ -231,17 +392,49 @@ func (h *AuthHandler) Refresh(c *gin.Context) {
 		return
 	}
 
-	// 验证刷新令牌
-	user, err := h.authService.ValidateRefreshToken(req.RefreshToken)
+	user, err := h.webAuthnService.FinishLogin(&req.Credential, c.ClientIP(), c.GetHeader("User-Agent"))
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"code":    1002,
-			"message": "刷新令牌无效或已过期",
+		switch {
+		case errors.Is(err, service.ErrInvalidWebAuthnChallenge):
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"code":    1002,
+				"message": "登录质询无效或已过期，请重试",
+			})
+		case errors.Is(err, service.ErrWebAuthnVerificationFailed):
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"code":    1002,
+				"message": "通行密钥验证失败",
+			})
+		case errors.Is(err, service.ErrPasskeyCloned):
+			c.JSON(http.StatusForbidden, gin.H{
+				"code":    1003,
+				"message": "该通行密钥可能已被复制，已停用，请使用其他方式登录",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    5000,
+				"message": "通行密钥登录失败",
+				"error":   err.Error(),
+			})
+		}
+		return
+	}
+
+	// 检查用户状态
+	if user.Status != "active" {
+		c.JSON(http.StatusForbidden, gin.H{
+			"code":    1003,
+			"message": "用户账号已被禁用",
 		})
 		return
 	}
 
-	// 生成新令牌
+	h.completeLogin(c, user, nil)
+}
+
+// completeLogin 生成令牌、创建会话并返回登录响应
+func (h *AuthHandler) completeLogin(c *gin.Context, user *models.User, recoveryCodes []string) {
+	// 生成令牌
 	accessToken, refreshToken, err := h.authService.GenerateTokens(user)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -252,10 +445,61 @@ func (h *AuthHandler) Refresh(c *gin.Context) {
 		return
 	}
 
-	// 撤销旧会话，创建新会话
+	// 创建会话
+	clientIP := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+	if err := h.authService.CreateSession(user.ID, refreshToken, clientIP, userAgent); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    5000,
+			"message": "创建会话失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	// 返回响应
+	response := LoginResponse{
+		Code:    0,
+		Message: "登录成功",
+	}
+	response.Data.User = user
+	response.Data.AccessToken = accessToken
+	response.Data.RefreshToken = refreshToken
+	response.Data.RecoveryCodes = recoveryCodes
+	c.JSON(http.StatusOK, response)
+}
+
+// Refresh 刷新令牌
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1001,
+			"message": "参数校验失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	// 轮换刷新令牌，已轮换的令牌被重放时整个令牌族失效
 	clientIP := c.ClientIP()
 	userAgent := c.GetHeader("User-Agent")
-	if err := h.authService.RefreshSession(req.RefreshToken, refreshToken, clientIP, userAgent); err != nil {
+	accessToken, refreshToken, err := h.authService.RefreshSession(req.RefreshToken, clientIP, userAgent)
+	if err != nil {
+		if errors.Is(err, service.ErrRefreshTokenReused) {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"code":    1002,
+				"message": "刷新令牌已被使用，请重新登录",
+			})
+			return
+		}
+		if errors.Is(err, service.ErrInvalidRefreshToken) {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"code":    1002,
+				"message": "刷新令牌无效或已过期",
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    5000,
 			"message": "刷新会话失败",
@@ -302,8 +546,8 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 
 	token := tokenParts[1]
 
-	// 验证令牌并获取用户ID
-	userID, err := utils.ValidateJWT(token)
+	// 验证令牌
+	claims, err := utils.ValidateJWTWithClaims(token)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"code":    1002,
@@ -312,8 +556,8 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 		return
 	}
 
-	// 撤销用户的所有会话
-	if err := h.authService.RevokeAllSessions(userID); err != nil {
+	// 撤销当前访问令牌和用户的所有会话
+	if err := h.authService.Logout(claims); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    5000,
 			"message": "撤销会话失败",
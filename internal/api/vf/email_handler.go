@@ -7,7 +7,6 @@ import (
 	"go-vibe-friend/internal/service"
 
 	"github.com/gin-gonic/gin"
-	"golang.org/x/crypto/bcrypt"
 )
 
 type EmailHandler struct {
@@ -158,22 +157,22 @@ func (h *EmailHandler) ResetPassword(c *gin.Context) {
 		return
 	}
 
-	// 加密新密码
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	// 重置密码
+	userID, err := h.emailService.ResetPassword(req.Token, req.NewPassword)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":    5000,
-			"message": "密码加密失败",
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1001,
+			"message": "密码重置失败",
 			"error":   err.Error(),
 		})
 		return
 	}
 
-	// 重置密码
-	if err := h.emailService.ResetPassword(req.Token, string(hashedPassword)); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    1001,
-			"message": "密码重置失败",
+	// 修改密码后注销所有会话，旧的访问令牌和刷新令牌都不能再使用
+	if err := h.authService.RevokeAllSessions(userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    5000,
+			"message": "密码已重置，但注销现有会话失败",
 			"error":   err.Error(),
 		})
 		return
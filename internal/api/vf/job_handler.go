@@ -0,0 +1,195 @@
+package vf
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+
+	"go-vibe-friend/internal/api/sse"
+	"go-vibe-friend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type JobHandler struct {
+	jobService  *service.JobService
+	fileService *service.FileService
+}
+
+func NewJobHandler(jobService *service.JobService, fileService *service.FileService) *JobHandler {
+	return &JobHandler{
+		jobService:  jobService,
+		fileService: fileService,
+	}
+}
+
+// StreamJobEvents 通过 Server-Sent Events 推送当前用户所有任务的状态变化
+func (h *JobHandler) StreamJobEvents(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"code":    1002,
+			"message": "未认证用户",
+		})
+		return
+	}
+
+	uid, ok := userID.(uint)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    5000,
+			"message": "用户ID类型错误",
+		})
+		return
+	}
+
+	events, err := h.jobService.SubscribeUserJobEvents(c.Request.Context(), uid)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"code":    5000,
+			"message": "任务事件服务不可用",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	sse.SetHeaders(c)
+	c.SSEvent("ready", gin.H{"user_id": uid})
+	c.Writer.Flush()
+
+	sse.Stream(c, "job", events, nil)
+}
+
+// ListJobArtifacts 获取当前用户任务生成的产物文件
+func (h *JobHandler) ListJobArtifacts(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"code":    1002,
+			"message": "未认证用户",
+		})
+		return
+	}
+
+	uid, ok := userID.(uint)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    5000,
+			"message": "用户ID类型错误",
+		})
+		return
+	}
+
+	jobID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1001,
+			"message": "任务ID格式错误",
+		})
+		return
+	}
+
+	files, err := h.fileService.GetJobArtifacts(uint(jobID), uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    5000,
+			"message": "获取任务产物失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	artifacts := make([]gin.H, 0, len(files))
+	for _, file := range files {
+		artifacts = append(artifacts, gin.H{
+			"file_id":      file.ID,
+			"name":         file.OriginalName,
+			"file_size":    file.FileSize,
+			"mime_type":    file.MimeType,
+			"download_url": "/api/vf/v1/jobs/" + strconv.FormatUint(jobID, 10) + "/artifacts/" + strconv.Itoa(int(file.ID)) + "/download",
+			"created_at":   file.CreatedAt,
+			"updated_at":   file.UpdatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "获取成功",
+		"data": gin.H{
+			"job_id":    jobID,
+			"artifacts": artifacts,
+			"count":     len(artifacts),
+		},
+	})
+}
+
+// DownloadJobArtifact 下载任务生成的产物文件
+func (h *JobHandler) DownloadJobArtifact(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"code":    1002,
+			"message": "未认证用户",
+		})
+		return
+	}
+
+	uid, ok := userID.(uint)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    5000,
+			"message": "用户ID类型错误",
+		})
+		return
+	}
+
+	jobID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1001,
+			"message": "任务ID格式错误",
+		})
+		return
+	}
+	fileID, err := strconv.ParseUint(c.Param("fileId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1001,
+			"message": "文件ID格式错误",
+		})
+		return
+	}
+
+	file, err := h.fileService.GetJobArtifact(uint(jobID), uint(fileID), uid)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    1004,
+			"message": "文件不存在或无权访问",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	obj, err := h.fileService.GetFileObject(file)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    1004,
+			"message": "文件不存在",
+			"error":   err.Error(),
+		})
+		return
+	}
+	defer obj.Close()
+
+	c.Header("Content-Type", file.MimeType)
+	c.Header("Content-Disposition", "attachment; filename=\""+file.OriginalName+"\"")
+	c.Header("Content-Length", strconv.FormatInt(file.FileSize, 10))
+
+	if _, err := io.Copy(c.Writer, obj); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    5000,
+			"message": "文件下载失败",
+		})
+		return
+	}
+}
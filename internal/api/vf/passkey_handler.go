@@ -0,0 +1,227 @@
+package vf
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"go-vibe-friend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PasskeyHandler struct {
+	webAuthnService *service.WebAuthnService
+}
+
+func NewPasskeyHandler(webAuthnService *service.WebAuthnService) *PasskeyHandler {
+	return &PasskeyHandler{
+		webAuthnService: webAuthnService,
+	}
+}
+
+// PasskeyRegisterRequest 通行密钥注册结果
+type PasskeyRegisterRequest struct {
+	Name       string                         `json:"name" binding:"max=64"`
+	Credential service.RegistrationCredential `json:"credential"`
+}
+
+// PasskeyRenameRequest 重命名通行密钥请求
+type PasskeyRenameRequest struct {
+	Name string `json:"name" binding:"required,max=64"`
+}
+
+// ListPasskeys 获取本人的通行密钥
+func (h *PasskeyHandler) ListPasskeys(c *gin.Context) {
+	uid, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	passkeys, err := h.webAuthnService.ListCredentials(uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    5000,
+			"message": "获取通行密钥失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "获取成功",
+		"data":    passkeys,
+	})
+}
+
+// BeginRegistration 开始登记通行密钥，返回 navigator.credentials.create 的参数
+func (h *PasskeyHandler) BeginRegistration(c *gin.Context) {
+	uid, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	options, err := h.webAuthnService.BeginRegistration(uid)
+	if err != nil {
+		if errors.Is(err, service.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"code":    1004,
+				"message": "用户不存在",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    5000,
+			"message": "生成注册质询失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "请使用认证器创建通行密钥",
+		"data": gin.H{
+			"publicKey": options,
+		},
+	})
+}
+
+// FinishRegistration 校验注册结果并保存通行密钥
+func (h *PasskeyHandler) FinishRegistration(c *gin.Context) {
+	uid, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req PasskeyRegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1001,
+			"message": "参数校验失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	passkey, err := h.webAuthnService.FinishRegistration(uid, req.Name, &req.Credential)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidWebAuthnChallenge):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    1001,
+				"message": "注册质询无效或已过期，请重试",
+			})
+		case errors.Is(err, service.ErrWebAuthnVerificationFailed):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    1001,
+				"message": "通行密钥验证失败",
+				"error":   err.Error(),
+			})
+		case errors.Is(err, service.ErrPasskeyAlreadyRegistered):
+			c.JSON(http.StatusConflict, gin.H{
+				"code":    1001,
+				"message": "该通行密钥已登记",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    5000,
+				"message": "保存通行密钥失败",
+				"error":   err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"code":    0,
+		"message": "通行密钥已登记",
+		"data":    passkey,
+	})
+}
+
+// RenamePasskey 重命名通行密钥
+func (h *PasskeyHandler) RenamePasskey(c *gin.Context) {
+	uid, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := passkeyID(c)
+	if !ok {
+		return
+	}
+
+	var req PasskeyRenameRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1001,
+			"message": "参数校验失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	passkey, err := h.webAuthnService.RenameCredential(uid, id, req.Name)
+	if err != nil {
+		h.handleError(c, err, "重命名通行密钥失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "重命名成功",
+		"data":    passkey,
+	})
+}
+
+// DeletePasskey 删除通行密钥
+func (h *PasskeyHandler) DeletePasskey(c *gin.Context) {
+	uid, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := passkeyID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.webAuthnService.DeleteCredential(uid, id); err != nil {
+		h.handleError(c, err, "删除通行密钥失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "删除成功",
+	})
+}
+
+// handleError 将通行密钥管理的错误转换为响应
+func (h *PasskeyHandler) handleError(c *gin.Context, err error, message string) {
+	if errors.Is(err, service.ErrPasskeyNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    1004,
+			"message": "通行密钥不存在",
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"code":    5000,
+		"message": message,
+		"error":   err.Error(),
+	})
+}
+
+// passkeyID 解析路径中的通行密钥ID，失败时写入错误响应
+func passkeyID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1001,
+			"message": "无效的通行密钥ID",
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
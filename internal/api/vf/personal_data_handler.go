@@ -0,0 +1,169 @@
+package vf
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"go-vibe-friend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PersonalDataHandler struct {
+	personalDataService *service.PersonalDataService
+}
+
+func NewPersonalDataHandler(personalDataService *service.PersonalDataService) *PersonalDataHandler {
+	return &PersonalDataHandler{
+		personalDataService: personalDataService,
+	}
+}
+
+// RequestExport 提交导出本人数据的任务，已有未完成的导出任务时返回该任务
+func (h *PersonalDataHandler) RequestExport(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"code":    1002,
+			"message": "未认证用户",
+		})
+		return
+	}
+
+	uid, ok := userID.(uint)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    5000,
+			"message": "用户ID类型错误",
+		})
+		return
+	}
+
+	job, created, err := h.personalDataService.SubmitExport(uid)
+	if err != nil {
+		if errors.Is(err, service.ErrQueueUnavailable) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"code":    5000,
+				"message": "任务队列不可用",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    5000,
+			"message": "提交数据导出任务失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	message := "数据导出任务已提交"
+	if !created {
+		message = "已有进行中的数据导出任务"
+	}
+	c.JSON(http.StatusAccepted, gin.H{
+		"code":    0,
+		"message": message,
+		"data": gin.H{
+			"job_id":     job.ID,
+			"status":     job.Status,
+			"status_url": "/api/vf/v1/me/export/" + strconv.FormatUint(uint64(job.ID), 10),
+		},
+	})
+}
+
+// GetExport 获取本人数据导出任务的状态，完成后返回限时下载链接
+func (h *PersonalDataHandler) GetExport(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"code":    1002,
+			"message": "未认证用户",
+		})
+		return
+	}
+
+	uid, ok := userID.(uint)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    5000,
+			"message": "用户ID类型错误",
+		})
+		return
+	}
+
+	jobID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1001,
+			"message": "任务ID格式错误",
+		})
+		return
+	}
+
+	job, result, err := h.personalDataService.GetExport(uid, uint(jobID))
+	if err != nil {
+		if errors.Is(err, service.ErrJobNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"code":    1004,
+				"message": "数据导出任务不存在",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    5000,
+			"message": "获取数据导出任务失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	data := gin.H{
+		"job_id":     job.ID,
+		"status":     job.Status,
+		"progress":   job.Progress,
+		"error":      job.ErrorMsg,
+		"created_at": job.CreatedAt,
+	}
+	if result == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"code":    0,
+			"message": "获取成功",
+			"data":    data,
+		})
+		return
+	}
+
+	data["file_name"] = result.FileName
+	data["file_size"] = result.FileSize
+	data["counts"] = result.Counts
+	data["missing_files"] = result.MissingFiles
+	data["expires_at"] = result.ExpiresAt
+
+	link, linkExpiresAt, err := h.personalDataService.DownloadLink(c.Request.Context(), uid, result)
+	if err != nil {
+		if errors.Is(err, service.ErrExportExpired) || errors.Is(err, service.ErrExportNotFound) {
+			c.JSON(http.StatusGone, gin.H{
+				"code":    1004,
+				"message": "数据包已过期，请重新导出",
+				"data":    data,
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    5000,
+			"message": "生成下载链接失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	// 每次请求都生成新的下载链接，链接过期后可重新获取
+	data["download_url"] = link
+	data["download_url_expires_at"] = linkExpiresAt
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "获取成功",
+		"data":    data,
+	})
+}
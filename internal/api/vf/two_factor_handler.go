@@ -0,0 +1,218 @@
+package vf
+
+import (
+	"errors"
+	"net/http"
+
+	"go-vibe-friend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TwoFactorHandler struct {
+	twoFactorService *service.TwoFactorService
+}
+
+func NewTwoFactorHandler(twoFactorService *service.TwoFactorService) *TwoFactorHandler {
+	return &TwoFactorHandler{
+		twoFactorService: twoFactorService,
+	}
+}
+
+// TwoFactorCodeRequest 需要验证码的请求，code 为 6 位验证码，关闭两步验证时也可以使用恢复码
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// GetStatus 获取本人的两步验证状态
+func (h *TwoFactorHandler) GetStatus(c *gin.Context) {
+	uid, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	status, err := h.twoFactorService.Status(uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    5000,
+			"message": "获取两步验证状态失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "获取成功",
+		"data":    status,
+	})
+}
+
+// Enroll 生成 TOTP 密钥和 otpauth URI，确认验证码后才会启用
+func (h *TwoFactorHandler) Enroll(c *gin.Context) {
+	uid, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	enrollment, err := h.twoFactorService.Enroll(uid)
+	if err != nil {
+		h.handleError(c, err, "生成两步验证密钥失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "请将密钥添加到认证器应用，并提交生成的验证码完成启用",
+		"data":    enrollment,
+	})
+}
+
+// Confirm 使用首个验证码启用两步验证，返回只显示一次的恢复码
+func (h *TwoFactorHandler) Confirm(c *gin.Context) {
+	uid, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if !bindTwoFactorCode(c, &req) {
+		return
+	}
+
+	codes, err := h.twoFactorService.Confirm(uid, req.Code)
+	if err != nil {
+		h.handleError(c, err, "启用两步验证失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "两步验证已启用，请妥善保存恢复码",
+		"data": gin.H{
+			"recovery_codes": codes,
+		},
+	})
+}
+
+// RegenerateRecoveryCodes 生成新的恢复码，旧恢复码全部失效
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	uid, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if !bindTwoFactorCode(c, &req) {
+		return
+	}
+
+	codes, err := h.twoFactorService.RegenerateRecoveryCodes(uid, req.Code)
+	if err != nil {
+		h.handleError(c, err, "生成恢复码失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "恢复码已重新生成，请妥善保存",
+		"data": gin.H{
+			"recovery_codes": codes,
+		},
+	})
+}
+
+// Disable 关闭两步验证
+func (h *TwoFactorHandler) Disable(c *gin.Context) {
+	uid, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if !bindTwoFactorCode(c, &req) {
+		return
+	}
+
+	if err := h.twoFactorService.Disable(uid, req.Code); err != nil {
+		h.handleError(c, err, "关闭两步验证失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "两步验证已关闭",
+	})
+}
+
+// handleError 将两步验证的错误转换为响应
+func (h *TwoFactorHandler) handleError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, service.ErrInvalidTwoFactorCode):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1001,
+			"message": "验证码错误",
+		})
+	case errors.Is(err, service.ErrTwoFactorAlreadyEnabled):
+		c.JSON(http.StatusConflict, gin.H{
+			"code":    1001,
+			"message": "两步验证已启用",
+		})
+	case errors.Is(err, service.ErrTwoFactorNotEnrolled), errors.Is(err, service.ErrTwoFactorNotEnabled):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1001,
+			"message": "两步验证未启用",
+		})
+	case errors.Is(err, service.ErrTwoFactorRequired):
+		c.JSON(http.StatusForbidden, gin.H{
+			"code":    1003,
+			"message": "账号所属角色要求启用两步验证，不能关闭",
+		})
+	case errors.Is(err, service.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    1004,
+			"message": "用户不存在",
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    5000,
+			"message": message,
+			"error":   err.Error(),
+		})
+	}
+}
+
+// bindTwoFactorCode 解析验证码请求，失败时写入错误响应
+func bindTwoFactorCode(c *gin.Context, req *TwoFactorCodeRequest) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1001,
+			"message": "参数校验失败",
+			"error":   err.Error(),
+		})
+		return false
+	}
+	return true
+}
+
+// currentUserID 获取当前登录用户的ID，失败时写入错误响应
+func currentUserID(c *gin.Context) (uint, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"code":    1002,
+			"message": "未认证用户",
+		})
+		return 0, false
+	}
+
+	uid, ok := userID.(uint)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    5000,
+			"message": "用户ID类型错误",
+		})
+		return 0, false
+	}
+	return uid, true
+}
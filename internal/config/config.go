@@ -9,10 +9,15 @@ import (
 )
 
 type Config struct {
-	Server   ServerConfig   `mapstructure:"server"`
-	Database DatabaseConfig `mapstructure:"database"`
-	Redis    RedisConfig    `mapstructure:"redis"`
-	MinIO    MinIOConfig    `mapstructure:"minio"`
+	Server      ServerConfig      `mapstructure:"server"`
+	Database    DatabaseConfig    `mapstructure:"database"`
+	Redis       RedisConfig       `mapstructure:"redis"`
+	MinIO       MinIOConfig       `mapstructure:"minio"`
+	Worker      WorkerConfig      `mapstructure:"worker"`
+	Scheduler   SchedulerConfig   `mapstructure:"scheduler"`
+	Idempotency IdempotencyConfig `mapstructure:"idempotency"`
+	JWT         JWTConfig         `mapstructure:"jwt"`
+	WebAuthn    WebAuthnConfig    `mapstructure:"webauthn"`
 }
 
 type ServerConfig struct {
@@ -50,6 +55,80 @@ type MinIOConfig struct {
 	BucketName      string `mapstructure:"bucket_name"`
 }
 
+type WorkerConfig struct {
+	Enabled         bool           `mapstructure:"enabled"`
+	Queues          map[string]int `mapstructure:"queues"` // queue name -> concurrency
+	PollInterval    time.Duration  `mapstructure:"poll_interval"`
+	ShutdownTimeout time.Duration  `mapstructure:"shutdown_timeout"`
+
+	// Reliable mode leases dequeued jobs instead of popping them, so jobs
+	// held by a crashed worker are redelivered once the lease expires
+	Reliable          bool          `mapstructure:"reliable"`
+	VisibilityTimeout time.Duration `mapstructure:"visibility_timeout"`
+	ReapInterval      time.Duration `mapstructure:"reap_interval"`
+
+	// DefaultTimeout bounds a single job attempt (0 disables it); Timeouts
+	// overrides it per job type
+	DefaultTimeout time.Duration            `mapstructure:"default_timeout"`
+	Timeouts       map[string]time.Duration `mapstructure:"timeouts"`
+}
+
+type SchedulerConfig struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	Interval time.Duration `mapstructure:"interval"` // how often due schedules are checked
+
+	// Only the replica holding the leader lock fires schedules; the lock
+	// expires after LockTTL if its holder stops renewing it
+	LockTTL time.Duration `mapstructure:"lock_ttl"`
+}
+
+type IdempotencyConfig struct {
+	TTL time.Duration `mapstructure:"ttl"` // how long responses are replayed for a key
+
+	// LockTTL bounds how long a key stays claimed by an in-flight request,
+	// so a crashed request does not block retries for the whole TTL
+	LockTTL time.Duration `mapstructure:"lock_ttl"`
+}
+
+type JWTConfig struct {
+	Issuer    string        `mapstructure:"issuer"`     // iss claim, checked on validation when set
+	AccessTTL time.Duration `mapstructure:"access_ttl"` // lifetime of access tokens
+
+	// SigningKey is the kid new tokens are signed with; it may be left empty
+	// when only one key can sign. Every configured key is accepted for
+	// verification, so to rotate keys add the new key, switch SigningKey to
+	// it and remove the old key once the tokens it signed have expired.
+	SigningKey string `mapstructure:"signing_key"`
+
+	// Keys are read from Keys, from the PEM files in KeysDir (the file name
+	// without .pem is the kid) and from Secret, an HS256 key with kid "default"
+	Keys    []JWTKeyConfig `mapstructure:"keys"`
+	KeysDir string         `mapstructure:"keys_dir"`
+	Secret  string         `mapstructure:"secret"`
+}
+
+// JWTKeyConfig describes one signing key. Asymmetric keys are given as PEM,
+// inline or as a file path; a key with only a public part verifies tokens
+// but cannot sign them.
+type JWTKeyConfig struct {
+	ID        string `mapstructure:"id"`
+	Algorithm string `mapstructure:"algorithm"` // HS256, RS256 or EdDSA; inferred from the PEM when empty
+	Secret    string `mapstructure:"secret"`    // HS256 only
+
+	PrivateKey     string `mapstructure:"private_key"`
+	PrivateKeyFile string `mapstructure:"private_key_file"`
+	PublicKey      string `mapstructure:"public_key"`
+	PublicKeyFile  string `mapstructure:"public_key_file"`
+}
+
+// WebAuthnConfig identifies the relying party for passkey login. Passkeys
+// are bound to RPID, so changing it invalidates every registered passkey.
+type WebAuthnConfig struct {
+	RPID    string   `mapstructure:"rp_id"`   // registrable domain of the front end, e.g. example.com
+	RPName  string   `mapstructure:"rp_name"` // shown by the browser during registration
+	Origins []string `mapstructure:"origins"` // front-end origins allowed to run the ceremonies
+}
+
 func Load() (*Config, error) {
 	viper.SetConfigName("config")
 	viper.SetConfigType("yaml")
@@ -79,6 +158,23 @@ func Load() (*Config, error) {
 	viper.SetDefault("minio.secret_access_key", "minioadmin123")
 	viper.SetDefault("minio.use_ssl", false)
 	viper.SetDefault("minio.bucket_name", "go-vibe-friend")
+	viper.SetDefault("worker.enabled", true)
+	viper.SetDefault("worker.queues", map[string]int{"default": 4})
+	viper.SetDefault("worker.poll_interval", "1s")
+	viper.SetDefault("worker.shutdown_timeout", "30s")
+	viper.SetDefault("worker.reliable", true)
+	viper.SetDefault("worker.visibility_timeout", "5m")
+	viper.SetDefault("worker.reap_interval", "30s")
+	viper.SetDefault("worker.default_timeout", "0s")
+	viper.SetDefault("scheduler.enabled", true)
+	viper.SetDefault("scheduler.interval", "15s")
+	viper.SetDefault("scheduler.lock_ttl", "45s")
+	viper.SetDefault("idempotency.ttl", "24h")
+	viper.SetDefault("idempotency.lock_ttl", "5m")
+	viper.SetDefault("jwt.access_ttl", "15m")
+	viper.SetDefault("webauthn.rp_id", "localhost")
+	viper.SetDefault("webauthn.rp_name", "go-vibe-friend")
+	viper.SetDefault("webauthn.origins", []string{"http://localhost:3000"})
 
 	// Bind environment variables
 	viper.SetEnvPrefix("APP")
@@ -112,6 +208,26 @@ func Load() (*Config, error) {
 	viper.BindEnv("minio.secret_access_key", "MINIO_SECRET_ACCESS_KEY")
 	viper.BindEnv("minio.use_ssl", "MINIO_USE_SSL")
 	viper.BindEnv("minio.bucket_name", "MINIO_BUCKET_NAME")
+	viper.BindEnv("worker.enabled", "WORKER_ENABLED")
+	viper.BindEnv("worker.poll_interval", "WORKER_POLL_INTERVAL")
+	viper.BindEnv("worker.shutdown_timeout", "WORKER_SHUTDOWN_TIMEOUT")
+	viper.BindEnv("worker.reliable", "WORKER_RELIABLE")
+	viper.BindEnv("worker.visibility_timeout", "WORKER_VISIBILITY_TIMEOUT")
+	viper.BindEnv("worker.reap_interval", "WORKER_REAP_INTERVAL")
+	viper.BindEnv("worker.default_timeout", "WORKER_DEFAULT_TIMEOUT")
+	viper.BindEnv("scheduler.enabled", "SCHEDULER_ENABLED")
+	viper.BindEnv("scheduler.interval", "SCHEDULER_INTERVAL")
+	viper.BindEnv("scheduler.lock_ttl", "SCHEDULER_LOCK_TTL")
+	viper.BindEnv("idempotency.ttl", "IDEMPOTENCY_TTL")
+	viper.BindEnv("idempotency.lock_ttl", "IDEMPOTENCY_LOCK_TTL")
+	viper.BindEnv("jwt.issuer", "JWT_ISSUER")
+	viper.BindEnv("jwt.access_ttl", "JWT_ACCESS_TTL")
+	viper.BindEnv("jwt.signing_key", "JWT_SIGNING_KEY")
+	viper.BindEnv("jwt.keys_dir", "JWT_KEYS_DIR")
+	viper.BindEnv("jwt.secret", "JWT_SECRET")
+	viper.BindEnv("webauthn.rp_id", "WEBAUTHN_RP_ID")
+	viper.BindEnv("webauthn.rp_name", "WEBAUTHN_RP_NAME")
+	viper.BindEnv("webauthn.origins", "WEBAUTHN_ORIGINS")
 
 	var config Config
 	if err := viper.Unmarshal(&config); err != nil {
@@ -42,6 +42,9 @@ type Role struct {
 	BaseModel
 	Name        string `json:"name" gorm:"uniqueIndex;not null"`
 	Description string `json:"description" gorm:"type:text"`
+
+	// RequireTwoFactor 拥有该角色的用户必须启用两步验证才能登录
+	RequireTwoFactor bool `json:"require_two_factor" gorm:"default:false"`
 	
 	// 关联
 	UserRoles []UserRole `json:"user_roles,omitempty" gorm:"foreignKey:RoleID"`
@@ -63,6 +66,10 @@ type Session struct {
 	IsRevoked    bool      `json:"is_revoked" gorm:"default:false"`
 	UserAgent    string    `json:"user_agent" gorm:"size:255"`
 	IPAddress    string    `json:"ip_address" gorm:"size:45"`
+
+	// 同一次登录轮换出的刷新令牌属于同一令牌族，RotatedAt 记录令牌被轮换的时间
+	FamilyID  string     `json:"family_id" gorm:"size:64;index"`
+	RotatedAt *time.Time `json:"rotated_at,omitempty"`
 }
 
 
@@ -91,9 +98,35 @@ type Job struct {
 	UserID      uint   `json:"user_id" gorm:"not null"`
 	Title       string `json:"title" gorm:"not null"`
 	Description string `json:"description" gorm:"type:text"`
-	Status      string `json:"status" gorm:"default:pending"` // pending, running, completed, failed
+	Status      string `json:"status" gorm:"default:pending"` // waiting, pending, running, completed, failed, cancelled
 	JobType     string `json:"job_type" gorm:"not null"`
 	Progress    int    `json:"progress" gorm:"default:0"`
 	Result      string `json:"result" gorm:"type:text"`
 	ErrorMsg    string `json:"error_msg" gorm:"type:text"`
-}
\ No newline at end of file
+	Queue       string `json:"queue" gorm:"size:100;default:default"`
+	Priority    int    `json:"priority" gorm:"default:0"`
+	Payload     string `json:"payload,omitempty" gorm:"type:text"` // JSON编码的任务参数
+	MaxRetries  int    `json:"max_retries" gorm:"default:0"`
+	Attempts    int    `json:"attempts" gorm:"default:0"`
+
+	RunAt      *time.Time `json:"run_at,omitempty"` // 计划执行时间，为空表示立即执行
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// Job 状态
+const (
+	JobStatusWaiting   = "waiting" // 等待依赖的任务完成，尚未入队
+	JobStatusPending   = "pending"
+	JobStatusRunning   = "running"
+	JobStatusCompleted = "completed"
+	JobStatusFailed    = "failed"
+	JobStatusCancelled = "cancelled"
+)
+
+// JobDependency 任务依赖关系，JobID 在 ParentID 成功完成后才会入队
+type JobDependency struct {
+	ID       uint `json:"id" gorm:"primarykey"`
+	JobID    uint `json:"job_id" gorm:"not null;uniqueIndex:idx_job_dependency"`
+	ParentID uint `json:"parent_id" gorm:"not null;uniqueIndex:idx_job_dependency;index"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// ConcurrencyLimit 任务并发上限，限制同一用户或同一任务类型同时运行的任务数量
+type ConcurrencyLimit struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Scope      string `json:"scope" gorm:"size:20;not null;uniqueIndex:idx_concurrency_limit"`   // user, job_type
+	Target     string `json:"target" gorm:"size:100;not null;uniqueIndex:idx_concurrency_limit"` // 用户ID或任务类型，* 表示默认值
+	MaxRunning int    `json:"max_running" gorm:"not null"`                                       // 0 表示暂停执行
+}
+
+// 并发上限的作用范围
+const (
+	ConcurrencyScopeUser    = "user"
+	ConcurrencyScopeJobType = "job_type"
+
+	// ConcurrencyTargetDefault 适用于没有单独设置上限的所有用户或任务类型
+	ConcurrencyTargetDefault = "*"
+)
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// ExportFile 导出文件记录，文件内容保存在对象存储的 exports/ 目录下
+type ExportFile struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	UserID      uint      `json:"user_id" gorm:"not null;index"`                   // 发起导出的用户
+	JobID       *uint     `json:"job_id,omitempty" gorm:"index"`                   // 生成文件的任务
+	DataType    string    `json:"data_type" gorm:"size:50;not null"`               // users, jobs, files, emails, permissions, system_report, personal_data
+	Format      string    `json:"format" gorm:"size:20;not null"`                  // csv, json, jsonl, xlsx, zip
+	FileName    string    `json:"file_name" gorm:"size:255;not null"`              // 下载时的文件名
+	ObjectKey   string    `json:"object_key" gorm:"size:500;not null;uniqueIndex"` // 对象存储中的路径
+	ContentType string    `json:"content_type" gorm:"size:100"`
+	FileSize    int64     `json:"file_size"`
+	RecordCount int       `json:"record_count"`
+	ExpiresAt   time.Time `json:"expires_at" gorm:"index;not null"`
+	CreatedAt   time.Time `json:"created_at"`
+}
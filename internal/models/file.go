@@ -25,7 +25,10 @@ type File struct {
 	UserID   uint   `gorm:"not null;index" json:"user_id"`
 	
 	// 文件分类
-	Category string `gorm:"size:50;not null;default:'general'" json:"category"` // avatar, document, image, etc.
+	Category string `gorm:"size:50;not null;default:'general'" json:"category"` // avatar, document, image, job_artifact, etc.
+	
+	// 生成该文件的任务（任务产物）
+	JobID *uint `gorm:"index" json:"job_id,omitempty"`
 	
 	// 访问控制
 	IsPublic bool `gorm:"default:false" json:"is_public"`
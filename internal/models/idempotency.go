@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// IdempotencyKey 幂等键记录，Redis 不可用时保存首次请求的响应
+type IdempotencyKey struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Key         string    `json:"key" gorm:"column:idempotency_key;size:64;uniqueIndex;not null"` // 用户、路由和 Idempotency-Key 的哈希
+	Fingerprint string    `json:"fingerprint" gorm:"size:64"`                                     // 请求体哈希
+	StatusCode  int       `json:"status_code" gorm:"default:0"`                                   // 0 表示首次请求仍在处理中
+	Headers     string    `json:"headers" gorm:"type:text"`                                       // JSON编码的响应头
+	Body        []byte    `json:"-"`
+	ExpiresAt   time.Time `json:"expires_at" gorm:"index;not null"`
+	CreatedAt   time.Time `json:"created_at"`
+}
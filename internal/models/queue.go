@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// QueueJob 数据库任务队列中的一条记录（Redis 不可用时使用）
+type QueueJob struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Queue    string    `json:"queue" gorm:"size:100;not null;index:idx_queue_jobs_pick,priority:1"`
+	State    string    `json:"state" gorm:"size:20;not null;index:idx_queue_jobs_pick,priority:2"` // ready, inflight, dead
+	Priority int       `json:"priority" gorm:"not null;default:0"`
+	RunAt    time.Time `json:"run_at" gorm:"not null;index:idx_queue_jobs_pick,priority:3"` // ready: 可执行时间；inflight: 租约到期时间；dead: 失败时间
+	JobKey   string    `json:"job_key" gorm:"size:100;index"`                               // 队列中的任务ID
+	UserID   uint      `json:"user_id" gorm:"not null;default:0"`                           // 用于按并发上限跳过任务
+	JobType  string    `json:"job_type" gorm:"size:100"`
+	Data     string    `json:"data" gorm:"type:text;not null"` // JSON编码的队列任务
+}
+
+// 数据库队列记录状态
+const (
+	QueueJobStateReady    = "ready"
+	QueueJobStateInflight = "inflight"
+	QueueJobStateDead     = "dead"
+)
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// RecurringJob 周期任务定义，按 cron 表达式定时提交任务
+type RecurringJob struct {
+	BaseModel
+	Name        string `json:"name" gorm:"size:100;uniqueIndex;not null"`
+	Description string `json:"description" gorm:"type:text"`
+	CronExpr    string `json:"cron_expr" gorm:"size:100;not null"` // 5 段 cron 表达式或 @daily 等别名
+	JobType     string `json:"job_type" gorm:"size:100;not null"`
+	Queue       string `json:"queue" gorm:"size:100;default:default"`
+	Priority    int    `json:"priority" gorm:"default:0"`
+	MaxRetries  int    `json:"max_retries" gorm:"default:0"`
+	Payload     string `json:"payload,omitempty" gorm:"type:text"` // JSON编码的任务参数
+	Enabled     bool   `json:"enabled"`
+	CreatedBy   uint   `json:"created_by" gorm:"default:0"` // 提交任务时使用的用户，0 表示系统
+
+	NextRunAt *time.Time `json:"next_run_at,omitempty" gorm:"index"`
+	LastRunAt *time.Time `json:"last_run_at,omitempty"`
+	RunCount  int        `json:"run_count" gorm:"default:0"` // 已触发次数，同时用作抢占执行的版本号
+	LastJobID *uint      `json:"last_job_id,omitempty"`
+	LastError string     `json:"last_error,omitempty" gorm:"type:text"`
+}
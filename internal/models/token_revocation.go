@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// RevokedToken 已撤销的访问令牌，Redis 不可用时保存在数据库中
+type RevokedToken struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	JTI       string    `json:"jti" gorm:"size:64;uniqueIndex;not null"`
+	UserID    uint      `json:"user_id" gorm:"index"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"index;not null"` // 令牌过期时间，之后记录可以清理
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TokenWatermark 用户的令牌水位线，不晚于 RevokedBefore 签发的访问令牌全部失效
+type TokenWatermark struct {
+	UserID        uint      `json:"user_id" gorm:"primaryKey;autoIncrement:false"`
+	RevokedBefore time.Time `json:"revoked_before" gorm:"not null"`
+	ExpiresAt     time.Time `json:"expires_at" gorm:"index;not null"` // 此前签发的令牌都已过期，之后记录可以清理
+	UpdatedAt     time.Time `json:"updated_at"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// TwoFactorAuth 用户的 TOTP 两步验证设置，确认首个验证码后才启用
+type TwoFactorAuth struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	UserID       uint       `json:"user_id" gorm:"uniqueIndex;not null"`
+	Secret       string     `json:"-" gorm:"size:64;not null"` // base32 编码的 TOTP 密钥
+	Enabled      bool       `json:"enabled" gorm:"default:false"`
+	ConfirmedAt  *time.Time `json:"confirmed_at,omitempty"`
+	LastUsedStep int64      `json:"-" gorm:"default:0"` // 最近一次使用的验证码时间步，防止验证码被重复使用
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// TwoFactorRecoveryCode 一次性恢复码，只保存哈希
+type TwoFactorRecoveryCode struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"index;not null"`
+	CodeHash  string     `json:"-" gorm:"size:64;not null"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// TwoFactorChallenge 登录第一步通过后签发的短期质询，凭质询令牌完成第二步验证
+type TwoFactorChallenge struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	UserID        uint      `json:"user_id" gorm:"index;not null"`
+	TokenHash     string    `json:"-" gorm:"size:64;uniqueIndex;not null"`
+	SetupRequired bool      `json:"setup_required" gorm:"default:false"` // 角色要求两步验证但用户尚未启用
+	Attempts      int       `json:"attempts" gorm:"default:0"`
+	ExpiresAt     time.Time `json:"expires_at" gorm:"index;not null"`
+	CreatedAt     time.Time `json:"created_at"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// WebAuthnCredential 用户登记的通行密钥（WebAuthn 凭据）
+type WebAuthnCredential struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	UserID         uint       `json:"user_id" gorm:"index;not null"`
+	User           User       `json:"-" gorm:"foreignKey:UserID"`
+	Name           string     `json:"name" gorm:"size:64;not null"`
+	CredentialID   string     `json:"credential_id" gorm:"size:1400;uniqueIndex;not null"` // base64url 编码
+	PublicKey      []byte     `json:"-" gorm:"not null"`                                   // COSE 编码的公钥
+	Algorithm      int64      `json:"algorithm"`
+	AAGUID         string     `json:"aaguid" gorm:"size:36"` // 认证器型号
+	Transports     string     `json:"transports" gorm:"size:255"`
+	SignCount      uint32     `json:"sign_count" gorm:"default:0"`
+	BackupEligible bool       `json:"backup_eligible" gorm:"default:false"`
+	BackupState    bool       `json:"backup_state" gorm:"default:false"`
+	CloneDetected  bool       `json:"clone_detected" gorm:"default:false"` // 签名计数器回退，凭据可能被复制，已停用
+	LastUsedAt     *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// WebAuthnChallenge 注册或登录仪式的一次性质询，按浏览器在 clientDataJSON 中返回的质询查找
+type WebAuthnChallenge struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	UserID        uint      `json:"user_id" gorm:"index"` // 可发现凭据登录时为 0
+	Ceremony      string    `json:"ceremony" gorm:"size:16;not null"`
+	ChallengeHash string    `json:"-" gorm:"size:64;uniqueIndex;not null"`
+	ExpiresAt     time.Time `json:"expires_at" gorm:"index;not null"`
+	CreatedAt     time.Time `json:"created_at"`
+}
@@ -1,8 +1,12 @@
 package service
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"time"
 
 	"go-vibe-friend/internal/models"
@@ -10,15 +14,31 @@ import (
 	"go-vibe-friend/internal/utils"
 )
 
+// refreshTokenTTL 刷新令牌有效期
+const refreshTokenTTL = 7 * 24 * time.Hour
+
+var (
+	// ErrInvalidRefreshToken 刷新令牌不存在、已撤销或已过期
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// ErrRefreshTokenReused 已轮换的刷新令牌被再次使用，所在令牌族已被撤销
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+	// ErrUserNotFound 用户不存在
+	ErrUserNotFound = errors.New("user not found")
+	// ErrInvalidUserStatus 用户状态不是 active、inactive 或 banned
+	ErrInvalidUserStatus = errors.New("invalid user status")
+)
+
 type AuthService struct {
-	userStore    *store.UserStore
-	sessionStore store.SessionStoreInterface
+	userStore              *store.UserStore
+	sessionStore           store.SessionStoreInterface
+	tokenRevocationService *TokenRevocationService
 }
 
-func NewAuthService(userStore *store.UserStore, sessionStore store.SessionStoreInterface) *AuthService {
+func NewAuthService(userStore *store.UserStore, sessionStore store.SessionStoreInterface, tokenRevocationService *TokenRevocationService) *AuthService {
 	return &AuthService{
-		userStore:    userStore,
-		sessionStore: sessionStore,
+		userStore:              userStore,
+		sessionStore:           sessionStore,
+		tokenRevocationService: tokenRevocationService,
 	}
 }
 
@@ -39,8 +59,9 @@ type LoginRequest struct {
 }
 
 type AuthResponse struct {
-	Token string      `json:"token"`
-	User  models.User `json:"user"`
+	Token         string      `json:"token"`
+	User          models.User `json:"user"`
+	RecoveryCodes []string    `json:"recovery_codes,omitempty"` // 登录时完成两步验证登记才返回
 }
 
 // UserExists 检查用户是否存在
@@ -149,12 +170,18 @@ func (s *AuthService) GenerateTokens(user *models.User) (string, string, error)
 	return accessToken, refreshToken, nil
 }
 
-// CreateSession 创建会话
+// CreateSession 创建会话，每次登录开始一个新的令牌族
 func (s *AuthService) CreateSession(userID uint, refreshToken, ipAddress, userAgent string) error {
+	familyID, err := newSessionFamilyID()
+	if err != nil {
+		return fmt.Errorf("failed to generate session family: %w", err)
+	}
+
 	session := &models.Session{
 		UserID:       userID,
 		RefreshToken: refreshToken,
-		ExpiresAt:    time.Now().Add(7 * 24 * time.Hour), // 7天有效期
+		FamilyID:     familyID,
+		ExpiresAt:    time.Now().Add(refreshTokenTTL),
 		IPAddress:    ipAddress,
 		UserAgent:    userAgent,
 	}
@@ -162,62 +189,149 @@ func (s *AuthService) CreateSession(userID uint, refreshToken, ipAddress, userAg
 	return s.sessionStore.CreateSession(session)
 }
 
-// ValidateRefreshToken 验证刷新令牌
-func (s *AuthService) ValidateRefreshToken(refreshToken string) (*models.User, error) {
-	// 获取会话
+// RefreshSession 轮换刷新令牌，返回新的访问令牌和刷新令牌。旧令牌被标记为已轮换，
+// 新令牌加入同一令牌族；已轮换的令牌再次出现说明令牌可能已泄露，
+// 此时撤销整个令牌族、记录安全事件并返回 ErrRefreshTokenReused
+func (s *AuthService) RefreshSession(refreshToken, ipAddress, userAgent string) (string, string, error) {
+	// 先读取旧会话再轮换，轮换后旧会话仍保留用于识别重放
 	session, err := s.sessionStore.GetSessionByToken(refreshToken)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get session: %w", err)
+		return "", "", fmt.Errorf("failed to get session: %w", err)
 	}
 	if session == nil {
-		return nil, errors.New("invalid refresh token")
+		return "", "", ErrInvalidRefreshToken
 	}
-
-	// 检查会话是否已撤销
-	if session.IsRevoked {
-		return nil, errors.New("refresh token has been revoked")
+	if session.RotatedAt != nil {
+		return "", "", s.revokeReusedFamily(session, ipAddress, userAgent)
 	}
-
-	// 检查会话是否已过期
-	if time.Now().After(session.ExpiresAt) {
-		return nil, errors.New("refresh token has expired")
+	if session.IsRevoked || time.Now().After(session.ExpiresAt) {
+		return "", "", ErrInvalidRefreshToken
 	}
 
-	// 获取用户信息
 	user, err := s.userStore.GetUserByID(session.UserID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user: %w", err)
+		return "", "", fmt.Errorf("failed to get user: %w", err)
 	}
-	if user == nil {
-		return nil, errors.New("user not found")
+	if user == nil || user.Status != "active" {
+		return "", "", ErrInvalidRefreshToken
 	}
 
-	return user, nil
+	accessToken, newRefreshToken, err := s.GenerateTokens(user)
+	if err != nil {
+		return "", "", err
+	}
+
+	// 轮换前创建的会话没有令牌族，从这次轮换开始一个
+	familyID := session.FamilyID
+	if familyID == "" {
+		if familyID, err = newSessionFamilyID(); err != nil {
+			return "", "", fmt.Errorf("failed to generate session family: %w", err)
+		}
+	}
+
+	next := &models.Session{
+		UserID:       session.UserID,
+		RefreshToken: newRefreshToken,
+		FamilyID:     familyID,
+		ExpiresAt:    time.Now().Add(refreshTokenTTL),
+		IPAddress:    ipAddress,
+		UserAgent:    userAgent,
+	}
+	if err := s.sessionStore.RotateSession(refreshToken, next); err != nil {
+		if errors.Is(err, store.ErrSessionRotated) {
+			// 同一令牌的另一个请求已先完成轮换
+			session.FamilyID = familyID
+			return "", "", s.revokeReusedFamily(session, ipAddress, userAgent)
+		}
+		return "", "", fmt.Errorf("failed to rotate session: %w", err)
+	}
+
+	return accessToken, newRefreshToken, nil
 }
 
-// RefreshSession 刷新会话
-func (s *AuthService) RefreshSession(oldRefreshToken, newRefreshToken, ipAddress, userAgent string) error {
-	// 撤销旧会话
-	if err := s.sessionStore.RevokeSession(oldRefreshToken); err != nil {
-		return fmt.Errorf("failed to revoke old session: %w", err)
+// revokeReusedFamily 撤销重放令牌所在的令牌族并记录安全事件，返回 ErrRefreshTokenReused
+func (s *AuthService) revokeReusedFamily(session *models.Session, ipAddress, userAgent string) error {
+	if err := s.sessionStore.RevokeSessionFamily(session.FamilyID); err != nil {
+		return fmt.Errorf("failed to revoke session family: %w", err)
 	}
 
-	// 获取旧会话信息
-	oldSession, err := s.sessionStore.GetSessionByToken(oldRefreshToken)
-	if err != nil {
-		return fmt.Errorf("failed to get old session: %w", err)
+	log.Printf("Refresh token reuse detected for user %d, revoked session family %s", session.UserID, session.FamilyID)
+
+	details, _ := json.Marshal(map[string]interface{}{
+		"family_id":  session.FamilyID,
+		"session_id": session.ID,
+		"rotated_at": session.RotatedAt,
+	})
+	auditLog := &models.AuditLog{
+		ActorID:   session.UserID,
+		Resource:  "session",
+		Action:    "refresh_token_reuse",
+		Details:   string(details),
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
 	}
-	if oldSession == nil {
-		return errors.New("old session not found")
+	if err := s.userStore.CreateAuditLog(auditLog); err != nil {
+		log.Printf("Failed to record refresh token reuse for user %d: %v", session.UserID, err)
 	}
 
-	// 创建新会话
-	return s.CreateSession(oldSession.UserID, newRefreshToken, ipAddress, userAgent)
+	return ErrRefreshTokenReused
+}
+
+// newSessionFamilyID 生成令牌族ID
+func newSessionFamilyID() (string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
 }
 
-// RevokeAllSessions 撤销用户的所有会话
+// RevokeAllSessions 撤销用户的所有会话，已签发的访问令牌同时失效
 func (s *AuthService) RevokeAllSessions(userID uint) error {
-	return s.sessionStore.RevokeAllUserSessions(userID)
+	if err := s.sessionStore.RevokeAllUserSessions(userID); err != nil {
+		return err
+	}
+	if err := s.tokenRevocationService.RevokeUserTokens(userID); err != nil {
+		return fmt.Errorf("failed to revoke access tokens: %w", err)
+	}
+	return nil
+}
+
+// Logout 撤销当前访问令牌和用户的所有会话
+func (s *AuthService) Logout(claims *utils.Claims) error {
+	if err := s.tokenRevocationService.RevokeToken(claims); err != nil {
+		return fmt.Errorf("failed to revoke access token: %w", err)
+	}
+	return s.RevokeAllSessions(claims.UserID)
+}
+
+// UpdateUserStatus 更新用户状态，停用或封禁用户时立即撤销其会话和访问令牌
+func (s *AuthService) UpdateUserStatus(userID uint, status string) (*models.User, error) {
+	if !containsString(userStatuses, status) {
+		return nil, ErrInvalidUserStatus
+	}
+
+	user, err := s.userStore.GetUserByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	user.Status = status
+	if err := s.userStore.UpdateUser(user); err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+
+	if status != "active" {
+		if err := s.RevokeAllSessions(userID); err != nil {
+			return nil, err
+		}
+	}
+
+	user.Password = ""
+	return user, nil
 }
 
 // GetUserByID 根据ID获取用户
@@ -264,13 +378,8 @@ func (s *AuthService) Register(req *RegisterRequest) (*AuthResponse, error) {
 	}, nil
 }
 
-func (s *AuthService) Login(req *LoginRequest) (*AuthResponse, error) {
-	// 验证用户
-	user, err := s.ValidateUser(req.Email, req.Password)
-	if err != nil {
-		return nil, err
-	}
-
+// LoginUser 为已通过验证的用户签发访问令牌，启用两步验证的用户需先完成第二步验证
+func (s *AuthService) LoginUser(user *models.User) (*AuthResponse, error) {
 	// 生成令牌
 	accessToken, _, err := s.GenerateTokens(user)
 	if err != nil {
@@ -284,4 +393,4 @@ func (s *AuthService) Login(req *LoginRequest) (*AuthResponse, error) {
 		Token: accessToken,
 		User:  *user,
 	}, nil
-}
\ No newline at end of file
+}
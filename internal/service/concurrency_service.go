@@ -0,0 +1,237 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"go-vibe-friend/internal/models"
+	"go-vibe-friend/internal/store"
+)
+
+var (
+	// ErrInvalidConcurrencyLimit 并发上限参数错误
+	ErrInvalidConcurrencyLimit = errors.New("invalid concurrency limit")
+	// ErrConcurrencyLimitNotFound 并发上限不存在
+	ErrConcurrencyLimitNotFound = errors.New("concurrency limit not found")
+)
+
+// limitCacheTTL 并发上限配置在进程内的缓存时间，修改后其他实例最多延迟这么久生效
+const limitCacheTTL = 5 * time.Second
+
+// ConcurrencyService 管理任务并发上限：同一用户、同一任务类型同时运行的任务数量。
+// 达到上限的用户或任务类型的任务会被 worker 跳过，让其他用户的任务先执行。
+type ConcurrencyService struct {
+	limitStore *store.ConcurrencyLimitStore
+	jobStore   *store.JobStore
+
+	mu       sync.Mutex
+	limits   []models.ConcurrencyLimit
+	loadedAt time.Time
+}
+
+func NewConcurrencyService(limitStore *store.ConcurrencyLimitStore, jobStore *store.JobStore) *ConcurrencyService {
+	return &ConcurrencyService{
+		limitStore: limitStore,
+		jobStore:   jobStore,
+	}
+}
+
+// ConcurrencyLimitRequest 设置并发上限请求
+type ConcurrencyLimitRequest struct {
+	Scope      string `json:"scope" binding:"required"`  // user 或 job_type
+	Target     string `json:"target" binding:"required"` // 用户ID或任务类型，* 表示默认值
+	MaxRunning *int   `json:"max_running" binding:"required,min=0"`
+}
+
+// ConcurrencyUsage 当前运行中的任务数量
+type ConcurrencyUsage struct {
+	Users    map[uint]int64   `json:"users"`
+	JobTypes map[string]int64 `json:"job_types"`
+}
+
+// ListLimits 获取所有并发上限
+func (s *ConcurrencyService) ListLimits() ([]models.ConcurrencyLimit, error) {
+	return s.limitStore.ListLimits()
+}
+
+// SetLimit 创建或更新并发上限
+func (s *ConcurrencyService) SetLimit(req *ConcurrencyLimitRequest) (*models.ConcurrencyLimit, error) {
+	if req.MaxRunning == nil || *req.MaxRunning < 0 {
+		return nil, fmt.Errorf("%w: max_running must be at least 0", ErrInvalidConcurrencyLimit)
+	}
+	switch req.Scope {
+	case models.ConcurrencyScopeUser:
+		if req.Target != models.ConcurrencyTargetDefault {
+			if id, err := strconv.ParseUint(req.Target, 10, 32); err != nil || id == 0 {
+				return nil, fmt.Errorf("%w: target must be a user ID or %q", ErrInvalidConcurrencyLimit, models.ConcurrencyTargetDefault)
+			}
+		}
+	case models.ConcurrencyScopeJobType:
+		if req.Target == "" {
+			return nil, fmt.Errorf("%w: target must be a job type or %q", ErrInvalidConcurrencyLimit, models.ConcurrencyTargetDefault)
+		}
+	default:
+		return nil, fmt.Errorf("%w: scope must be %q or %q", ErrInvalidConcurrencyLimit, models.ConcurrencyScopeUser, models.ConcurrencyScopeJobType)
+	}
+
+	limit := &models.ConcurrencyLimit{
+		Scope:      req.Scope,
+		Target:     req.Target,
+		MaxRunning: *req.MaxRunning,
+	}
+	if err := s.limitStore.UpsertLimit(limit); err != nil {
+		return nil, fmt.Errorf("failed to save concurrency limit: %w", err)
+	}
+
+	s.invalidate()
+	return limit, nil
+}
+
+// DeleteLimit 删除并发上限
+func (s *ConcurrencyService) DeleteLimit(id uint) error {
+	limit, err := s.limitStore.GetLimitByID(id)
+	if err != nil {
+		return fmt.Errorf("failed to get concurrency limit: %w", err)
+	}
+	if limit == nil {
+		return ErrConcurrencyLimitNotFound
+	}
+	if err := s.limitStore.DeleteLimit(id); err != nil {
+		return fmt.Errorf("failed to delete concurrency limit: %w", err)
+	}
+
+	s.invalidate()
+	return nil
+}
+
+// Usage 获取每个用户和任务类型当前运行中的任务数量
+func (s *ConcurrencyService) Usage() (*ConcurrencyUsage, error) {
+	users, err := s.jobStore.CountRunningByUser()
+	if err != nil {
+		return nil, fmt.Errorf("failed to count running jobs: %w", err)
+	}
+	types, err := s.jobStore.CountRunningByType()
+	if err != nil {
+		return nil, fmt.Errorf("failed to count running jobs: %w", err)
+	}
+	return &ConcurrencyUsage{Users: users, JobTypes: types}, nil
+}
+
+// LimitsFor 返回用户和任务类型的并发上限，-1 表示不限制
+func (s *ConcurrencyService) LimitsFor(userID uint, jobType string) (int, int, error) {
+	limits, err := s.cachedLimits()
+	if err != nil {
+		return 0, 0, err
+	}
+	return lookupLimit(limits, models.ConcurrencyScopeUser, strconv.FormatUint(uint64(userID), 10)),
+		lookupLimit(limits, models.ConcurrencyScopeJobType, jobType), nil
+}
+
+// ReserveFilter 返回已达到并发上限的用户和任务类型，worker 取任务时跳过它们
+func (s *ConcurrencyService) ReserveFilter() (store.ReserveFilter, error) {
+	var filter store.ReserveFilter
+
+	limits, err := s.cachedLimits()
+	if err != nil || len(limits) == 0 {
+		return filter, err
+	}
+
+	var userLimits, typeLimits bool
+	for _, limit := range limits {
+		if limit.Scope == models.ConcurrencyScopeUser {
+			userLimits = true
+		} else {
+			typeLimits = true
+		}
+	}
+
+	if userLimits {
+		running, err := s.jobStore.CountRunningByUser()
+		if err != nil {
+			return filter, err
+		}
+		for userID, count := range running {
+			limit := lookupLimit(limits, models.ConcurrencyScopeUser, strconv.FormatUint(uint64(userID), 10))
+			if limit >= 0 && count >= int64(limit) {
+				filter.SkipUsers = append(filter.SkipUsers, userID)
+			}
+		}
+		// 上限为 0 的用户即使没有运行中的任务也要跳过
+		for _, limit := range limits {
+			if limit.Scope == models.ConcurrencyScopeUser && limit.MaxRunning == 0 && limit.Target != models.ConcurrencyTargetDefault {
+				if userID, err := strconv.ParseUint(limit.Target, 10, 32); err == nil && running[uint(userID)] == 0 {
+					filter.SkipUsers = append(filter.SkipUsers, uint(userID))
+				}
+			}
+		}
+	}
+
+	if typeLimits {
+		running, err := s.jobStore.CountRunningByType()
+		if err != nil {
+			return filter, err
+		}
+		for jobType, count := range running {
+			limit := lookupLimit(limits, models.ConcurrencyScopeJobType, jobType)
+			if limit >= 0 && count >= int64(limit) {
+				filter.SkipTypes = append(filter.SkipTypes, jobType)
+			}
+		}
+		for _, limit := range limits {
+			if limit.Scope == models.ConcurrencyScopeJobType && limit.MaxRunning == 0 && limit.Target != models.ConcurrencyTargetDefault && running[limit.Target] == 0 {
+				filter.SkipTypes = append(filter.SkipTypes, limit.Target)
+			}
+		}
+	}
+
+	sort.Slice(filter.SkipUsers, func(i, j int) bool { return filter.SkipUsers[i] < filter.SkipUsers[j] })
+	sort.Strings(filter.SkipTypes)
+	return filter, nil
+}
+
+// lookupLimit 优先使用单独设置的上限，其次是默认值，都没有时返回 -1
+func lookupLimit(limits []models.ConcurrencyLimit, scope, target string) int {
+	fallback := -1
+	for _, limit := range limits {
+		if limit.Scope != scope {
+			continue
+		}
+		if limit.Target == target {
+			return limit.MaxRunning
+		}
+		if limit.Target == models.ConcurrencyTargetDefault {
+			fallback = limit.MaxRunning
+		}
+	}
+	return fallback
+}
+
+func (s *ConcurrencyService) cachedLimits() ([]models.ConcurrencyLimit, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.limits != nil && time.Since(s.loadedAt) < limitCacheTTL {
+		return s.limits, nil
+	}
+
+	limits, err := s.limitStore.ListLimits()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load concurrency limits: %w", err)
+	}
+	if limits == nil {
+		limits = []models.ConcurrencyLimit{}
+	}
+	s.limits = limits
+	s.loadedAt = time.Now()
+	return limits, nil
+}
+
+func (s *ConcurrencyService) invalidate() {
+	s.mu.Lock()
+	s.limits = nil
+	s.mu.Unlock()
+}
@@ -5,11 +5,13 @@ import (
 	"crypto/tls"
 	"encoding/hex"
 	"fmt"
+	"html"
 	"net/smtp"
 	"time"
 
 	"go-vibe-friend/internal/models"
 	"go-vibe-friend/internal/store"
+	"go-vibe-friend/internal/utils"
 )
 
 type EmailService struct {
@@ -82,6 +84,32 @@ func (s *EmailService) SendPasswordResetEmail(userID uint, email string) error {
 	return s.sendEmail(email, subject, body, "password_reset", &userID)
 }
 
+// SendInvitationEmail 发送账号邀请邮件，用户通过密码重置令牌设置初始密码
+func (s *EmailService) SendInvitationEmail(userID uint, email, username string) error {
+	// 生成设置密码的令牌
+	token := s.generateToken()
+	
+	reset := &models.PasswordReset{
+		UserID:    userID,
+		Email:     email,
+		Token:     token,
+		ExpiresAt: time.Now().Add(invitationExpiry),
+	}
+	
+	if err := s.emailStore.CreatePasswordReset(reset); err != nil {
+		return fmt.Errorf("创建邀请记录失败: %v", err)
+	}
+	
+	// 发送邮件
+	subject := "您已受邀加入"
+	body := s.buildInvitationEmailBody(username, token)
+	
+	return s.sendEmail(email, subject, body, "invitation", &userID)
+}
+
+// invitationExpiry 邀请邮件中设置密码链接的有效期
+const invitationExpiry = 7 * 24 * time.Hour
+
 // VerifyEmail 验证邮箱
 func (s *EmailService) VerifyEmail(token string) error {
 	verification, err := s.emailStore.GetEmailVerificationByToken(token)
@@ -109,31 +137,42 @@ func (s *EmailService) VerifyEmail(token string) error {
 	return s.emailStore.UpdateEmailVerification(verification)
 }
 
-// ResetPassword 重置密码
-func (s *EmailService) ResetPassword(token, newPassword string) error {
+// ResetPassword 校验重置令牌并设置新密码，邀请的用户也通过它设置初始密码。
+// 返回用户ID，调用方据此撤销用户现有的会话和访问令牌
+func (s *EmailService) ResetPassword(token, newPassword string) (uint, error) {
 	reset, err := s.emailStore.GetPasswordResetByToken(token)
 	if err != nil {
-		return fmt.Errorf("重置记录不存在: %v", err)
+		return 0, fmt.Errorf("重置记录不存在: %v", err)
 	}
 	
 	if reset == nil {
-		return fmt.Errorf("无效的重置令牌")
+		return 0, fmt.Errorf("无效的重置令牌")
 	}
 	
 	if reset.IsUsed {
-		return fmt.Errorf("重置令牌已使用")
+		return 0, fmt.Errorf("重置令牌已使用")
 	}
 	
 	if time.Now().After(reset.ExpiresAt) {
-		return fmt.Errorf("重置令牌已过期")
+		return 0, fmt.Errorf("重置令牌已过期")
 	}
 	
-	// 标记为已使用
-	now := time.Now()
-	reset.IsUsed = true
-	reset.UsedAt = &now
+	// 加密新密码
+	passwordHash, err := utils.HashPassword(newPassword)
+	if err != nil {
+		return 0, fmt.Errorf("密码加密失败: %v", err)
+	}
 	
-	return s.emailStore.UpdatePasswordReset(reset)
+	// 标记令牌已使用并更新密码
+	used, err := s.emailStore.ResetPassword(reset, passwordHash)
+	if err != nil {
+		return 0, fmt.Errorf("更新密码失败: %v", err)
+	}
+	if !used {
+		return 0, fmt.Errorf("重置令牌已使用")
+	}
+	
+	return reset.UserID, nil
 }
 
 // sendEmail 发送邮件
@@ -300,6 +339,33 @@ func (s *EmailService) buildPasswordResetEmailBody(token string) string {
 	`, resetURL, resetURL)
 }
 
+// buildInvitationEmailBody 构建账号邀请邮件内容
+func (s *EmailService) buildInvitationEmailBody(username, token string) string {
+	setupURL := fmt.Sprintf("http://localhost:3000/reset-password?token=%s", token)
+	
+	return fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>账号邀请</title>
+</head>
+<body>
+    <div style="max-width: 600px; margin: 0 auto; padding: 20px; font-family: Arial, sans-serif;">
+        <h2>欢迎加入</h2>
+        <p>%s，您好！</p>
+        <p>管理员已为您创建了账号。</p>
+        <p>请点击下面的链接设置您的密码：</p>
+        <p><a href="%s" style="background-color: #007bff; color: white; padding: 10px 20px; text-decoration: none; border-radius: 5px;">设置密码</a></p>
+        <p>如果您无法点击上面的链接，请复制以下地址到浏览器中打开：</p>
+        <p>%s</p>
+        <p>此链接将在7天后过期。</p>
+    </div>
+</body>
+</html>
+	`, html.EscapeString(username), setupURL, setupURL)
+}
+
 // IsEmailVerified 检查邮箱是否已验证
 func (s *EmailService) IsEmailVerified(userID uint, email string) (bool, error) {
 	verifications, err := s.emailStore.GetEmailVerificationsByUserID(userID)
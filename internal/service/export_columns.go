@@ -0,0 +1,415 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"go-vibe-friend/internal/models"
+	"go-vibe-friend/internal/store"
+)
+
+// exportColumn 导出列。Key 同时是 include_data 中的列名和 JSON 字段名，
+// Default 为 true 的列在未指定 include_data 时导出
+type exportColumn[T any] struct {
+	Key     string
+	Header  string
+	Default bool
+	Value   func(*T) interface{}
+}
+
+// exportFilterField 可用于 filters 的字段；Values 非空时只接受其中的值
+type exportFilterField struct {
+	Column string
+	Values []string
+}
+
+// ExportOption 可选的列或筛选字段，用于向管理员展示
+type ExportOption struct {
+	Key     string   `json:"key"`
+	Name    string   `json:"name,omitempty"`
+	Default bool     `json:"default,omitempty"`
+	Values  []string `json:"values,omitempty"`
+}
+
+// exportTimeLayout CSV 中时间的格式
+const exportTimeLayout = "2006-01-02 15:04:05"
+
+// exportDateLayout date_range 中只有日期时的格式
+const exportDateLayout = "2006-01-02"
+
+// exportBatchSize 每次从数据库读取的记录数
+const exportBatchSize = 500
+
+var userExportColumns = []exportColumn[models.User]{
+	{"id", "ID", true, func(u *models.User) interface{} { return u.ID }},
+	{"username", "用户名", true, func(u *models.User) interface{} { return u.Username }},
+	{"email", "邮箱", true, func(u *models.User) interface{} { return u.Email }},
+	{"status", "状态", true, func(u *models.User) interface{} { return u.Status }},
+	{"created_at", "创建时间", true, func(u *models.User) interface{} { return u.CreatedAt }},
+	{"updated_at", "更新时间", true, func(u *models.User) interface{} { return u.UpdatedAt }},
+}
+
+var jobExportColumns = []exportColumn[models.Job]{
+	{"id", "ID", true, func(j *models.Job) interface{} { return j.ID }},
+	{"user_id", "用户ID", true, func(j *models.Job) interface{} { return j.UserID }},
+	{"title", "标题", false, func(j *models.Job) interface{} { return j.Title }},
+	{"status", "状态", true, func(j *models.Job) interface{} { return j.Status }},
+	{"job_type", "任务类型", true, func(j *models.Job) interface{} { return j.JobType }},
+	{"queue", "队列", false, func(j *models.Job) interface{} { return j.Queue }},
+	{"priority", "优先级", false, func(j *models.Job) interface{} { return j.Priority }},
+	{"progress", "进度", false, func(j *models.Job) interface{} { return j.Progress }},
+	{"attempts", "执行次数", false, func(j *models.Job) interface{} { return j.Attempts }},
+	{"error_msg", "错误信息", false, func(j *models.Job) interface{} { return j.ErrorMsg }},
+	{"created_at", "创建时间", true, func(j *models.Job) interface{} { return j.CreatedAt }},
+	{"updated_at", "更新时间", true, func(j *models.Job) interface{} { return j.UpdatedAt }},
+	{"started_at", "开始时间", false, func(j *models.Job) interface{} { return j.StartedAt }},
+	{"finished_at", "结束时间", false, func(j *models.Job) interface{} { return j.FinishedAt }},
+}
+
+var fileExportColumns = []exportColumn[models.File]{
+	{"id", "ID", true, func(f *models.File) interface{} { return f.ID }},
+	{"file_name", "文件名", true, func(f *models.File) interface{} { return f.FileName }},
+	{"original_name", "原始名称", true, func(f *models.File) interface{} { return f.OriginalName }},
+	{"file_size", "大小", true, func(f *models.File) interface{} { return f.FileSize }},
+	{"mime_type", "类型", true, func(f *models.File) interface{} { return f.MimeType }},
+	{"category", "分类", true, func(f *models.File) interface{} { return f.Category }},
+	{"user_id", "用户ID", true, func(f *models.File) interface{} { return f.UserID }},
+	{"job_id", "任务ID", false, func(f *models.File) interface{} { return f.JobID }},
+	{"is_public", "公开", false, func(f *models.File) interface{} { return f.IsPublic }},
+	{"status", "状态", false, func(f *models.File) interface{} { return f.Status }},
+	{"created_at", "创建时间", true, func(f *models.File) interface{} { return f.CreatedAt }},
+	{"updated_at", "更新时间", false, func(f *models.File) interface{} { return f.UpdatedAt }},
+}
+
+var emailExportColumns = []exportColumn[models.EmailLog]{
+	{"id", "ID", true, func(e *models.EmailLog) interface{} { return e.ID }},
+	{"to_email", "收件人", true, func(e *models.EmailLog) interface{} { return e.ToEmail }},
+	{"subject", "主题", true, func(e *models.EmailLog) interface{} { return e.Subject }},
+	{"status", "状态", true, func(e *models.EmailLog) interface{} { return e.Status }},
+	{"email_type", "类型", true, func(e *models.EmailLog) interface{} { return e.EmailType }},
+	{"error_msg", "错误信息", false, func(e *models.EmailLog) interface{} { return e.ErrorMsg }},
+	{"user_id", "用户ID", false, func(e *models.EmailLog) interface{} { return e.UserID }},
+	{"sent_at", "发送时间", true, func(e *models.EmailLog) interface{} { return e.SentAt }},
+	{"created_at", "创建时间", true, func(e *models.EmailLog) interface{} { return e.CreatedAt }},
+}
+
+// exportColumnOptions 每种数据类型可选的列
+var exportColumnOptions = map[string][]ExportOption{
+	"users":  columnOptions(userExportColumns),
+	"jobs":   columnOptions(jobExportColumns),
+	"files":  columnOptions(fileExportColumns),
+	"emails": columnOptions(emailExportColumns),
+}
+
+// exportFilterFields 每种数据类型可用的筛选字段
+var exportFilterFields = map[string]map[string]exportFilterField{
+	"users": {
+		"status":   {Column: "status", Values: []string{"active", "inactive", "banned"}},
+		"username": {Column: "username"},
+		"email":    {Column: "email"},
+	},
+	"jobs": {
+		"status": {Column: "status", Values: []string{
+			models.JobStatusWaiting, models.JobStatusPending, models.JobStatusRunning,
+			models.JobStatusCompleted, models.JobStatusFailed, models.JobStatusCancelled,
+		}},
+		"job_type": {Column: "job_type"},
+		"queue":    {Column: "queue"},
+	},
+	"files": {
+		"category":  {Column: "category"},
+		"mime_type": {Column: "mime_type"},
+		"status":    {Column: "status", Values: []string{"active", "deleted", "processing"}},
+	},
+	"emails": {
+		"status":     {Column: "status", Values: []string{"pending", "sent", "failed"}},
+		"email_type": {Column: "email_type"},
+		"to_email":   {Column: "to_email"},
+	},
+}
+
+func columnOptions[T any](columns []exportColumn[T]) []ExportOption {
+	options := make([]ExportOption, len(columns))
+	for i, column := range columns {
+		options[i] = ExportOption{Key: column.Key, Name: column.Header, Default: column.Default}
+	}
+	return options
+}
+
+// ExportColumns 数据类型可选的列，不支持选择列时返回 nil
+func ExportColumns(dataType string) []ExportOption {
+	return exportColumnOptions[dataType]
+}
+
+// ExportFilters 数据类型可用的筛选字段，不支持筛选时返回 nil
+func ExportFilters(dataType string) []ExportOption {
+	fields := exportFilterFields[dataType]
+	if len(fields) == 0 {
+		return nil
+	}
+	options := make([]ExportOption, 0, len(fields))
+	for key, field := range fields {
+		options = append(options, ExportOption{Key: key, Values: field.Values})
+	}
+	sort.Slice(options, func(i, j int) bool { return options[i].Key < options[j].Key })
+	return options
+}
+
+// validateExportQuery 检查筛选字段、日期范围和导出列是否适用于该数据类型
+func validateExportQuery(req *ExportRequest) error {
+	fields, filterable := exportFilterFields[req.DataType]
+	if !filterable && (req.UserID != nil || req.DateRange != nil) {
+		return fmt.Errorf("%w: %s exports do not support user_id or date_range", ErrInvalidExportFilter, req.DataType)
+	}
+
+	for key, value := range req.Filters {
+		field, ok := fields[key]
+		if !ok {
+			return fmt.Errorf("%w: unknown filter %q for %s", ErrInvalidExportFilter, key, req.DataType)
+		}
+		if len(field.Values) > 0 && !containsString(field.Values, value) {
+			return fmt.Errorf("%w: invalid value %q for filter %q", ErrInvalidExportFilter, value, key)
+		}
+	}
+
+	if _, _, err := req.DateRange.bounds(); err != nil {
+		return err
+	}
+
+	if len(req.IncludeData) > 0 {
+		options, ok := exportColumnOptions[req.DataType]
+		if !ok {
+			return fmt.Errorf("%w: %s exports do not support include_data", ErrInvalidExportColumn, req.DataType)
+		}
+		for _, key := range req.IncludeData {
+			found := false
+			for _, option := range options {
+				if option.Key == key {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return fmt.Errorf("%w: unknown column %q for %s", ErrInvalidExportColumn, key, req.DataType)
+			}
+		}
+	}
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// bounds 解析日期范围，返回 [from, to)。只有日期的结束日期包含当天
+func (r *DateRange) bounds() (*time.Time, *time.Time, error) {
+	if r == nil {
+		return nil, nil, nil
+	}
+
+	from, err := parseExportDate(r.StartDate, false)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: invalid start_date %q", ErrInvalidDateRange, r.StartDate)
+	}
+	to, err := parseExportDate(r.EndDate, true)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: invalid end_date %q", ErrInvalidDateRange, r.EndDate)
+	}
+	if from != nil && to != nil && !from.Before(*to) {
+		return nil, nil, fmt.Errorf("%w: start_date must be before end_date", ErrInvalidDateRange)
+	}
+	return from, to, nil
+}
+
+// parseExportDate 解析 RFC3339 时间或日期，end 为 true 时日期解析为次日零点
+func parseExportDate(value string, end bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.ParseInLocation(exportDateLayout, value, time.Local)
+	if err != nil {
+		return nil, err
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
+
+// exportFilter 将已校验的导出请求转换为查询条件
+func exportFilter(req *ExportRequest) (store.ExportFilter, error) {
+	from, to, err := req.DateRange.bounds()
+	if err != nil {
+		return store.ExportFilter{}, err
+	}
+
+	filter := store.ExportFilter{
+		UserID: req.UserID,
+		From:   from,
+		To:     to,
+	}
+	if len(req.Filters) > 0 {
+		fields := exportFilterFields[req.DataType]
+		filter.Conditions = make(map[string]string, len(req.Filters))
+		for key, value := range req.Filters {
+			field, ok := fields[key]
+			if !ok {
+				return store.ExportFilter{}, fmt.Errorf("%w: unknown filter %q for %s", ErrInvalidExportFilter, key, req.DataType)
+			}
+			filter.Conditions[field.Column] = value
+		}
+	}
+	return filter, nil
+}
+
+// selectExportColumns 按 include_data 的顺序选择导出列，未指定时使用默认列
+func selectExportColumns[T any](columns []exportColumn[T], include []string) []exportColumn[T] {
+	var selected []exportColumn[T]
+	if len(include) == 0 {
+		for _, column := range columns {
+			if column.Default {
+				selected = append(selected, column)
+			}
+		}
+		return selected
+	}
+
+	for _, key := range include {
+		for _, column := range columns {
+			if column.Key == key {
+				selected = append(selected, column)
+				break
+			}
+		}
+	}
+	return selected
+}
+
+// streamExportRecords 分批读取记录并按请求的格式和列逐条写入 w，内存占用与记录数无关。
+// JSON 和 JSON Lines 格式未指定 include_data 时输出完整记录
+func streamExportRecords[T any](w io.Writer, req *ExportRequest, columns []exportColumn[T], cursor store.ExportCursor[T], progress ExportProgressFunc) (int, error) {
+	total, err := cursor.Count()
+	if err != nil {
+		return 0, err
+	}
+
+	selected := selectExportColumns(columns, req.IncludeData)
+	layout := exportLayout{
+		Sheet:       req.DataType,
+		Keys:        make([]string, len(selected)),
+		Headers:     make([]string, len(selected)),
+		FullRecords: len(req.IncludeData) == 0,
+	}
+	for i, column := range selected {
+		layout.Keys[i] = column.Key
+		layout.Headers[i] = column.Header
+	}
+
+	sink, err := newExportSink(w, req.Format, layout)
+	if err != nil {
+		return 0, err
+	}
+
+	done := 0
+	values := make([]interface{}, len(selected))
+	err = cursor.Each(exportBatchSize, func(batch []T) error {
+		for i := range batch {
+			for j, column := range selected {
+				values[j] = column.Value(&batch[i])
+			}
+			if err := sink.Write(values, &batch[i]); err != nil {
+				return err
+			}
+			done++
+			// 导出期间新增的记录也会被导出
+			if done > int(total) {
+				total = int64(done)
+			}
+			if err := reportExportProgress(progress, done, int(total)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if err := sink.Close(); err != nil {
+		return 0, err
+	}
+	// 没有记录或导出期间有记录被删除时，写完后仍报告一次完成
+	if done == 0 || done < int(total) {
+		if err := reportExportProgress(progress, done, done); err != nil {
+			return 0, err
+		}
+	}
+	return done, nil
+}
+
+// formatCSVValue 将列的值格式化为 CSV 文本，空指针为空字符串
+func formatCSVValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case time.Time:
+		return v.Format(exportTimeLayout)
+	case *time.Time:
+		if v == nil {
+			return ""
+		}
+		return v.Format(exportTimeLayout)
+	case *uint:
+		if v == nil {
+			return ""
+		}
+		return strconv.FormatUint(uint64(*v), 10)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// exportJSONRow 按列顺序编码字段的 JSON 对象
+type exportJSONRow []exportJSONField
+
+type exportJSONField struct {
+	Key   string
+	Value interface{}
+}
+
+func (r exportJSONRow) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range r {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(field.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(field.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
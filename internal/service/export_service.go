@@ -1,12 +1,12 @@
 package service
 
 import (
-	"encoding/csv"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
-	"path/filepath"
-	"strconv"
 	"time"
 
 	"go-vibe-friend/internal/models"
@@ -19,37 +19,79 @@ type ExportService struct {
 	fileStore       *store.FileStore
 	emailStore      *store.EmailStore
 	permissionStore *store.PermissionStore
-	exportPath      string
+	jobService      *JobService
+	exportStorage   *ExportStorage
 }
 
-func NewExportService(userStore *store.UserStore, jobStore *store.JobStore, fileStore *store.FileStore, emailStore *store.EmailStore, permissionStore *store.PermissionStore) *ExportService {
+func NewExportService(userStore *store.UserStore, jobStore *store.JobStore, fileStore *store.FileStore, emailStore *store.EmailStore, permissionStore *store.PermissionStore, jobService *JobService, exportStorage *ExportStorage) *ExportService {
 	return &ExportService{
 		userStore:       userStore,
 		jobStore:        jobStore,
 		fileStore:       fileStore,
 		emailStore:      emailStore,
 		permissionStore: permissionStore,
-		exportPath:      "exports",
+		jobService:      jobService,
+		exportStorage:   exportStorage,
 	}
 }
 
+// JobTypeExport 后台导出任务的类型
+const JobTypeExport = "export"
+
+const (
+	// exportRetention 导出文件生成后保留的时间，过期后由 CleanupExpiredExports 删除
+	exportRetention = 24 * time.Hour
+	// exportLinkExpiry 每次获取的下载链接的有效期
+	exportLinkExpiry = 15 * time.Minute
+)
+
+var (
+	// ErrInvalidExportType 不支持的导出数据类型
+	ErrInvalidExportType = errors.New("invalid export data type")
+	// ErrInvalidExportFormat 数据类型不支持该导出格式
+	ErrInvalidExportFormat = errors.New("invalid export format")
+	// ErrInvalidExportFilter 数据类型不支持该筛选条件
+	ErrInvalidExportFilter = errors.New("invalid export filter")
+	// ErrInvalidExportColumn 数据类型没有该导出列
+	ErrInvalidExportColumn = errors.New("invalid export column")
+	// ErrInvalidDateRange 日期范围无法解析或开始时间不早于结束时间
+	ErrInvalidDateRange = errors.New("invalid date range")
+)
+
+// exportFormats 每种数据类型支持的导出格式
+var exportFormats = map[string][]string{
+	"users":         {"csv", "json", "jsonl", "xlsx"},
+	"jobs":          {"csv", "json", "jsonl", "xlsx"},
+	"files":         {"csv", "json", "jsonl", "xlsx"},
+	"emails":        {"csv", "json", "jsonl", "xlsx"},
+	"permissions":   {"json", "jsonl"},
+	"system_report": {"json", "xlsx"},
+}
+
+// ExportProgressFunc 每写入一批记录后调用，报告已写入数量和总数；返回错误时中止导出
+type ExportProgressFunc func(done, total int) error
+
+// exportProgressInterval 每写入多少条记录报告一次进度
+const exportProgressInterval = 100
+
 // ExportRequest 导出请求
 type ExportRequest struct {
-	DataType    string            `json:"data_type"`    // users, jobs, files, emails, permissions
-	Format      string            `json:"format"`       // csv, json, xlsx
+	DataType    string            `json:"data_type"` // users, jobs, files, emails, permissions
+	Format      string            `json:"format"`    // csv, json, jsonl, xlsx
 	DateRange   *DateRange        `json:"date_range,omitempty"`
-	Filters     map[string]string `json:"filters,omitempty"`
-	UserID      *uint             `json:"user_id,omitempty"` // 用于用户特定数据导出
-	IncludeData []string          `json:"include_data,omitempty"`
+	Filters     map[string]string `json:"filters,omitempty"`      // 字段等值筛选，可用字段见 ExportFilters
+	UserID      *uint             `json:"user_id,omitempty"`      // 用于用户特定数据导出
+	IncludeData []string          `json:"include_data,omitempty"` // 导出的列及顺序，可选列见 ExportColumns
 }
 
+// DateRange 按创建时间筛选，日期为 2006-01-02 或 RFC3339 格式，任一端可以为空
 type DateRange struct {
 	StartDate string `json:"start_date"`
 	EndDate   string `json:"end_date"`
 }
 
 type ExportResult struct {
-	FilePath    string    `json:"file_path"`
+	ExportID    uint      `json:"export_id"` // 导出文件记录的ID，用于获取下载链接
 	FileName    string    `json:"file_name"`
 	FileSize    int64     `json:"file_size"`
 	RecordCount int       `json:"record_count"`
@@ -57,418 +99,198 @@ type ExportResult struct {
 	ExpiresAt   time.Time `json:"expires_at"`
 }
 
-// ExportData 导出数据
-func (s *ExportService) ExportData(req *ExportRequest) (*ExportResult, error) {
-	// 确保导出目录存在
-	if err := os.MkdirAll(s.exportPath, 0755); err != nil {
-		return nil, fmt.Errorf("创建导出目录失败: %v", err)
-	}
-
-	// 生成文件名
-	timestamp := time.Now().Format("20060102_150405")
-	fileName := fmt.Sprintf("%s_%s.%s", req.DataType, timestamp, req.Format)
-	filePath := filepath.Join(s.exportPath, fileName)
-
-	var recordCount int
-	var err error
-
-	// 根据数据类型执行导出
-	switch req.DataType {
-	case "users":
-		recordCount, err = s.exportUsers(filePath, req)
-	case "jobs":
-		recordCount, err = s.exportJobs(filePath, req)
-	case "files":
-		recordCount, err = s.exportFiles(filePath, req)
-	case "emails":
-		recordCount, err = s.exportEmails(filePath, req)
-	case "permissions":
-		recordCount, err = s.exportPermissions(filePath, req)
-	case "system_report":
-		recordCount, err = s.exportSystemReport(filePath, req)
-	default:
-		return nil, fmt.Errorf("不支持的数据类型: %s", req.DataType)
-	}
-
-	if err != nil {
-		return nil, fmt.Errorf("导出失败: %v", err)
-	}
-
-	// 获取文件大小
-	fileInfo, err := os.Stat(filePath)
-	if err != nil {
-		return nil, fmt.Errorf("获取文件信息失败: %v", err)
-	}
-
-	return &ExportResult{
-		FilePath:    filePath,
-		FileName:    fileName,
-		FileSize:    fileInfo.Size(),
-		RecordCount: recordCount,
-		CreatedAt:   time.Now(),
-		ExpiresAt:   time.Now().Add(24 * time.Hour), // 24小时后过期
-	}, nil
-}
-
-// exportUsers 导出用户数据
-func (s *ExportService) exportUsers(filePath string, req *ExportRequest) (int, error) {
-	// 获取用户列表
-	users, err := s.userStore.ListUsers(1000, 0) // 限制最大1000条
-	if err != nil {
-		return 0, err
+// ValidateExportRequest 检查数据类型、格式、筛选条件、日期范围和导出列是否支持
+func ValidateExportRequest(req *ExportRequest) error {
+	formats, ok := exportFormats[req.DataType]
+	if !ok {
+		return ErrInvalidExportType
 	}
-
-	// 如果指定了用户ID，只导出该用户的数据
-	if req.UserID != nil {
-		user, err := s.userStore.GetUserByID(*req.UserID)
-		if err != nil {
-			return 0, err
-		}
-		if user != nil {
-			users = []models.User{*user}
-		} else {
-			users = []models.User{}
-		}
-	}
-
-	switch req.Format {
-	case "csv":
-		return s.exportUsersCSV(filePath, users)
-	case "json":
-		return s.exportUsersJSON(filePath, users)
-	default:
-		return 0, fmt.Errorf("不支持的格式: %s", req.Format)
+	if !containsString(formats, req.Format) {
+		return ErrInvalidExportFormat
 	}
+	return validateExportQuery(req)
 }
 
-// exportUsersCSV 导出用户CSV
-func (s *ExportService) exportUsersCSV(filePath string, users []models.User) (int, error) {
-	file, err := os.Create(filePath)
-	if err != nil {
-		return 0, err
-	}
-	defer file.Close()
-
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
-
-	// 写入头部
-	headers := []string{"ID", "用户名", "邮箱", "状态", "创建时间", "更新时间"}
-	if err := writer.Write(headers); err != nil {
-		return 0, err
+// SubmitExport 校验导出请求并提交后台导出任务，立即返回任务记录
+func (s *ExportService) SubmitExport(userID uint, req *ExportRequest) (*models.Job, error) {
+	if err := ValidateExportRequest(req); err != nil {
+		return nil, err
 	}
-
-	// 写入数据
-	for _, user := range users {
-		record := []string{
-			strconv.Itoa(int(user.ID)),
-			user.Username,
-			user.Email,
-			user.Status,
-			user.CreatedAt.Format("2006-01-02 15:04:05"),
-			user.UpdatedAt.Format("2006-01-02 15:04:05"),
-		}
-		if err := writer.Write(record); err != nil {
-			return 0, err
-		}
+	if s.jobService == nil {
+		return nil, ErrQueueUnavailable
 	}
 
-	return len(users), nil
-}
-
-// exportUsersJSON 导出用户JSON
-func (s *ExportService) exportUsersJSON(filePath string, users []models.User) (int, error) {
-	// 清除敏感信息
-	for i := range users {
-		users[i].Password = ""
-	}
-
-	file, err := os.Create(filePath)
+	payload, err := req.payload()
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	defer file.Close()
 
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ")
-	
-	if err := encoder.Encode(users); err != nil {
-		return 0, err
-	}
-
-	return len(users), nil
+	return s.jobService.SubmitJob(&SubmitJobRequest{
+		UserID:      userID,
+		Title:       fmt.Sprintf("导出%s数据 (%s)", req.DataType, req.Format),
+		Description: "后台数据导出",
+		JobType:     JobTypeExport,
+		Payload:     payload,
+	})
 }
 
-// exportJobs 导出任务数据
-func (s *ExportService) exportJobs(filePath string, req *ExportRequest) (int, error) {
-	jobs, err := s.jobStore.ListJobs(1000, 0)
+// payload 将导出请求编码为任务参数
+func (req *ExportRequest) payload() (map[string]interface{}, error) {
+	data, err := json.Marshal(req)
 	if err != nil {
-		return 0, err
-	}
-
-	// 如果指定了用户ID，只导出该用户的任务
-	if req.UserID != nil {
-		jobs, err = s.jobStore.GetJobsByUserID(*req.UserID, 1000, 0)
-		if err != nil {
-			return 0, err
-		}
+		return nil, fmt.Errorf("failed to encode export request: %w", err)
 	}
-
-	switch req.Format {
-	case "csv":
-		return s.exportJobsCSV(filePath, jobs)
-	case "json":
-		return s.exportJobsJSON(filePath, jobs)
-	default:
-		return 0, fmt.Errorf("不支持的格式: %s", req.Format)
+	var payload map[string]interface{}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, fmt.Errorf("failed to encode export request: %w", err)
 	}
+	return payload, nil
 }
 
-// exportJobsCSV 导出任务CSV
-func (s *ExportService) exportJobsCSV(filePath string, jobs []models.Job) (int, error) {
-	file, err := os.Create(filePath)
+// ExportRequestFromPayload 从任务参数解析导出请求
+func ExportRequestFromPayload(payload map[string]interface{}) (*ExportRequest, error) {
+	data, err := json.Marshal(payload)
 	if err != nil {
-		return 0, err
+		return nil, fmt.Errorf("invalid export payload: %w", err)
 	}
-	defer file.Close()
-
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
-
-	// 写入头部
-	headers := []string{"ID", "用户ID", "状态", "任务类型", "创建时间", "更新时间"}
-	if err := writer.Write(headers); err != nil {
-		return 0, err
+	var req ExportRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, fmt.Errorf("invalid export payload: %w", err)
 	}
-
-	// 写入数据
-	for _, job := range jobs {
-		record := []string{
-			strconv.Itoa(int(job.ID)),
-			strconv.Itoa(int(job.UserID)),
-			string(job.Status),
-			job.JobType,
-			job.CreatedAt.Format("2006-01-02 15:04:05"),
-			job.UpdatedAt.Format("2006-01-02 15:04:05"),
-		}
-		if err := writer.Write(record); err != nil {
-			return 0, err
-		}
+	if err := ValidateExportRequest(&req); err != nil {
+		return nil, err
 	}
-
-	return len(jobs), nil
+	return &req, nil
 }
 
-// exportJobsJSON 导出任务JSON
-func (s *ExportService) exportJobsJSON(filePath string, jobs []models.Job) (int, error) {
-	file, err := os.Create(filePath)
+// GetExportJob 获取导出任务，任务完成后同时返回导出结果
+func (s *ExportService) GetExportJob(id uint) (*models.Job, *ExportResult, error) {
+	job, err := s.jobStore.GetJobByID(id)
 	if err != nil {
-		return 0, err
+		return nil, nil, fmt.Errorf("failed to get job: %w", err)
 	}
-	defer file.Close()
-
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ")
-	
-	if err := encoder.Encode(jobs); err != nil {
-		return 0, err
-	}
-
-	return len(jobs), nil
-}
-
-// exportFiles 导出文件数据
-func (s *ExportService) exportFiles(filePath string, req *ExportRequest) (int, error) {
-	var files []models.File
-	var err error
-
-	if req.UserID != nil {
-		files, err = s.fileStore.GetFilesByUserID(*req.UserID, "", 1000, 0)
-	} else {
-		// 需要在FileStore中添加GetAllFiles方法
-		files = []models.File{} // 暂时返回空
+	if job == nil || job.JobType != JobTypeExport {
+		return nil, nil, ErrJobNotFound
 	}
-
-	if err != nil {
-		return 0, err
+	if job.Status != models.JobStatusCompleted {
+		return job, nil, nil
 	}
 
-	switch req.Format {
-	case "csv":
-		return s.exportFilesCSV(filePath, files)
-	case "json":
-		return s.exportFilesJSON(filePath, files)
-	default:
-		return 0, fmt.Errorf("不支持的格式: %s", req.Format)
+	var result ExportResult
+	if err := json.Unmarshal([]byte(job.Result), &result); err != nil {
+		return job, nil, nil
 	}
+	return job, &result, nil
 }
 
-// exportFilesCSV 导出文件CSV
-func (s *ExportService) exportFilesCSV(filePath string, files []models.File) (int, error) {
-	file, err := os.Create(filePath)
+// ExportData 导出数据并保存到对象存储，userID 为发起导出的用户，jobID 为执行导出的任务（可以为 nil）；
+// progress 可以为 nil
+func (s *ExportService) ExportData(ctx context.Context, userID uint, jobID *uint, req *ExportRequest, progress ExportProgressFunc) (*ExportResult, error) {
+	// 先写入临时文件，上传时即可确定文件大小
+	tmp, err := os.CreateTemp("", "export-*")
 	if err != nil {
-		return 0, err
+		return nil, fmt.Errorf("创建导出文件失败: %v", err)
 	}
-	defer file.Close()
-
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
 
-	// 写入头部
-	headers := []string{"ID", "文件名", "原始名称", "大小", "类型", "分类", "用户ID", "创建时间"}
-	if err := writer.Write(headers); err != nil {
-		return 0, err
-	}
-
-	// 写入数据
-	for _, f := range files {
-		record := []string{
-			strconv.Itoa(int(f.ID)),
-			f.FileName,
-			f.OriginalName,
-			strconv.FormatInt(f.FileSize, 10),
-			f.MimeType,
-			f.Category,
-			strconv.Itoa(int(f.UserID)),
-			f.CreatedAt.Format("2006-01-02 15:04:05"),
-		}
-		if err := writer.Write(record); err != nil {
-			return 0, err
-		}
-	}
-
-	return len(files), nil
-}
-
-// exportFilesJSON 导出文件JSON
-func (s *ExportService) exportFilesJSON(filePath string, files []models.File) (int, error) {
-	file, err := os.Create(filePath)
+	recordCount, err := s.writeExport(tmp, req, progress)
 	if err != nil {
-		return 0, err
-	}
-	defer file.Close()
-
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ")
-	
-	if err := encoder.Encode(files); err != nil {
-		return 0, err
+		return nil, fmt.Errorf("导出失败: %w", err)
 	}
-
-	return len(files), nil
-}
-
-// exportEmails 导出邮件数据
-func (s *ExportService) exportEmails(filePath string, req *ExportRequest) (int, error) {
-	emails, err := s.emailStore.GetEmailLogs(req.UserID, 1000, 0)
+	size, err := tmp.Seek(0, io.SeekCurrent)
 	if err != nil {
-		return 0, err
-	}
-
-	switch req.Format {
-	case "csv":
-		return s.exportEmailsCSV(filePath, emails)
-	case "json":
-		return s.exportEmailsJSON(filePath, emails)
-	default:
-		return 0, fmt.Errorf("不支持的格式: %s", req.Format)
+		return nil, fmt.Errorf("获取文件信息失败: %v", err)
 	}
-}
-
-// exportEmailsCSV 导出邮件CSV
-func (s *ExportService) exportEmailsCSV(filePath string, emails []models.EmailLog) (int, error) {
-	file, err := os.Create(filePath)
-	if err != nil {
-		return 0, err
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("获取文件信息失败: %v", err)
 	}
-	defer file.Close()
-
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
 
-	// 写入头部
-	headers := []string{"ID", "收件人", "主题", "状态", "类型", "发送时间", "创建时间"}
-	if err := writer.Write(headers); err != nil {
-		return 0, err
+	file := &models.ExportFile{
+		UserID:      userID,
+		JobID:       jobID,
+		DataType:    req.DataType,
+		Format:      req.Format,
+		FileName:    ExportFileName(req),
+		ContentType: ExportContentType(req.Format),
+		RecordCount: recordCount,
+		ExpiresAt:   time.Now().Add(exportRetention),
 	}
-
-	// 写入数据
-	for _, email := range emails {
-		sentAt := ""
-		if email.SentAt != nil {
-			sentAt = email.SentAt.Format("2006-01-02 15:04:05")
-		}
-		
-		record := []string{
-			strconv.Itoa(int(email.ID)),
-			email.ToEmail,
-			email.Subject,
-			email.Status,
-			email.EmailType,
-			sentAt,
-			email.CreatedAt.Format("2006-01-02 15:04:05"),
-		}
-		if err := writer.Write(record); err != nil {
-			return 0, err
-		}
+	if err := s.exportStorage.Save(ctx, file, tmp, size); err != nil {
+		return nil, err
 	}
 
-	return len(emails), nil
+	return &ExportResult{
+		ExportID:    file.ID,
+		FileName:    file.FileName,
+		FileSize:    file.FileSize,
+		RecordCount: recordCount,
+		CreatedAt:   file.CreatedAt,
+		ExpiresAt:   file.ExpiresAt,
+	}, nil
 }
 
-// exportEmailsJSON 导出邮件JSON
-func (s *ExportService) exportEmailsJSON(filePath string, emails []models.EmailLog) (int, error) {
-	file, err := os.Create(filePath)
-	if err != nil {
+// StreamExport 校验导出请求并将导出直接写入 w，不生成临时文件。
+// ctx 取消后在下一批记录处中止导出
+func (s *ExportService) StreamExport(ctx context.Context, w io.Writer, req *ExportRequest) (int, error) {
+	if err := ValidateExportRequest(req); err != nil {
 		return 0, err
 	}
-	defer file.Close()
-
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ")
-	
-	if err := encoder.Encode(emails); err != nil {
-		return 0, err
-	}
-
-	return len(emails), nil
+	return s.writeExport(w, req, func(done, total int) error {
+		return ctx.Err()
+	})
 }
 
-// exportPermissions 导出权限数据
-func (s *ExportService) exportPermissions(filePath string, req *ExportRequest) (int, error) {
-	permissions, err := s.permissionStore.GetPermissions(1000, 0)
-	if err != nil {
-		return 0, err
-	}
+// ExportFileName 导出文件名，如 users_20060102_150405.csv
+func ExportFileName(req *ExportRequest) string {
+	timestamp := time.Now().Format("20060102_150405")
+	return fmt.Sprintf("%s_%s.%s", req.DataType, timestamp, req.Format)
+}
 
-	switch req.Format {
+// ExportContentType 导出格式对应的 Content-Type
+func ExportContentType(format string) string {
+	switch format {
+	case "csv":
+		return "text/csv; charset=utf-8"
 	case "json":
-		return s.exportPermissionsJSON(filePath, permissions)
+		return "application/json; charset=utf-8"
+	case "jsonl":
+		return "application/x-ndjson; charset=utf-8"
+	case "xlsx":
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
 	default:
-		return 0, fmt.Errorf("权限数据仅支持JSON格式")
+		return "application/octet-stream"
 	}
 }
 
-// exportPermissionsJSON 导出权限JSON
-func (s *ExportService) exportPermissionsJSON(filePath string, permissions []models.Permission) (int, error) {
-	file, err := os.Create(filePath)
+// writeExport 根据数据类型将导出写入 w，返回导出的记录数
+func (s *ExportService) writeExport(w io.Writer, req *ExportRequest, progress ExportProgressFunc) (int, error) {
+	filter, err := exportFilter(req)
 	if err != nil {
 		return 0, err
 	}
-	defer file.Close()
 
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ")
-	
-	if err := encoder.Encode(permissions); err != nil {
-		return 0, err
+	switch req.DataType {
+	case "users":
+		return streamExportRecords(w, req, userExportColumns, s.userStore.ExportUsers(filter), progress)
+	case "jobs":
+		return streamExportRecords(w, req, jobExportColumns, s.jobStore.ExportJobs(filter), progress)
+	case "files":
+		return streamExportRecords(w, req, fileExportColumns, s.fileStore.ExportFiles(filter), progress)
+	case "emails":
+		return streamExportRecords(w, req, emailExportColumns, s.emailStore.ExportEmailLogs(filter), progress)
+	case "permissions":
+		// 权限数据只支持完整记录的 JSON 格式，不需要导出列
+		return streamExportRecords[models.Permission](w, req, nil, s.permissionStore.ExportPermissions(), progress)
+	case "system_report":
+		return s.exportSystemReport(w, req, progress)
+	default:
+		return 0, fmt.Errorf("不支持的数据类型: %s", req.DataType)
 	}
-
-	return len(permissions), nil
 }
 
 // exportSystemReport 导出系统报告
-func (s *ExportService) exportSystemReport(filePath string, req *ExportRequest) (int, error) {
+func (s *ExportService) exportSystemReport(w io.Writer, req *ExportRequest, progress ExportProgressFunc) (int, error) {
 	// 收集系统统计信息
 	report := make(map[string]interface{})
 	
@@ -501,49 +323,42 @@ func (s *ExportService) exportSystemReport(filePath string, req *ExportRequest)
 		"version":     "1.0.0",
 	}
 
-	file, err := os.Create(filePath)
-	if err != nil {
-		return 0, err
+	if req.Format == "xlsx" {
+		if err := s.exportSystemReportXLSX(w, report); err != nil {
+			return 0, err
+		}
+		return 1, reportExportProgress(progress, 1, 1)
 	}
-	defer file.Close()
 
-	encoder := json.NewEncoder(file)
+	encoder := json.NewEncoder(w)
 	encoder.SetIndent("", "  ")
 	
 	if err := encoder.Encode(report); err != nil {
 		return 0, err
 	}
 
-	return 1, nil
+	return 1, reportExportProgress(progress, 1, 1)
 }
 
-// GetExportFile 获取导出文件
-func (s *ExportService) GetExportFile(fileName string) (string, error) {
-	filePath := filepath.Join(s.exportPath, fileName)
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		return "", fmt.Errorf("文件不存在")
+// GetExportDownload 获取导出文件记录和限时下载链接，返回链接的失效时间
+func (s *ExportService) GetExportDownload(ctx context.Context, id uint) (*models.ExportFile, string, time.Time, error) {
+	file, err := s.exportStorage.Get(id)
+	if err != nil {
+		return nil, "", time.Time{}, err
 	}
-	return filePath, nil
-}
-
-// CleanupExpiredExports 清理过期的导出文件
-func (s *ExportService) CleanupExpiredExports() error {
-	files, err := filepath.Glob(filepath.Join(s.exportPath, "*"))
+	link, expiresAt, err := s.exportStorage.DownloadURL(ctx, file, exportLinkExpiry)
 	if err != nil {
-		return err
+		return file, "", time.Time{}, err
 	}
+	return file, link, expiresAt, nil
+}
 
-	for _, file := range files {
-		info, err := os.Stat(file)
-		if err != nil {
-			continue
-		}
-
-		// 删除24小时前的文件
-		if time.Since(info.ModTime()) > 24*time.Hour {
-			os.Remove(file)
-		}
-	}
+// ListExports 分页获取所有导出文件记录
+func (s *ExportService) ListExports(limit, offset int) ([]models.ExportFile, int64, error) {
+	return s.exportStorage.List(nil, limit, offset)
+}
 
-	return nil
-}
\ No newline at end of file
+// CleanupExpiredExports 从对象存储中删除过期的导出文件，返回删除的文件数
+func (s *ExportService) CleanupExpiredExports(ctx context.Context) (int, error) {
+	return s.exportStorage.CleanupExpired(ctx)
+}
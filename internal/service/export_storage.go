@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+
+	"go-vibe-friend/internal/config"
+	"go-vibe-friend/internal/models"
+	"go-vibe-friend/internal/store"
+)
+
+// exportObjectPrefix 导出文件在对象存储中的目录
+const exportObjectPrefix = "exports/"
+
+// exportCleanupBatchSize 每批清理的过期导出文件数
+const exportCleanupBatchSize = 100
+
+var (
+	// ErrExportNotFound 导出文件不存在或已被清理
+	ErrExportNotFound = errors.New("export file not found")
+	// ErrExportExpired 导出文件已过期
+	ErrExportExpired = errors.New("export file has expired")
+)
+
+// ExportStorage 将导出文件保存到对象存储的 exports/ 目录下并在数据库中记录，
+// 多个实例共享同一份导出文件，下载通过限时的预签名链接直接从对象存储获取
+type ExportStorage struct {
+	exportStore *store.ExportStore
+	minioClient *minio.Client
+	bucket      string
+}
+
+func NewExportStorage(exportStore *store.ExportStore, minioClient *minio.Client, cfg *config.Config) *ExportStorage {
+	return &ExportStorage{
+		exportStore: exportStore,
+		minioClient: minioClient,
+		bucket:      cfg.MinIO.BucketName,
+	}
+}
+
+// Save 上传导出文件并保存记录。file 需填写除 ObjectKey 和 FileSize 外的信息，size 未知时传 -1
+func (s *ExportStorage) Save(ctx context.Context, file *models.ExportFile, r io.Reader, size int64) error {
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	// 随机前缀使同名文件互不覆盖，重试的任务也会生成新的对象
+	file.ObjectKey = fmt.Sprintf("%s%s/%s_%s", exportObjectPrefix, time.Now().Format("2006/01/02"), hex.EncodeToString(suffix), file.FileName)
+
+	info, err := s.minioClient.PutObject(ctx, s.bucket, file.ObjectKey, r, size, minio.PutObjectOptions{
+		ContentType: file.ContentType,
+	})
+	if err != nil {
+		return fmt.Errorf("上传导出文件到 MinIO 失败: %v", err)
+	}
+	file.FileSize = info.Size
+
+	if err := s.exportStore.CreateExportFile(file); err != nil {
+		// 如果数据库保存失败，删除已上传的文件
+		_ = s.minioClient.RemoveObject(context.Background(), s.bucket, file.ObjectKey, minio.RemoveObjectOptions{})
+		return fmt.Errorf("保存导出文件信息失败: %v", err)
+	}
+	return nil
+}
+
+// Get 获取导出文件记录，文件不存在或已被清理时返回 ErrExportNotFound
+func (s *ExportStorage) Get(id uint) (*models.ExportFile, error) {
+	file, err := s.exportStore.GetExportFileByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get export file: %w", err)
+	}
+	if file == nil {
+		return nil, ErrExportNotFound
+	}
+	return file, nil
+}
+
+// List 分页获取导出文件记录，userID 为 nil 时返回所有用户的导出文件
+func (s *ExportStorage) List(userID *uint, limit, offset int) ([]models.ExportFile, int64, error) {
+	return s.exportStore.ListExportFiles(userID, limit, offset)
+}
+
+// DownloadURL 生成导出文件的预签名下载链接，链接在 expiry 后失效，且不晚于文件的过期时间。
+// 返回链接和链接的失效时间
+func (s *ExportStorage) DownloadURL(ctx context.Context, file *models.ExportFile, expiry time.Duration) (string, time.Time, error) {
+	remaining := time.Until(file.ExpiresAt)
+	if remaining <= 0 {
+		return "", time.Time{}, ErrExportExpired
+	}
+	if remaining < expiry {
+		expiry = remaining
+	}
+	// 预签名链接的有效期以秒为单位，至少为 1 秒
+	if expiry < time.Second {
+		expiry = time.Second
+	}
+
+	params := url.Values{}
+	params.Set("response-content-disposition", fmt.Sprintf("attachment; filename=%q", file.FileName))
+	if file.ContentType != "" {
+		params.Set("response-content-type", file.ContentType)
+	}
+	u, err := s.minioClient.PresignedGetObject(ctx, s.bucket, file.ObjectKey, expiry, params)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("生成下载链接失败: %v", err)
+	}
+	return u.String(), time.Now().Add(expiry), nil
+}
+
+// CleanupExpired 从对象存储中删除过期的导出文件及其记录，返回删除的文件数。
+// 删除对象失败时停止清理并保留其余记录，下次清理时重试
+func (s *ExportStorage) CleanupExpired(ctx context.Context) (int, error) {
+	now := time.Now()
+	removed := 0
+	for {
+		files, err := s.exportStore.GetExpiredExportFiles(now, exportCleanupBatchSize)
+		if err != nil {
+			return removed, fmt.Errorf("failed to list expired exports: %w", err)
+		}
+
+		for _, file := range files {
+			if err := ctx.Err(); err != nil {
+				return removed, err
+			}
+			// 对象已不存在时 RemoveObject 不返回错误
+			if err := s.minioClient.RemoveObject(ctx, s.bucket, file.ObjectKey, minio.RemoveObjectOptions{}); err != nil {
+				return removed, fmt.Errorf("删除导出文件 %s 失败: %v", file.ObjectKey, err)
+			}
+			if err := s.exportStore.DeleteExportFile(file.ID); err != nil {
+				return removed, fmt.Errorf("failed to delete export record: %w", err)
+			}
+			removed++
+		}
+
+		if len(files) < exportCleanupBatchSize {
+			return removed, nil
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"go-vibe-friend/internal/utils"
+)
+
+// reportExportProgress 每写入 exportProgressInterval 条记录及写完最后一条时报告进度
+func reportExportProgress(progress ExportProgressFunc, done, total int) error {
+	if progress == nil {
+		return nil
+	}
+	if done%exportProgressInterval != 0 && done != total {
+		return nil
+	}
+	return progress(done, total)
+}
+
+// exportLayout 导出文件的结构
+type exportLayout struct {
+	Sheet       string   // xlsx 工作表名称
+	Keys        []string // 各列的 JSON 字段名
+	Headers     []string // 各列的表头
+	FullRecords bool     // JSON 和 JSON Lines 输出完整记录而不是所选的列
+}
+
+// exportSink 按格式逐条写入导出记录，写完后必须调用 Close
+type exportSink interface {
+	// Write 写入一条记录，values 为所选列的值，record 为完整记录
+	Write(values []interface{}, record interface{}) error
+	Close() error
+}
+
+// newExportSink 创建写入 w 的导出格式，CSV 和 xlsx 立即写入表头
+func newExportSink(w io.Writer, format string, layout exportLayout) (exportSink, error) {
+	switch format {
+	case "csv":
+		writer := csv.NewWriter(w)
+		if err := writer.Write(layout.Headers); err != nil {
+			return nil, err
+		}
+		return &csvSink{writer: writer, record: make([]string, len(layout.Headers))}, nil
+	case "json":
+		return &jsonSink{w: bufio.NewWriter(w), layout: layout}, nil
+	case "jsonl":
+		return &jsonSink{w: bufio.NewWriter(w), layout: layout, lines: true}, nil
+	case "xlsx":
+		xw := utils.NewXLSXWriter(w)
+		if err := xw.AddSheet(layout.Sheet); err != nil {
+			return nil, err
+		}
+		if err := xw.WriteHeader(layout.Headers); err != nil {
+			return nil, err
+		}
+		return &xlsxSink{xw: xw}, nil
+	default:
+		return nil, fmt.Errorf("不支持的格式: %s", format)
+	}
+}
+
+type csvSink struct {
+	writer *csv.Writer
+	record []string
+}
+
+func (s *csvSink) Write(values []interface{}, _ interface{}) error {
+	for i, value := range values {
+		s.record[i] = formatCSVValue(value)
+	}
+	return s.writer.Write(s.record)
+}
+
+func (s *csvSink) Close() error {
+	s.writer.Flush()
+	return s.writer.Error()
+}
+
+// jsonSink 写入 JSON 数组，输出与两个空格缩进的 json.Encoder 相同；
+// lines 为 true 时每行写入一条紧凑的 JSON 记录
+type jsonSink struct {
+	w      *bufio.Writer
+	layout exportLayout
+	lines  bool
+	count  int
+}
+
+func (s *jsonSink) Write(values []interface{}, record interface{}) error {
+	item := record
+	if !s.layout.FullRecords {
+		row := make(exportJSONRow, len(values))
+		for i, value := range values {
+			row[i] = exportJSONField{Key: s.layout.Keys[i], Value: value}
+		}
+		item = row
+	}
+
+	if s.lines {
+		data, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		s.count++
+		if _, err := s.w.Write(data); err != nil {
+			return err
+		}
+		return s.w.WriteByte('\n')
+	}
+
+	data, err := json.MarshalIndent(item, "  ", "  ")
+	if err != nil {
+		return err
+	}
+	prefix := ",\n  "
+	if s.count == 0 {
+		prefix = "[\n  "
+	}
+	s.count++
+	if _, err := s.w.WriteString(prefix); err != nil {
+		return err
+	}
+	_, err = s.w.Write(data)
+	return err
+}
+
+func (s *jsonSink) Close() error {
+	if !s.lines {
+		end := "\n]\n"
+		if s.count == 0 {
+			end = "[]\n"
+		}
+		if _, err := s.w.WriteString(end); err != nil {
+			return err
+		}
+	}
+	return s.w.Flush()
+}
+
+type xlsxSink struct {
+	xw *utils.XLSXWriter
+}
+
+func (s *xlsxSink) Write(values []interface{}, _ interface{}) error {
+	return s.xw.WriteRow(values)
+}
+
+func (s *xlsxSink) Close() error {
+	return s.xw.Close()
+}
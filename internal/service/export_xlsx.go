@@ -0,0 +1,147 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"go-vibe-friend/internal/utils"
+)
+
+// systemReportSheets 系统报告各部分在 xlsx 中的工作表顺序
+var systemReportSheets = []string{"user_stats", "job_stats", "email_stats", "permission_stats", "system_info"}
+
+// exportSystemReportXLSX 导出系统报告XLSX，每部分统计一个工作表。
+// 标量统计写为“指标/值”两列，分组统计（如 by_status）在其后写为单独的表格
+func (s *ExportService) exportSystemReportXLSX(w io.Writer, report map[string]interface{}) error {
+	xw := utils.NewXLSXWriter(w)
+	for _, section := range systemReportSheets {
+		data, ok := report[section]
+		if !ok {
+			continue
+		}
+		if err := xw.AddSheet(section); err != nil {
+			return err
+		}
+		if err := writeReportSection(xw, data); err != nil {
+			return fmt.Errorf("failed to write %s: %w", section, err)
+		}
+	}
+	return xw.Close()
+}
+
+// writeReportSection 将一部分统计写入当前工作表
+func writeReportSection(xw *utils.XLSXWriter, section interface{}) error {
+	// 统一转换为 JSON 结构，按字段名读取匿名结构体中的统计
+	data, err := json.Marshal(section)
+	if err != nil {
+		return err
+	}
+	var values map[string]interface{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+
+	if err := xw.WriteHeader([]string{"指标", "值"}); err != nil {
+		return err
+	}
+
+	var scalars [][]interface{}
+	tables := make(map[string][]interface{})
+	flattenReportValues("", values, &scalars, tables)
+	for _, row := range scalars {
+		if err := xw.WriteRow(row); err != nil {
+			return err
+		}
+	}
+
+	names := make([]string, 0, len(tables))
+	for name := range tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := writeReportTable(xw, name, tables[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flattenReportValues 按名称顺序展开嵌套对象，标量写入 scalars，数组写入 tables
+func flattenReportValues(prefix string, values map[string]interface{}, scalars *[][]interface{}, tables map[string][]interface{}) {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		name := key
+		if prefix != "" {
+			name = prefix + "." + key
+		}
+		switch v := values[key].(type) {
+		case map[string]interface{}:
+			flattenReportValues(name, v, scalars, tables)
+		case []interface{}:
+			tables[name] = v
+		default:
+			*scalars = append(*scalars, []interface{}{name, v})
+		}
+	}
+}
+
+// writeReportTable 在空行后写入分组统计表格，表头为各行字段名的并集
+func writeReportTable(xw *utils.XLSXWriter, name string, rows []interface{}) error {
+	if err := xw.WriteRow(nil); err != nil {
+		return err
+	}
+	if err := xw.WriteRow([]interface{}{name}); err != nil {
+		return err
+	}
+
+	var columns []string
+	seen := make(map[string]bool)
+	for _, row := range rows {
+		fields, ok := row.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		keys := make([]string, 0, len(fields))
+		for key := range fields {
+			if !seen[key] {
+				keys = append(keys, key)
+				seen[key] = true
+			}
+		}
+		sort.Strings(keys)
+		columns = append(columns, keys...)
+	}
+
+	// 数组元素不是对象时逐行写入其值
+	if len(columns) == 0 {
+		for _, row := range rows {
+			if err := xw.WriteRow([]interface{}{row}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := xw.WriteHeader(columns); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		fields, _ := row.(map[string]interface{})
+		cells := make([]interface{}, len(columns))
+		for i, column := range columns {
+			cells[i] = fields[column]
+		}
+		if err := xw.WriteRow(cells); err != nil {
+			return err
+		}
+	}
+	return nil
+}
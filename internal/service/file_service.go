@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"crypto/md5"
+	"crypto/sha256"
 	"fmt"
 	"go-vibe-friend/internal/config"
 	"io"
@@ -154,6 +155,100 @@ func (s *FileService) GetFileObject(file *models.File) (*minio.Object, error) {
 	return s.minioClient.GetObject(context.Background(), s.cfg.MinIO.BucketName, file.FilePath, minio.GetObjectOptions{})
 }
 
+// JobArtifactCategory 任务产物文件的分类
+const JobArtifactCategory = "job_artifact"
+
+// SaveJobArtifact 将任务生成的文件上传到 MinIO 的 jobs/<任务ID>/ 目录下，并记录为属于任务所有者的文件。
+// 同一任务中同名的产物会被覆盖，任务重试时不会产生重复记录；size 未知时传 -1
+func (s *FileService) SaveJobArtifact(ctx context.Context, job *models.Job, name, mimeType string, reader io.Reader, size int64) (*models.File, error) {
+	name = filepath.Base(filepath.Clean("/" + name))
+	if name == "/" || name == "." {
+		return nil, fmt.Errorf("产物文件名不能为空")
+	}
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	objectName := fmt.Sprintf("jobs/%d/%s", job.ID, name)
+
+	// 哈希包含任务ID和文件名，不同任务生成的相同内容各自保存一条记录
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%d\n%s\n", job.ID, name)
+
+	info, err := s.minioClient.PutObject(ctx, s.cfg.MinIO.BucketName, objectName, io.TeeReader(reader, hash), size, minio.PutObjectOptions{
+		ContentType: mimeType,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("上传文件到 MinIO 失败: %v", err)
+	}
+	fileHash := fmt.Sprintf("%x", hash.Sum(nil))
+
+	existing, err := s.fileStore.GetJobFileByName(job.ID, name)
+	if err != nil {
+		return nil, fmt.Errorf("检查任务产物是否存在失败: %v", err)
+	}
+	if existing != nil {
+		existing.FilePath = objectName
+		existing.FileSize = info.Size
+		existing.MimeType = mimeType
+		existing.FileHash = fileHash
+		existing.Status = "active"
+		if err := s.fileStore.UpdateFile(existing); err != nil {
+			return nil, fmt.Errorf("保存文件信息失败: %v", err)
+		}
+		return existing, nil
+	}
+
+	jobID := job.ID
+	fileModel := &models.File{
+		FileName:     name,
+		OriginalName: name,
+		FilePath:     objectName,
+		FileSize:     info.Size,
+		MimeType:     mimeType,
+		FileHash:     fileHash,
+		UserID:       job.UserID,
+		Category:     JobArtifactCategory,
+		JobID:        &jobID,
+		Status:       "active",
+	}
+
+	if err := s.fileStore.CreateFile(fileModel); err != nil {
+		// 如果数据库保存失败，删除已上传的文件
+		_ = s.minioClient.RemoveObject(context.Background(), s.cfg.MinIO.BucketName, objectName, minio.RemoveObjectOptions{})
+		return nil, fmt.Errorf("保存文件信息失败: %v", err)
+	}
+
+	return fileModel, nil
+}
+
+// GetJobArtifacts 获取用户可以访问的任务产物文件
+func (s *FileService) GetJobArtifacts(jobID uint, userID uint) ([]models.File, error) {
+	files, err := s.fileStore.GetFilesByJobID(jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	artifacts := make([]models.File, 0, len(files))
+	for _, file := range files {
+		if file.IsPublic || file.UserID == userID {
+			artifacts = append(artifacts, file)
+		}
+	}
+	return artifacts, nil
+}
+
+// GetJobArtifact 获取任务的产物文件，访问权限与 GetFile 相同
+func (s *FileService) GetJobArtifact(jobID, fileID uint, userID uint) (*models.File, error) {
+	file, err := s.GetFile(fileID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if file.JobID == nil || *file.JobID != jobID {
+		return nil, fmt.Errorf("文件不存在")
+	}
+	return file, nil
+}
+
 // DeleteFile 删除文件
 func (s *FileService) DeleteFile(fileID uint, userID uint) error {
 	file, err := s.fileStore.GetFileByID(fileID)
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"go-vibe-friend/internal/config"
+	"go-vibe-friend/internal/store"
+)
+
+// IdempotencyService 保存 Idempotency-Key 对应的首次响应，
+// Redis 可用时存入 Redis，否则存入数据库
+type IdempotencyService struct {
+	storeManager *store.Store
+	cfg          config.IdempotencyConfig
+}
+
+func NewIdempotencyService(storeManager *store.Store, cfg config.IdempotencyConfig) *IdempotencyService {
+	return &IdempotencyService{
+		storeManager: storeManager,
+		cfg:          cfg,
+	}
+}
+
+// ScopedKey 将 Idempotency-Key 限定到用户和路由，不同用户使用相同的 key 互不影响
+func (s *IdempotencyService) ScopedKey(userID uint, method, route, key string) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d\n%s\n%s\n%s", userID, method, route, key)))
+	return hex.EncodeToString(sum[:])
+}
+
+// Reserve 占用幂等键；返回 nil 表示当前请求是首次请求，否则返回已保存的记录
+func (s *IdempotencyService) Reserve(key string) (*store.IdempotencyRecord, error) {
+	return s.storeManager.GetIdempotencyStore().Reserve(key, s.cfg.LockTTL)
+}
+
+// Save 保存首次请求的响应，在配置的有效期内重放
+func (s *IdempotencyService) Save(key string, record *store.IdempotencyRecord) error {
+	return s.storeManager.GetIdempotencyStore().Save(key, record, s.cfg.TTL)
+}
+
+// Release 释放幂等键，允许客户端重试
+func (s *IdempotencyService) Release(key string) error {
+	return s.storeManager.GetIdempotencyStore().Release(key)
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"go-vibe-friend/internal/models"
+	"go-vibe-friend/internal/store"
+)
+
+// JobEvent 任务状态变化事件
+type JobEvent struct {
+	JobID     uint      `json:"job_id"`
+	UserID    uint      `json:"user_id"`
+	JobType   string    `json:"job_type"`
+	Status    string    `json:"status"`
+	Progress  int       `json:"progress"`
+	Result    string    `json:"result,omitempty"`
+	ErrorMsg  string    `json:"error_msg,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// NewJobEvent 根据任务当前状态生成事件
+func NewJobEvent(job *models.Job) JobEvent {
+	return JobEvent{
+		JobID:     job.ID,
+		UserID:    job.UserID,
+		JobType:   job.JobType,
+		Status:    job.Status,
+		Progress:  job.Progress,
+		Result:    job.Result,
+		ErrorMsg:  job.ErrorMsg,
+		Timestamp: time.Now(),
+	}
+}
+
+// IsFinal 任务是否已结束，不会再有后续事件
+func (e JobEvent) IsFinal() bool {
+	return e.Status == models.JobStatusCompleted ||
+		e.Status == models.JobStatusFailed ||
+		e.Status == models.JobStatusCancelled
+}
+
+// JobEventService 发布和订阅任务事件。
+// Redis 可用时通过 pub/sub 广播到所有实例，否则只在当前进程内分发。
+type JobEventService struct {
+	notify *store.RedisNotifyService
+
+	mu          sync.RWMutex
+	subscribers map[string]map[chan JobEvent]struct{}
+}
+
+func NewJobEventService(notify *store.RedisNotifyService) *JobEventService {
+	return &JobEventService{
+		notify:      notify,
+		subscribers: make(map[string]map[chan JobEvent]struct{}),
+	}
+}
+
+func jobEventChannel(jobID uint) string {
+	return fmt.Sprintf("job:%d", jobID)
+}
+
+func userJobEventChannel(userID uint) string {
+	return fmt.Sprintf("user:%d:jobs", userID)
+}
+
+// jobCancelChannel 取消信号频道，所有 worker 都会订阅
+const jobCancelChannel = "jobs:cancel"
+
+// Publish 发布任务事件到任务频道和所属用户频道
+func (s *JobEventService) Publish(event JobEvent) {
+	s.publish([]string{jobEventChannel(event.JobID), userJobEventChannel(event.UserID)}, event)
+}
+
+func (s *JobEventService) publish(channels []string, event JobEvent) {
+	if s.notify != nil {
+		for _, channel := range channels {
+			if err := s.notify.Publish(channel, event); err != nil {
+				log.Printf("Failed to publish job event for job %d: %v", event.JobID, err)
+			}
+		}
+		return
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, channel := range channels {
+		for ch := range s.subscribers[channel] {
+			select {
+			case ch <- event:
+			default:
+				// 订阅方处理过慢时丢弃中间事件，客户端会收到之后的最新状态
+			}
+		}
+	}
+}
+
+// PublishCancel 通知所有 worker 停止执行该任务
+func (s *JobEventService) PublishCancel(event JobEvent) {
+	s.publish([]string{jobCancelChannel}, event)
+}
+
+// SubscribeCancellations 订阅任务取消信号
+func (s *JobEventService) SubscribeCancellations(ctx context.Context) (<-chan JobEvent, error) {
+	return s.subscribe(ctx, jobCancelChannel)
+}
+
+// SubscribeJob 订阅单个任务的事件，ctx 结束时返回的 channel 会被关闭
+func (s *JobEventService) SubscribeJob(ctx context.Context, jobID uint) (<-chan JobEvent, error) {
+	return s.subscribe(ctx, jobEventChannel(jobID))
+}
+
+// SubscribeUser 订阅某个用户所有任务的事件
+func (s *JobEventService) SubscribeUser(ctx context.Context, userID uint) (<-chan JobEvent, error) {
+	return s.subscribe(ctx, userJobEventChannel(userID))
+}
+
+func (s *JobEventService) subscribe(ctx context.Context, channel string) (<-chan JobEvent, error) {
+	if s.notify != nil {
+		return s.subscribeRedis(ctx, channel)
+	}
+
+	ch := make(chan JobEvent, 16)
+	s.mu.Lock()
+	if s.subscribers[channel] == nil {
+		s.subscribers[channel] = make(map[chan JobEvent]struct{})
+	}
+	s.subscribers[channel][ch] = struct{}{}
+	s.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		s.mu.Lock()
+		delete(s.subscribers[channel], ch)
+		if len(s.subscribers[channel]) == 0 {
+			delete(s.subscribers, channel)
+		}
+		s.mu.Unlock()
+		close(ch)
+	}()
+
+	return ch, nil
+}
+
+func (s *JobEventService) subscribeRedis(ctx context.Context, channel string) (<-chan JobEvent, error) {
+	messages, err := s.notify.Subscribe(ctx, channel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to job events: %w", err)
+	}
+
+	events := make(chan JobEvent, 16)
+	go func() {
+		defer close(events)
+		for payload := range messages {
+			var event JobEvent
+			if err := json.Unmarshal([]byte(payload), &event); err != nil {
+				continue // Skip malformed events
+			}
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return events, nil
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"go-vibe-friend/internal/models"
+	"go-vibe-friend/internal/store"
+)
+
+var (
+	// ErrQueuedJobNotFound 队列中不存在该等待执行的任务
+	ErrQueuedJobNotFound = errors.New("queued job not found")
+	// ErrInvalidPriority 任务优先级超出范围
+	ErrInvalidPriority = errors.New("priority must be between 0 and 10")
+)
+
+// QueueSummary 队列中各状态的任务数量
+type QueueSummary struct {
+	Name     string `json:"name"`
+	Depth    int64  `json:"depth"`    // 等待 worker 执行的任务
+	Inflight int64  `json:"inflight"` // 已被 worker 取走、正在执行的任务
+	Delayed  int64  `json:"delayed"`  // 计划执行或等待重试的任务
+	Dead     int64  `json:"dead"`     // 死信任务
+}
+
+// ListQueues 获取所有队列及其任务数量，默认队列总是包含在内
+func (s *JobService) ListQueues() ([]QueueSummary, error) {
+	if s.queue == nil {
+		return nil, ErrQueueUnavailable
+	}
+
+	names, err := s.queue.ListQueues()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list queues: %w", err)
+	}
+	hasDefault := false
+	for _, name := range names {
+		if name == DefaultQueue {
+			hasDefault = true
+		}
+	}
+	if !hasDefault {
+		names = append([]string{DefaultQueue}, names...)
+	}
+
+	queues := make([]QueueSummary, 0, len(names))
+	for _, name := range names {
+		summary, err := s.GetQueue(name)
+		if err != nil {
+			return nil, err
+		}
+		queues = append(queues, *summary)
+	}
+	return queues, nil
+}
+
+// GetQueue 获取单个队列的任务数量
+func (s *JobService) GetQueue(queueName string) (*QueueSummary, error) {
+	if s.queue == nil {
+		return nil, ErrQueueUnavailable
+	}
+
+	summary := &QueueSummary{Name: queueName}
+	counts := []struct {
+		dst   *int64
+		count func(string) (int64, error)
+	}{
+		{&summary.Depth, s.queue.GetQueueLength},
+		{&summary.Inflight, s.queue.GetInflightLength},
+		{&summary.Delayed, s.queue.GetDelayedLength},
+		{&summary.Dead, s.queue.GetDeadLetterLength},
+	}
+	for _, c := range counts {
+		count, err := c.count(queueName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count jobs in queue %q: %w", queueName, err)
+		}
+		*c.dst = count
+	}
+	return summary, nil
+}
+
+// QueueDepth 所有队列中等待执行的任务总数
+func (s *JobService) QueueDepth() (int64, error) {
+	queues, err := s.ListQueues()
+	if err != nil {
+		return 0, err
+	}
+	var depth int64
+	for _, queue := range queues {
+		depth += queue.Depth
+	}
+	return depth, nil
+}
+
+// ListQueuedJobs 分页获取队列中等待执行的任务；delayed 为 true 时获取计划执行或等待重试的任务
+func (s *JobService) ListQueuedJobs(queueName string, delayed bool, limit, offset int) ([]store.QueuedJob, int64, error) {
+	if s.queue == nil {
+		return nil, 0, ErrQueueUnavailable
+	}
+
+	length, list := s.queue.GetQueueLength, s.queue.GetJobs
+	if delayed {
+		length, list = s.queue.GetDelayedLength, s.queue.GetDelayedJobs
+	}
+
+	total, err := length(queueName)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count queued jobs: %w", err)
+	}
+
+	jobs, err := list(queueName, int64(offset), int64(offset+limit-1))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list queued jobs: %w", err)
+	}
+	if jobs == nil {
+		jobs = []store.QueuedJob{}
+	}
+
+	return jobs, total, nil
+}
+
+// SetQueuedJobPriority 修改等待执行的任务的优先级，并同步到任务记录
+func (s *JobService) SetQueuedJobPriority(queueName, id string, priority int) (*store.QueuedJob, error) {
+	if s.queue == nil {
+		return nil, ErrQueueUnavailable
+	}
+	if priority < 0 || priority > 10 {
+		return nil, ErrInvalidPriority
+	}
+
+	queued, err := s.queue.SetQueuedJobPriority(queueName, id, priority)
+	if err != nil {
+		return nil, fmt.Errorf("failed to change job priority: %w", err)
+	}
+	if queued == nil {
+		return nil, ErrQueuedJobNotFound
+	}
+
+	if queued.JobID != 0 {
+		if err := s.jobStore.UpdateJobPriority(queued.JobID, priority); err != nil {
+			log.Printf("Failed to update priority of job %d: %v", queued.JobID, err)
+		}
+	}
+	return queued, nil
+}
+
+// RemoveQueuedJob 从队列中移除等待执行的任务，任务记录被标记为已取消，
+// 等待该任务的下游任务会被标记失败
+func (s *JobService) RemoveQueuedJob(queueName, id string) error {
+	if s.queue == nil {
+		return ErrQueueUnavailable
+	}
+
+	removed, err := s.queue.RemoveQueuedJob(queueName, id)
+	if err != nil {
+		return fmt.Errorf("failed to remove queued job: %w", err)
+	}
+	if !removed {
+		return ErrQueuedJobNotFound
+	}
+
+	// 队列任务ID即任务记录ID
+	jobID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return nil
+	}
+	job, err := s.jobStore.GetJobByID(uint(jobID))
+	if err != nil || job == nil || job.Status != models.JobStatusPending {
+		return nil
+	}
+
+	now := time.Now()
+	job.Status = models.JobStatusCancelled
+	job.ErrorMsg = "removed from queue"
+	job.FinishedAt = &now
+	if err := s.transition(job); err != nil {
+		log.Printf("Failed to cancel job %d removed from queue %q: %v", job.ID, queueName, err)
+		return nil
+	}
+	s.resolveDependents(job)
+	return nil
+}
@@ -0,0 +1,466 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"go-vibe-friend/internal/models"
+	"go-vibe-friend/internal/store"
+)
+
+// DefaultQueue 默认任务队列名称
+const DefaultQueue = "default"
+
+var (
+	// ErrQueueUnavailable 任务队列不可用
+	ErrQueueUnavailable = errors.New("job queue is not available")
+	// ErrDeadJobNotFound 死信队列中不存在该任务
+	ErrDeadJobNotFound = errors.New("dead job not found")
+	// ErrEventsUnavailable 未配置任务事件服务
+	ErrEventsUnavailable = errors.New("job events are not available")
+	// ErrJobNotFound 任务不存在
+	ErrJobNotFound = errors.New("job not found")
+	// ErrJobNotActive 任务已结束（完成、失败或取消），不能再变更状态
+	ErrJobNotActive = errors.New("job is no longer active")
+	// ErrJobNotReplayable 只有失败的任务可以从死信队列重新执行
+	ErrJobNotReplayable = errors.New("only failed jobs can be replayed")
+	// ErrInvalidDependency 依赖的任务不存在或已失败
+	ErrInvalidDependency = errors.New("invalid job dependency")
+	// ErrConcurrencyLimit 用户或任务类型运行中的任务已达到并发上限
+	ErrConcurrencyLimit = errors.New("job concurrency limit reached")
+)
+
+type JobService struct {
+	jobStore *store.JobStore
+	queue    store.JobQueue
+	events   *JobEventService
+	limits   *ConcurrencyService
+}
+
+func NewJobService(jobStore *store.JobStore, queue store.JobQueue, events *JobEventService, limits *ConcurrencyService) *JobService {
+	return &JobService{
+		jobStore: jobStore,
+		queue:    queue,
+		events:   events,
+		limits:   limits,
+	}
+}
+
+// SubmitJobRequest 提交任务请求
+type SubmitJobRequest struct {
+	UserID      uint
+	Title       string
+	Description string
+	JobType     string
+	Queue       string
+	Priority    int
+	MaxRetries  int
+	Payload     map[string]interface{}
+	RunAt       *time.Time // 计划执行时间，为空或已过去则立即执行
+	DependsOn   []uint     // 依赖的任务ID，全部成功完成后才会入队
+}
+
+// SubmitJob 创建任务记录并放入队列等待 worker 执行。
+// 有未完成的依赖时任务处于 waiting 状态，依赖全部成功完成后才会入队。
+func (s *JobService) SubmitJob(req *SubmitJobRequest) (*models.Job, error) {
+	if s.queue == nil {
+		return nil, ErrQueueUnavailable
+	}
+
+	queueName := req.Queue
+	if queueName == "" {
+		queueName = DefaultQueue
+	}
+
+	var runAt *time.Time
+	if req.RunAt != nil && req.RunAt.After(time.Now()) {
+		runAt = req.RunAt
+	}
+
+	parentIDs, ready, err := s.checkDependencies(req.DependsOn)
+	if err != nil {
+		return nil, err
+	}
+	status := models.JobStatusPending
+	if !ready {
+		status = models.JobStatusWaiting
+	}
+
+	payload := ""
+	if req.Payload != nil {
+		data, err := json.Marshal(req.Payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal job payload: %w", err)
+		}
+		payload = string(data)
+	}
+
+	job := &models.Job{
+		UserID:      req.UserID,
+		Title:       req.Title,
+		Description: req.Description,
+		Status:      status,
+		JobType:     req.JobType,
+		Progress:    0,
+		Queue:       queueName,
+		Priority:    req.Priority,
+		Payload:     payload,
+		MaxRetries:  req.MaxRetries,
+		RunAt:       runAt,
+	}
+
+	if err := s.jobStore.CreateJobWithDependencies(job, parentIDs); err != nil {
+		return nil, fmt.Errorf("failed to create job: %w", err)
+	}
+
+	if !ready {
+		s.publish(job)
+		// 依赖可能在检查之后、依赖关系写入之前结束
+		s.resolve(job)
+		return job, nil
+	}
+
+	if err := s.enqueue(job); err != nil {
+		// 入队失败时标记任务失败，避免任务永远停留在 pending
+		_ = s.FailJob(job, fmt.Sprintf("failed to enqueue job: %v", err))
+		return nil, fmt.Errorf("failed to enqueue job: %w", err)
+	}
+
+	s.publish(job)
+	return job, nil
+}
+
+// enqueue 根据任务记录构造队列消息并放入任务所在的队列
+func (s *JobService) enqueue(job *models.Job) error {
+	var payload map[string]interface{}
+	if job.Payload != "" {
+		if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+			return fmt.Errorf("invalid job payload: %w", err)
+		}
+	}
+
+	queued := store.QueuedJob{
+		ID:         strconv.FormatUint(uint64(job.ID), 10),
+		JobID:      job.ID,
+		UserID:     job.UserID,
+		Type:       job.JobType,
+		Priority:   job.Priority,
+		Payload:    payload,
+		MaxRetries: job.MaxRetries,
+	}
+	if job.RunAt != nil && job.RunAt.After(time.Now()) {
+		queued.RunAt = *job.RunAt
+	}
+
+	return s.queue.Enqueue(job.Queue, queued)
+}
+
+// GetJob 根据ID获取任务
+func (s *JobService) GetJob(id uint) (*models.Job, error) {
+	return s.jobStore.GetJobByID(id)
+}
+
+// StartJob 标记任务开始执行，任务已被取消时返回 ErrJobNotActive，
+// 用户或任务类型已达到并发上限时返回 ErrConcurrencyLimit
+func (s *JobService) StartJob(job *models.Job) error {
+	now := time.Now()
+	job.Status = models.JobStatusRunning
+	job.Progress = 0
+	job.ErrorMsg = ""
+	job.Attempts++
+	job.StartedAt = &now
+	job.FinishedAt = nil
+
+	if s.limits == nil {
+		return s.transition(job)
+	}
+	userLimit, typeLimit, err := s.limits.LimitsFor(job.UserID, job.JobType)
+	if err != nil {
+		return err
+	}
+	if userLimit < 0 && typeLimit < 0 {
+		return s.transition(job)
+	}
+
+	// 检查运行数量和更新在同一事务中串行执行，避免多个 worker 同时超出上限
+	started, err := s.jobStore.StartJobWithinLimits(job, userLimit, typeLimit)
+	if err != nil {
+		return err
+	}
+	if !started {
+		current, err := s.jobStore.GetJobByID(job.ID)
+		if err != nil {
+			return err
+		}
+		if current != nil && (current.Status == models.JobStatusPending || current.Status == models.JobStatusRunning) {
+			return ErrConcurrencyLimit
+		}
+		return ErrJobNotActive
+	}
+	s.publish(job)
+	return nil
+}
+
+// ReserveFilter 返回 worker 取任务时需要跳过的用户和任务类型
+func (s *JobService) ReserveFilter() (store.ReserveFilter, error) {
+	if s.limits == nil {
+		return store.ReserveFilter{}, nil
+	}
+	return s.limits.ReserveFilter()
+}
+
+// UpdateProgress 更新运行中任务的进度（0-100）
+func (s *JobService) UpdateProgress(job *models.Job, progress int) error {
+	if progress < 0 {
+		progress = 0
+	}
+	if progress > 100 {
+		progress = 100
+	}
+
+	updated, err := s.jobStore.UpdateJobProgress(job.ID, progress)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return ErrJobNotActive
+	}
+	job.Progress = progress
+	s.publish(job)
+	return nil
+}
+
+// CompleteJob 标记任务成功完成，并将依赖已全部完成的下游任务入队
+func (s *JobService) CompleteJob(job *models.Job, result string) error {
+	now := time.Now()
+	job.Status = models.JobStatusCompleted
+	job.Progress = 100
+	job.Result = result
+	job.ErrorMsg = ""
+	job.FinishedAt = &now
+	if err := s.transition(job); err != nil {
+		return err
+	}
+	s.resolveDependents(job)
+	return nil
+}
+
+// FailJob 标记任务最终失败，等待它的下游任务也会被标记失败
+func (s *JobService) FailJob(job *models.Job, errMsg string) error {
+	now := time.Now()
+	job.Status = models.JobStatusFailed
+	job.ErrorMsg = errMsg
+	job.FinishedAt = &now
+	if err := s.transition(job); err != nil {
+		return err
+	}
+	s.resolveDependents(job)
+	return nil
+}
+
+// RetryJob 任务执行失败但会重试，状态回到 pending 并保留最近的错误
+func (s *JobService) RetryJob(job *models.Job, errMsg string) error {
+	job.Status = models.JobStatusPending
+	job.ErrorMsg = errMsg
+	job.StartedAt = nil
+	return s.transition(job)
+}
+
+// CancelJob 取消等待中或运行中的任务：从队列中移除等待的任务，并通知 worker 中止正在执行的任务。
+// 等待该任务的下游任务会被标记失败。
+func (s *JobService) CancelJob(id uint) (*models.Job, error) {
+	job, err := s.jobStore.GetJobByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job: %w", err)
+	}
+	if job == nil {
+		return nil, ErrJobNotFound
+	}
+
+	wasPending := job.Status == models.JobStatusPending
+	now := time.Now()
+	job.Status = models.JobStatusCancelled
+	job.ErrorMsg = "cancelled"
+	job.FinishedAt = &now
+	if err := s.transition(job); err != nil {
+		return nil, err
+	}
+
+	if wasPending && s.queue != nil {
+		queueName := job.Queue
+		if queueName == "" {
+			queueName = DefaultQueue
+		}
+		// 未能移除时 worker 取到任务后会因状态为 cancelled 而跳过
+		if _, err := s.queue.RemoveQueuedJob(queueName, strconv.FormatUint(uint64(job.ID), 10)); err != nil {
+			log.Printf("Failed to remove cancelled job %d from queue %q: %v", job.ID, queueName, err)
+		}
+	}
+
+	if s.events != nil {
+		s.events.PublishCancel(NewJobEvent(job))
+	}
+
+	s.resolveDependents(job)
+	return job, nil
+}
+
+// SubscribeCancellations 订阅任务取消信号，供 worker 中止正在执行的任务
+func (s *JobService) SubscribeCancellations(ctx context.Context) (<-chan JobEvent, error) {
+	if s.events == nil {
+		return nil, ErrEventsUnavailable
+	}
+	return s.events.SubscribeCancellations(ctx)
+}
+
+// SubscribeJobEvents 订阅单个任务的状态变化
+func (s *JobService) SubscribeJobEvents(ctx context.Context, jobID uint) (<-chan JobEvent, error) {
+	if s.events == nil {
+		return nil, ErrEventsUnavailable
+	}
+	return s.events.SubscribeJob(ctx, jobID)
+}
+
+// SubscribeUserJobEvents 订阅用户所有任务的状态变化
+func (s *JobService) SubscribeUserJobEvents(ctx context.Context, userID uint) (<-chan JobEvent, error) {
+	if s.events == nil {
+		return nil, ErrEventsUnavailable
+	}
+	return s.events.SubscribeUser(ctx, userID)
+}
+
+// save 保存任务并通知订阅方
+func (s *JobService) save(job *models.Job) error {
+	if err := s.jobStore.UpdateJob(job); err != nil {
+		return err
+	}
+	s.publish(job)
+	return nil
+}
+
+// transition 仅在任务仍处于 waiting、pending 或 running 时保存，
+// 避免 worker 覆盖已被取消的任务
+func (s *JobService) transition(job *models.Job) error {
+	updated, err := s.jobStore.UpdateJobIfStatus(job, models.JobStatusWaiting, models.JobStatusPending, models.JobStatusRunning)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return ErrJobNotActive
+	}
+	s.publish(job)
+	return nil
+}
+
+func (s *JobService) publish(job *models.Job) {
+	if s.events != nil {
+		s.events.Publish(NewJobEvent(job))
+	}
+}
+
+// ListDeadJobs 分页获取队列中的死信任务
+func (s *JobService) ListDeadJobs(queueName string, limit, offset int) ([]store.DeadJob, int64, error) {
+	if s.queue == nil {
+		return nil, 0, ErrQueueUnavailable
+	}
+
+	total, err := s.queue.GetDeadLetterLength(queueName)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count dead jobs: %w", err)
+	}
+
+	jobs, err := s.queue.GetDeadJobs(queueName, int64(offset), int64(offset+limit-1))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list dead jobs: %w", err)
+	}
+
+	return jobs, total, nil
+}
+
+// GetDeadJob 获取单个死信任务
+func (s *JobService) GetDeadJob(queueName, id string) (*store.DeadJob, error) {
+	if s.queue == nil {
+		return nil, ErrQueueUnavailable
+	}
+
+	dead, err := s.queue.GetDeadJob(queueName, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dead job: %w", err)
+	}
+	if dead == nil {
+		return nil, ErrDeadJobNotFound
+	}
+	return dead, nil
+}
+
+// ReplayDeadJob 将死信任务重新放回队列，并把任务记录从 failed 重置为 pending，
+// 因它级联失败的下游任务恢复为 waiting。任务已被取消或已完成时返回 ErrJobNotReplayable
+func (s *JobService) ReplayDeadJob(queueName, id string) (*store.DeadJob, error) {
+	dead, err := s.GetDeadJob(queueName, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// 先重置任务记录，避免 worker 取到任务时看到 failed 状态而跳过；
+	// 只在任务仍为 failed 时更新，不会复活同时被取消的任务
+	job, err := s.jobStore.GetJobByID(dead.Job.JobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job: %w", err)
+	}
+	if job != nil {
+		job.Status = models.JobStatusPending
+		job.Progress = 0
+		job.ErrorMsg = ""
+		job.StartedAt = nil
+		job.FinishedAt = nil
+		updated, err := s.jobStore.UpdateJobIfStatus(job, models.JobStatusFailed)
+		if err != nil {
+			return nil, fmt.Errorf("failed to reset job: %w", err)
+		}
+		if !updated {
+			return nil, ErrJobNotReplayable
+		}
+		s.publish(job)
+	}
+
+	replayed, err := s.queue.ReplayDeadJob(queueName, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to replay dead job: %w", err)
+	}
+	if replayed == nil {
+		return nil, ErrDeadJobNotFound
+	}
+	if job != nil {
+		s.reopenDependents(job)
+	}
+	return replayed, nil
+}
+
+// PurgeDeadJob 删除单个死信任务
+func (s *JobService) PurgeDeadJob(queueName, id string) error {
+	if s.queue == nil {
+		return ErrQueueUnavailable
+	}
+
+	dead, err := s.queue.RemoveDeadJob(queueName, id)
+	if err != nil {
+		return fmt.Errorf("failed to purge dead job: %w", err)
+	}
+	if dead == nil {
+		return ErrDeadJobNotFound
+	}
+	return nil
+}
+
+// PurgeDeadJobs 清空队列的死信任务，返回删除数量
+func (s *JobService) PurgeDeadJobs(queueName string) (int64, error) {
+	if s.queue == nil {
+		return 0, ErrQueueUnavailable
+	}
+	return s.queue.PurgeDeadLetter(queueName)
+}
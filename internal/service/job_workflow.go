@@ -0,0 +1,302 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"go-vibe-friend/internal/models"
+)
+
+// maxWorkflowNodes 依赖图最多返回的任务数量
+const maxWorkflowNodes = 500
+
+// WorkflowNode 依赖图中的一个任务
+type WorkflowNode struct {
+	JobID      uint       `json:"job_id"`
+	Title      string     `json:"title"`
+	JobType    string     `json:"job_type"`
+	Status     string     `json:"status"`
+	Progress   int        `json:"progress"`
+	ErrorMsg   string     `json:"error_msg,omitempty"`
+	DependsOn  []uint     `json:"depends_on"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// WorkflowEdge 依赖关系，To 在 From 成功完成后才会执行
+type WorkflowEdge struct {
+	From uint `json:"from"`
+	To   uint `json:"to"`
+}
+
+// JobWorkflow 任务所在的依赖图及各节点状态
+type JobWorkflow struct {
+	Status    string         `json:"status"` // pending, running, completed, failed
+	Counts    map[string]int `json:"counts"`
+	Nodes     []WorkflowNode `json:"nodes"`
+	Edges     []WorkflowEdge `json:"edges"`
+	Truncated bool           `json:"truncated"` // 超过 maxWorkflowNodes 时只返回部分节点
+}
+
+// checkDependencies 校验依赖的任务，返回去重后的任务ID以及是否已全部成功完成。
+// 依赖只能指向已存在的任务，因此依赖图不会出现环。
+func (s *JobService) checkDependencies(ids []uint) ([]uint, bool, error) {
+	seen := make(map[uint]bool, len(ids))
+	parentIDs := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			parentIDs = append(parentIDs, id)
+		}
+	}
+
+	parents, err := s.jobStore.GetJobsByIDs(parentIDs)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get dependency jobs: %w", err)
+	}
+
+	found := make(map[uint]bool, len(parents))
+	ready := true
+	for _, parent := range parents {
+		found[parent.ID] = true
+		switch parent.Status {
+		case models.JobStatusCompleted:
+		case models.JobStatusFailed, models.JobStatusCancelled:
+			return nil, false, fmt.Errorf("%w: job %d has %s", ErrInvalidDependency, parent.ID, parent.Status)
+		default:
+			ready = false
+		}
+	}
+	for _, id := range parentIDs {
+		if !found[id] {
+			return nil, false, fmt.Errorf("%w: job %d does not exist", ErrInvalidDependency, id)
+		}
+	}
+
+	return parentIDs, ready, nil
+}
+
+// resolveDependents 任务结束后检查所有等待它的下游任务
+func (s *JobService) resolveDependents(parent *models.Job) {
+	children, err := s.jobStore.GetDependentJobs(parent.ID)
+	if err != nil {
+		log.Printf("Failed to load dependents of job %d: %v", parent.ID, err)
+		return
+	}
+
+	for i := range children {
+		if children[i].Status == models.JobStatusWaiting {
+			s.resolve(&children[i])
+		}
+	}
+}
+
+// resolve 依赖全部成功完成时将等待中的任务入队，任一依赖失败或被取消时级联标记失败
+func (s *JobService) resolve(job *models.Job) {
+	parents, err := s.jobStore.GetParentJobs(job.ID)
+	if err != nil {
+		log.Printf("Failed to load dependencies of job %d: %v", job.ID, err)
+		return
+	}
+
+	ready := true
+	for _, parent := range parents {
+		switch parent.Status {
+		case models.JobStatusCompleted:
+		case models.JobStatusFailed, models.JobStatusCancelled:
+			s.failWaiting(job, fmt.Sprintf("dependency job %d %s", parent.ID, parent.Status))
+			return
+		default:
+			ready = false
+		}
+	}
+	if !ready {
+		return
+	}
+
+	// 多个依赖同时完成时只有一个能将任务从 waiting 改为 pending
+	job.Status = models.JobStatusPending
+	updated, err := s.jobStore.UpdateJobIfStatus(job, models.JobStatusWaiting)
+	if err != nil {
+		log.Printf("Failed to release job %d: %v", job.ID, err)
+		return
+	}
+	if !updated {
+		return
+	}
+
+	if err := s.enqueue(job); err != nil {
+		log.Printf("Failed to enqueue released job %d: %v", job.ID, err)
+		_ = s.FailJob(job, fmt.Sprintf("failed to enqueue job: %v", err))
+		return
+	}
+	s.publish(job)
+}
+
+// failWaiting 将等待中的任务标记为失败，并继续传递给它的下游任务
+func (s *JobService) failWaiting(job *models.Job, errMsg string) {
+	now := time.Now()
+	job.Status = models.JobStatusFailed
+	job.ErrorMsg = errMsg
+	job.FinishedAt = &now
+
+	updated, err := s.jobStore.UpdateJobIfStatus(job, models.JobStatusWaiting)
+	if err != nil {
+		log.Printf("Failed to fail dependent job %d: %v", job.ID, err)
+		return
+	}
+	if !updated {
+		return
+	}
+
+	s.publish(job)
+	s.resolveDependents(job)
+}
+
+// reopenDependents 失败的任务重新执行时，将因它而级联失败的下游任务恢复为 waiting，
+// 任务结束后由 resolveDependents 重新判断。下游任务要等依赖全部完成才会开始，
+// 因此失败任务下从未开始的 failed 任务都是 failWaiting 级联标记的
+func (s *JobService) reopenDependents(parent *models.Job) {
+	children, err := s.jobStore.GetDependentJobs(parent.ID)
+	if err != nil {
+		log.Printf("Failed to load dependents of job %d: %v", parent.ID, err)
+		return
+	}
+
+	for i := range children {
+		child := &children[i]
+		if child.Status != models.JobStatusFailed || child.StartedAt != nil {
+			continue
+		}
+		child.Status = models.JobStatusWaiting
+		child.ErrorMsg = ""
+		child.FinishedAt = nil
+
+		updated, err := s.jobStore.UpdateJobIfStatus(child, models.JobStatusFailed)
+		if err != nil {
+			log.Printf("Failed to reopen dependent job %d: %v", child.ID, err)
+			continue
+		}
+		if !updated {
+			continue
+		}
+
+		s.publish(child)
+		s.reopenDependents(child)
+	}
+}
+
+// GetWorkflow 获取任务所在的完整依赖图（包括上游和下游任务）
+func (s *JobService) GetWorkflow(id uint) (*JobWorkflow, error) {
+	job, err := s.jobStore.GetJobByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job: %w", err)
+	}
+	if job == nil {
+		return nil, ErrJobNotFound
+	}
+
+	seen := map[uint]bool{id: true}
+	edges := make(map[WorkflowEdge]bool)
+	truncated := false
+	for frontier := []uint{id}; len(frontier) > 0; {
+		deps, err := s.jobStore.GetDependencies(frontier)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get job dependencies: %w", err)
+		}
+
+		var next []uint
+		for _, dep := range deps {
+			edges[WorkflowEdge{From: dep.ParentID, To: dep.JobID}] = true
+			for _, jobID := range []uint{dep.ParentID, dep.JobID} {
+				if seen[jobID] {
+					continue
+				}
+				if len(seen) >= maxWorkflowNodes {
+					truncated = true
+					continue
+				}
+				seen[jobID] = true
+				next = append(next, jobID)
+			}
+		}
+		frontier = next
+	}
+
+	ids := make([]uint, 0, len(seen))
+	for jobID := range seen {
+		ids = append(ids, jobID)
+	}
+	jobs, err := s.jobStore.GetJobsByIDs(ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workflow jobs: %w", err)
+	}
+
+	workflow := &JobWorkflow{
+		Counts:    make(map[string]int),
+		Nodes:     make([]WorkflowNode, 0, len(jobs)),
+		Edges:     make([]WorkflowEdge, 0, len(edges)),
+		Truncated: truncated,
+	}
+
+	// 已删除或超出范围的任务不出现在图中
+	index := make(map[uint]int, len(jobs))
+	for i, j := range jobs {
+		index[j.ID] = i
+		workflow.Counts[j.Status]++
+		workflow.Nodes = append(workflow.Nodes, WorkflowNode{
+			JobID:      j.ID,
+			Title:      j.Title,
+			JobType:    j.JobType,
+			Status:     j.Status,
+			Progress:   j.Progress,
+			ErrorMsg:   j.ErrorMsg,
+			DependsOn:  []uint{},
+			StartedAt:  j.StartedAt,
+			FinishedAt: j.FinishedAt,
+		})
+	}
+	for edge := range edges {
+		from, ok := index[edge.From]
+		if !ok {
+			continue
+		}
+		to, ok := index[edge.To]
+		if !ok {
+			continue
+		}
+		workflow.Edges = append(workflow.Edges, edge)
+		workflow.Nodes[to].DependsOn = append(workflow.Nodes[to].DependsOn, workflow.Nodes[from].JobID)
+	}
+
+	sort.Slice(workflow.Edges, func(i, j int) bool {
+		if workflow.Edges[i].From != workflow.Edges[j].From {
+			return workflow.Edges[i].From < workflow.Edges[j].From
+		}
+		return workflow.Edges[i].To < workflow.Edges[j].To
+	})
+	for i := range workflow.Nodes {
+		deps := workflow.Nodes[i].DependsOn
+		sort.Slice(deps, func(a, b int) bool { return deps[a] < deps[b] })
+	}
+
+	workflow.Status = workflowStatus(workflow.Counts, len(jobs))
+	return workflow, nil
+}
+
+// workflowStatus 汇总依赖图的整体状态
+func workflowStatus(counts map[string]int, total int) string {
+	active := counts[models.JobStatusWaiting] + counts[models.JobStatusPending] + counts[models.JobStatusRunning]
+	switch {
+	case counts[models.JobStatusCompleted] == total:
+		return models.JobStatusCompleted
+	case active == 0:
+		return models.JobStatusFailed
+	case active < total || counts[models.JobStatusRunning] > 0:
+		return models.JobStatusRunning
+	default:
+		return models.JobStatusPending
+	}
+}
@@ -0,0 +1,451 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"go-vibe-friend/internal/models"
+	"go-vibe-friend/internal/store"
+)
+
+// JobTypePersonalDataExport 用户导出本人数据的任务类型
+const JobTypePersonalDataExport = "personal_data_export"
+
+const (
+	// personalDataRetention 数据包生成后可供下载的时间
+	personalDataRetention = 7 * 24 * time.Hour
+	// personalDataLinkExpiry 每次获取的下载链接的有效期
+	personalDataLinkExpiry = time.Hour
+)
+
+// PersonalDataResult 个人数据导出任务的结果
+type PersonalDataResult struct {
+	ExportID     uint           `json:"export_id"`
+	FileName     string         `json:"file_name"`
+	FileSize     int64          `json:"file_size"`
+	ExpiresAt    time.Time      `json:"expires_at"`              // 数据包可供下载的截止时间
+	Counts       map[string]int `json:"counts"`                  // 各部分的记录数
+	MissingFiles int            `json:"missing_files,omitempty"` // 无法从存储读取、未包含在数据包中的文件数
+}
+
+// PersonalDataManifest 数据包中的 manifest.json，说明数据包的内容
+type PersonalDataManifest struct {
+	UserID       uint                      `json:"user_id"`
+	GeneratedAt  time.Time                 `json:"generated_at"`
+	Contents     map[string]string         `json:"contents"` // 数据包中的文件及说明
+	Counts       map[string]int            `json:"counts"`
+	MissingFiles []PersonalDataMissingFile `json:"missing_files"`
+}
+
+// PersonalDataMissingFile 无法从存储读取的文件
+type PersonalDataMissingFile struct {
+	FileID uint   `json:"file_id"`
+	Name   string `json:"name"`
+	Error  string `json:"error"`
+}
+
+// personalDataAccount account.json 的内容
+type personalDataAccount struct {
+	ID        uint      `json:"id"`
+	Username  string    `json:"username"`
+	Email     string    `json:"email"`
+	Status    string    `json:"status"`
+	Roles     []string  `json:"roles"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// personalDataSession sessions.json 中的会话，不包含刷新令牌
+type personalDataSession struct {
+	ID        uint      `json:"id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	LastUsed  time.Time `json:"last_used"`
+	ExpiresAt time.Time `json:"expires_at"`
+	IsRevoked bool      `json:"is_revoked"`
+	IPAddress string    `json:"ip_address"`
+	UserAgent string    `json:"user_agent"`
+}
+
+// personalDataFile files.json 中的文件信息，Path 为文件在数据包中的路径
+type personalDataFile struct {
+	models.File
+	Path string `json:"path,omitempty"`
+}
+
+// personalDataContents 数据包中各文件的说明
+var personalDataContents = map[string]string{
+	"manifest.json":   "数据包说明",
+	"account.json":    "账号信息和角色",
+	"profile.json":    "个人资料",
+	"sessions.json":   "登录会话",
+	"files.json":      "上传和生成的文件信息",
+	"files/":          "文件内容",
+	"email_logs.json": "发送给你的邮件",
+	"audit_logs.json": "你的操作审计记录",
+}
+
+// PersonalDataService 生成用户本人数据的 ZIP 数据包
+type PersonalDataService struct {
+	userStore     *store.UserStore
+	profileStore  *store.ProfileStore
+	sessionStore  store.SessionStoreInterface
+	fileStore     *store.FileStore
+	emailStore    *store.EmailStore
+	jobStore      *store.JobStore
+	fileService   *FileService
+	exportStorage *ExportStorage
+	jobService    *JobService
+}
+
+func NewPersonalDataService(userStore *store.UserStore, profileStore *store.ProfileStore, sessionStore store.SessionStoreInterface, fileStore *store.FileStore, emailStore *store.EmailStore, jobStore *store.JobStore, fileService *FileService, exportStorage *ExportStorage, jobService *JobService) *PersonalDataService {
+	return &PersonalDataService{
+		userStore:     userStore,
+		profileStore:  profileStore,
+		sessionStore:  sessionStore,
+		fileStore:     fileStore,
+		emailStore:    emailStore,
+		jobStore:      jobStore,
+		fileService:   fileService,
+		exportStorage: exportStorage,
+		jobService:    jobService,
+	}
+}
+
+// SubmitExport 提交导出本人数据的任务。用户已有未完成的导出任务时返回该任务，created 为 false
+func (s *PersonalDataService) SubmitExport(userID uint) (job *models.Job, created bool, err error) {
+	if s.jobService == nil {
+		return nil, false, ErrQueueUnavailable
+	}
+
+	job, err = s.jobStore.GetActiveUserJob(userID, JobTypePersonalDataExport)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get job: %w", err)
+	}
+	if job != nil {
+		return job, false, nil
+	}
+
+	job, err = s.jobService.SubmitJob(&SubmitJobRequest{
+		UserID:      userID,
+		Title:       "导出个人数据",
+		Description: "生成包含账号、资料、会话、文件、邮件和审计记录的数据包",
+		JobType:     JobTypePersonalDataExport,
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return job, true, nil
+}
+
+// GetExport 获取用户本人的数据导出任务，任务完成后同时返回导出结果
+func (s *PersonalDataService) GetExport(userID, jobID uint) (*models.Job, *PersonalDataResult, error) {
+	job, err := s.jobStore.GetJobByID(jobID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get job: %w", err)
+	}
+	if job == nil || job.JobType != JobTypePersonalDataExport || job.UserID != userID {
+		return nil, nil, ErrJobNotFound
+	}
+	if job.Status != models.JobStatusCompleted {
+		return job, nil, nil
+	}
+
+	var result PersonalDataResult
+	if err := json.Unmarshal([]byte(job.Result), &result); err != nil {
+		return job, nil, nil
+	}
+	return job, &result, nil
+}
+
+// DownloadLink 生成数据包的限时下载链接，返回链接和链接的失效时间。
+// 数据包已过期或已被清理时返回 ErrExportExpired 或 ErrExportNotFound
+func (s *PersonalDataService) DownloadLink(ctx context.Context, userID uint, result *PersonalDataResult) (string, time.Time, error) {
+	file, err := s.exportStorage.Get(result.ExportID)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if file.UserID != userID {
+		return "", time.Time{}, ErrExportNotFound
+	}
+	return s.exportStorage.DownloadURL(ctx, file, personalDataLinkExpiry)
+}
+
+// SaveBundle 将 WriteBundle 写好的数据包保存到对象存储，返回任务结果；size 未知时传 -1
+func (s *PersonalDataService) SaveBundle(ctx context.Context, job *models.Job, r io.Reader, size int64, manifest *PersonalDataManifest) (*PersonalDataResult, error) {
+	jobID := job.ID
+	file := &models.ExportFile{
+		UserID:      job.UserID,
+		JobID:       &jobID,
+		DataType:    "personal_data",
+		Format:      "zip",
+		FileName:    fmt.Sprintf("personal_data_%d_%s.zip", job.UserID, job.CreatedAt.Format("20060102_150405")),
+		ContentType: "application/zip",
+		RecordCount: 1,
+		ExpiresAt:   time.Now().Add(personalDataRetention),
+	}
+	if err := s.exportStorage.Save(ctx, file, r, size); err != nil {
+		return nil, err
+	}
+
+	return &PersonalDataResult{
+		ExportID:     file.ID,
+		FileName:     file.FileName,
+		FileSize:     file.FileSize,
+		ExpiresAt:    file.ExpiresAt,
+		Counts:       manifest.Counts,
+		MissingFiles: len(manifest.MissingFiles),
+	}, nil
+}
+
+// WriteBundle 将用户的数据写为 ZIP 数据包。无法从存储读取的文件记录在 manifest 中，不会导致导出失败；
+// progress 可以为 nil，按已写入的部分和文件报告进度
+func (s *PersonalDataService) WriteBundle(ctx context.Context, w io.Writer, userID uint, progress ExportProgressFunc) (*PersonalDataManifest, error) {
+	user, err := s.userStore.GetUserByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+
+	manifest := &PersonalDataManifest{
+		UserID:       userID,
+		GeneratedAt:  time.Now(),
+		Contents:     personalDataContents,
+		Counts:       make(map[string]int),
+		MissingFiles: []PersonalDataMissingFile{},
+	}
+	zw := zip.NewWriter(w)
+	filter := store.ExportFilter{UserID: &userID}
+
+	// 依次写入的记录部分，文件内容在记录之后写入
+	blobs, err := s.bundleFiles(filter)
+	if err != nil {
+		return nil, err
+	}
+	sections := []struct {
+		name  string
+		write func(io.Writer) (int, error)
+	}{
+		{"account.json", func(w io.Writer) (int, error) { return s.writeAccount(w, user) }},
+		{"profile.json", func(w io.Writer) (int, error) { return s.writeProfile(w, userID) }},
+		{"sessions.json", func(w io.Writer) (int, error) { return s.writeSessions(w, userID) }},
+		{"files.json", func(w io.Writer) (int, error) { return writeJSONRecords(w, blobs.cursor, blobs.entry) }},
+		{"email_logs.json", func(w io.Writer) (int, error) {
+			return writeJSONRecords(w, s.emailStore.ExportEmailLogs(filter), nil)
+		}},
+		{"audit_logs.json", func(w io.Writer) (int, error) {
+			return writeJSONRecords(w, s.userStore.ExportAuditLogs(filter), nil)
+		}},
+	}
+
+	total := len(sections) + len(blobs.files)
+	done := 0
+	step := func() error {
+		done++
+		if progress == nil {
+			return nil
+		}
+		return progress(done, total)
+	}
+
+	for _, section := range sections {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		entry, err := zw.Create(section.name)
+		if err != nil {
+			return nil, err
+		}
+		count, err := section.write(entry)
+		if err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", section.name, err)
+		}
+		manifest.Counts[strings.TrimSuffix(section.name, ".json")] = count
+		if err := step(); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, file := range blobs.files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := s.writeFileContent(zw, file, blobs.paths[file.ID]); err != nil {
+			manifest.MissingFiles = append(manifest.MissingFiles, PersonalDataMissingFile{
+				FileID: file.ID,
+				Name:   file.OriginalName,
+				Error:  err.Error(),
+			})
+		}
+		if err := step(); err != nil {
+			return nil, err
+		}
+	}
+
+	entry, err := zw.Create("manifest.json")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeIndentedJSON(entry, manifest); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// personalDataBlobs 要写入数据包的文件
+type personalDataBlobs struct {
+	cursor store.ExportCursor[models.File]
+	files  []models.File   // 需要写入内容的文件
+	paths  map[uint]string // 文件ID到数据包中路径的映射
+}
+
+// bundleFiles 确定要写入内容的文件及其在数据包中的路径
+func (s *PersonalDataService) bundleFiles(filter store.ExportFilter) (*personalDataBlobs, error) {
+	blobs := &personalDataBlobs{
+		cursor: s.fileStore.ExportFiles(filter),
+		paths:  make(map[uint]string),
+	}
+	err := blobs.cursor.Each(exportBatchSize, func(batch []models.File) error {
+		for _, file := range batch {
+			blobs.files = append(blobs.files, file)
+			blobs.paths[file.ID] = personalDataFilePath(&file)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+	return blobs, nil
+}
+
+// entry 为文件信息附上文件在数据包中的路径
+func (b *personalDataBlobs) entry(file *models.File) interface{} {
+	return personalDataFile{File: *file, Path: b.paths[file.ID]}
+}
+
+// personalDataFilePath 文件在数据包中的路径，以文件ID为前缀避免重名
+func personalDataFilePath(file *models.File) string {
+	name := path.Base(strings.ReplaceAll(file.OriginalName, "\\", "/"))
+	if name == "." || name == "/" || name == ".." {
+		name = file.FileName
+	}
+	return fmt.Sprintf("files/%d_%s", file.ID, name)
+}
+
+// writeFileContent 从存储读取文件内容写入数据包
+func (s *PersonalDataService) writeFileContent(zw *zip.Writer, file models.File, name string) error {
+	obj, err := s.fileService.GetFileObject(&file)
+	if err != nil {
+		return err
+	}
+	defer obj.Close()
+
+	// 先确认对象存在，避免在数据包中留下不完整的文件
+	info, err := obj.Stat()
+	if err != nil {
+		return err
+	}
+	entry, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: info.LastModified,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(entry, obj)
+	return err
+}
+
+func (s *PersonalDataService) writeAccount(w io.Writer, user *models.User) (int, error) {
+	roles, err := s.userStore.GetUserRoles(user.ID)
+	if err != nil {
+		return 0, err
+	}
+	if roles == nil {
+		roles = []string{}
+	}
+	return 1, writeIndentedJSON(w, personalDataAccount{
+		ID:        user.ID,
+		Username:  user.Username,
+		Email:     user.Email,
+		Status:    user.Status,
+		Roles:     roles,
+		CreatedAt: user.CreatedAt,
+		UpdatedAt: user.UpdatedAt,
+	})
+}
+
+func (s *PersonalDataService) writeProfile(w io.Writer, userID uint) (int, error) {
+	profile, err := s.profileStore.GetProfileByUserID(userID)
+	if err != nil {
+		return 0, err
+	}
+	if profile == nil {
+		return 0, writeIndentedJSON(w, nil)
+	}
+	return 1, writeIndentedJSON(w, profile)
+}
+
+func (s *PersonalDataService) writeSessions(w io.Writer, userID uint) (int, error) {
+	sessions, err := s.sessionStore.ListUserSessions(userID)
+	if err != nil {
+		return 0, err
+	}
+	items := make([]personalDataSession, 0, len(sessions))
+	for _, session := range sessions {
+		items = append(items, personalDataSession{
+			ID:        session.ID,
+			CreatedAt: session.CreatedAt,
+			LastUsed:  session.UpdatedAt,
+			ExpiresAt: session.ExpiresAt,
+			IsRevoked: session.IsRevoked,
+			IPAddress: session.IPAddress,
+			UserAgent: session.UserAgent,
+		})
+	}
+	return len(items), writeIndentedJSON(w, items)
+}
+
+// writeJSONRecords 将游标读取的记录逐批写为 JSON 数组，entry 不为 nil 时用于转换每条记录
+func writeJSONRecords[T any](w io.Writer, cursor store.ExportCursor[T], entry func(*T) interface{}) (int, error) {
+	sink, err := newExportSink(w, "json", exportLayout{FullRecords: true})
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	err = cursor.Each(exportBatchSize, func(batch []T) error {
+		for i := range batch {
+			var record interface{} = &batch[i]
+			if entry != nil {
+				record = entry(&batch[i])
+			}
+			if err := sink.Write(nil, record); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+	return count, sink.Close()
+}
+
+func writeIndentedJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
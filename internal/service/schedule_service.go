@@ -0,0 +1,269 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"go-vibe-friend/internal/models"
+	"go-vibe-friend/internal/store"
+	"go-vibe-friend/internal/utils"
+)
+
+var (
+	// ErrScheduleNotFound 周期任务不存在
+	ErrScheduleNotFound = errors.New("schedule not found")
+	// ErrScheduleExists 同名周期任务已存在
+	ErrScheduleExists = errors.New("schedule with this name already exists")
+	// ErrInvalidSchedule cron 表达式无效或永远不会触发
+	ErrInvalidSchedule = errors.New("invalid schedule")
+)
+
+type ScheduleService struct {
+	recurringStore *store.RecurringJobStore
+	jobService     *JobService
+}
+
+func NewScheduleService(recurringStore *store.RecurringJobStore, jobService *JobService) *ScheduleService {
+	return &ScheduleService{
+		recurringStore: recurringStore,
+		jobService:     jobService,
+	}
+}
+
+// ScheduleRequest 创建或更新周期任务的请求
+type ScheduleRequest struct {
+	Name        string                 `json:"name" binding:"required"`
+	Description string                 `json:"description"`
+	CronExpr    string                 `json:"cron_expr" binding:"required"`
+	JobType     string                 `json:"job_type" binding:"required"`
+	Queue       string                 `json:"queue"`
+	Priority    int                    `json:"priority" binding:"min=0,max=10"`
+	MaxRetries  int                    `json:"max_retries" binding:"min=0"`
+	Payload     map[string]interface{} `json:"payload"`
+	Enabled     *bool                  `json:"enabled"`
+}
+
+// ListSchedules 获取所有周期任务
+func (s *ScheduleService) ListSchedules() ([]models.RecurringJob, error) {
+	return s.recurringStore.ListRecurringJobs()
+}
+
+// GetSchedule 获取周期任务
+func (s *ScheduleService) GetSchedule(id uint) (*models.RecurringJob, error) {
+	schedule, err := s.recurringStore.GetRecurringJobByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if schedule == nil {
+		return nil, ErrScheduleNotFound
+	}
+	return schedule, nil
+}
+
+// CreateSchedule 创建周期任务
+func (s *ScheduleService) CreateSchedule(req *ScheduleRequest, createdBy uint) (*models.RecurringJob, error) {
+	existing, err := s.recurringStore.GetRecurringJobByName(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrScheduleExists
+	}
+
+	schedule := &models.RecurringJob{CreatedBy: createdBy}
+	if err := s.applyRequest(schedule, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.recurringStore.CreateRecurringJob(schedule); err != nil {
+		return nil, fmt.Errorf("failed to create schedule: %w", err)
+	}
+	return schedule, nil
+}
+
+// UpdateSchedule 更新周期任务，修改后按新的表达式重新计算下次执行时间
+func (s *ScheduleService) UpdateSchedule(id uint, req *ScheduleRequest) (*models.RecurringJob, error) {
+	schedule, err := s.GetSchedule(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != schedule.Name {
+		existing, err := s.recurringStore.GetRecurringJobByName(req.Name)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			return nil, ErrScheduleExists
+		}
+	}
+
+	if err := s.applyRequest(schedule, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.recurringStore.UpdateRecurringJob(schedule); err != nil {
+		return nil, fmt.Errorf("failed to update schedule: %w", err)
+	}
+	return schedule, nil
+}
+
+// DeleteSchedule 删除周期任务
+func (s *ScheduleService) DeleteSchedule(id uint) error {
+	if _, err := s.GetSchedule(id); err != nil {
+		return err
+	}
+	return s.recurringStore.DeleteRecurringJob(id)
+}
+
+// RunScheduleNow 立即提交一次任务，不影响正常的执行计划
+func (s *ScheduleService) RunScheduleNow(id uint) (*models.Job, error) {
+	schedule, err := s.GetSchedule(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.submit(schedule)
+}
+
+// EnsureSchedules 创建尚不存在的周期任务（按名称判断），已存在的保持管理员的修改
+func (s *ScheduleService) EnsureSchedules(defaults []ScheduleRequest) error {
+	for i := range defaults {
+		req := &defaults[i]
+		existing, err := s.recurringStore.GetRecurringJobByName(req.Name)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			continue
+		}
+		if _, err := s.CreateSchedule(req, 0); err != nil && !errors.Is(err, ErrScheduleExists) {
+			return fmt.Errorf("failed to create schedule %q: %w", req.Name, err)
+		}
+	}
+	return nil
+}
+
+// RunDueSchedules 提交所有到期的周期任务，返回提交数量。
+// 每个任务先通过 run_count 条件更新抢占本次执行，多个实例同时调用也只会提交一次。
+func (s *ScheduleService) RunDueSchedules(now time.Time) (int, error) {
+	schedules, err := s.recurringStore.GetDueRecurringJobs(now)
+	if err != nil {
+		return 0, err
+	}
+
+	submitted := 0
+	for i := range schedules {
+		schedule := &schedules[i]
+
+		cron, err := utils.ParseCron(schedule.CronExpr)
+		if err != nil {
+			log.Printf("Scheduler skipped %q: %v", schedule.Name, err)
+			continue
+		}
+		// 错过的执行（例如停机期间）只补一次，下次时间从现在算起
+		next := cron.Next(now)
+		if next.IsZero() {
+			continue
+		}
+
+		claimed, err := s.recurringStore.ClaimRun(schedule, now, next)
+		if err != nil {
+			return submitted, err
+		}
+		if !claimed {
+			continue
+		}
+
+		if _, err := s.submit(schedule); err != nil {
+			log.Printf("Scheduler failed to submit %q: %v", schedule.Name, err)
+			continue
+		}
+		submitted++
+	}
+
+	return submitted, nil
+}
+
+// submit 按周期任务定义提交一次任务并记录结果
+func (s *ScheduleService) submit(schedule *models.RecurringJob) (*models.Job, error) {
+	var payload map[string]interface{}
+	if schedule.Payload != "" {
+		if err := json.Unmarshal([]byte(schedule.Payload), &payload); err != nil {
+			return nil, fmt.Errorf("invalid schedule payload: %w", err)
+		}
+	}
+
+	job, err := s.jobService.SubmitJob(&SubmitJobRequest{
+		UserID:      schedule.CreatedBy,
+		Title:       schedule.Name,
+		Description: fmt.Sprintf("周期任务 %s (%s)", schedule.Name, schedule.CronExpr),
+		JobType:     schedule.JobType,
+		Queue:       schedule.Queue,
+		Priority:    schedule.Priority,
+		MaxRetries:  schedule.MaxRetries,
+		Payload:     payload,
+	})
+
+	var jobID *uint
+	errMsg := ""
+	if err != nil {
+		errMsg = err.Error()
+	} else {
+		jobID = &job.ID
+	}
+	if recordErr := s.recurringStore.RecordRunResult(schedule.ID, jobID, errMsg); recordErr != nil {
+		log.Printf("Scheduler failed to record run of %q: %v", schedule.Name, recordErr)
+	}
+
+	return job, err
+}
+
+// applyRequest 校验请求并写入周期任务
+func (s *ScheduleService) applyRequest(schedule *models.RecurringJob, req *ScheduleRequest) error {
+	cron, err := utils.ParseCron(req.CronExpr)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSchedule, err)
+	}
+
+	payload := ""
+	if req.Payload != nil {
+		data, err := json.Marshal(req.Payload)
+		if err != nil {
+			return fmt.Errorf("failed to marshal schedule payload: %w", err)
+		}
+		payload = string(data)
+	}
+
+	schedule.Name = req.Name
+	schedule.Description = req.Description
+	schedule.JobType = req.JobType
+	schedule.Queue = req.Queue
+	if schedule.Queue == "" {
+		schedule.Queue = DefaultQueue
+	}
+	schedule.Priority = req.Priority
+	schedule.MaxRetries = req.MaxRetries
+	schedule.Payload = payload
+
+	enabled := schedule.Enabled || schedule.ID == 0
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+	// 重新启用时从现在开始计算，避免补跑停用期间错过的执行
+	reenabled := enabled && !schedule.Enabled
+	schedule.Enabled = enabled
+
+	if schedule.CronExpr != req.CronExpr || schedule.NextRunAt == nil || reenabled {
+		schedule.CronExpr = req.CronExpr
+		next := cron.Next(time.Now())
+		if next.IsZero() {
+			return fmt.Errorf("%w: cron expression %q never fires", ErrInvalidSchedule, req.CronExpr)
+		}
+		schedule.NextRunAt = &next
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"time"
+
+	"go-vibe-friend/internal/config"
+	"go-vibe-friend/internal/store"
+	"go-vibe-friend/internal/utils"
+)
+
+// TokenRevocationService 撤销尚未过期的访问令牌：单个令牌按 jti 加入黑名单，
+// 撤销用户全部令牌时记录水位线，水位线之前签发的令牌全部失效。
+// Redis 可用时存入 Redis，否则存入数据库
+type TokenRevocationService struct {
+	storeManager *store.Store
+	accessTTL    time.Duration
+}
+
+func NewTokenRevocationService(storeManager *store.Store, cfg config.JWTConfig) *TokenRevocationService {
+	accessTTL := cfg.AccessTTL
+	if accessTTL <= 0 {
+		accessTTL = 15 * time.Minute
+	}
+	return &TokenRevocationService{
+		storeManager: storeManager,
+		accessTTL:    accessTTL,
+	}
+}
+
+// RevokeToken 将访问令牌加入黑名单直到其过期
+func (s *TokenRevocationService) RevokeToken(claims *utils.Claims) error {
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		// 没有 jti 的旧令牌只能通过水位线撤销
+		return s.RevokeUserTokens(claims.UserID)
+	}
+	return s.storeManager.GetTokenRevocationStore().RevokeToken(claims.ID, claims.UserID, claims.ExpiresAt.Time)
+}
+
+// RevokeUserTokens 撤销用户此刻之前签发的所有访问令牌，
+// 水位线保留一个访问令牌有效期，之后这些令牌已自然过期
+func (s *TokenRevocationService) RevokeUserTokens(userID uint) error {
+	return s.storeManager.GetTokenRevocationStore().RevokeUserTokens(userID, time.Now(), s.accessTTL)
+}
+
+// IsRevoked 检查访问令牌是否已被撤销
+func (s *TokenRevocationService) IsRevoked(claims *utils.Claims) (bool, error) {
+	var issuedAt time.Time
+	if claims.IssuedAt != nil {
+		issuedAt = claims.IssuedAt.Time
+	}
+	return s.storeManager.GetTokenRevocationStore().IsRevoked(claims.ID, claims.UserID, issuedAt)
+}
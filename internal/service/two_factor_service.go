@@ -0,0 +1,464 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go-vibe-friend/internal/config"
+	"go-vibe-friend/internal/models"
+	"go-vibe-friend/internal/store"
+	"go-vibe-friend/internal/utils"
+)
+
+const (
+	// twoFactorChallengeTTL 登录质询的有效期
+	twoFactorChallengeTTL = 5 * time.Minute
+	// twoFactorMaxAttempts 每个登录质询允许输错验证码的次数，用尽后需要重新输入密码
+	twoFactorMaxAttempts = 5
+	// recoveryCodeCount 每次生成的恢复码数量
+	recoveryCodeCount = 10
+	// defaultTOTPIssuer 认证器应用中显示的默认发行方
+	defaultTOTPIssuer = "go-vibe-friend"
+)
+
+var (
+	// ErrTwoFactorAlreadyEnabled 两步验证已启用
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	// ErrTwoFactorNotEnrolled 尚未生成两步验证密钥
+	ErrTwoFactorNotEnrolled = errors.New("two-factor authentication is not enrolled")
+	// ErrTwoFactorNotEnabled 两步验证未启用
+	ErrTwoFactorNotEnabled = errors.New("two-factor authentication is not enabled")
+	// ErrTwoFactorRequired 用户的角色要求启用两步验证，不能关闭
+	ErrTwoFactorRequired = errors.New("two-factor authentication is required for the user's role")
+	// ErrInvalidTwoFactorCode 验证码或恢复码错误，或验证码已被使用
+	ErrInvalidTwoFactorCode = errors.New("invalid two-factor code")
+	// ErrInvalidChallenge 登录质询不存在、已过期或已用完尝试次数
+	ErrInvalidChallenge = errors.New("invalid or expired two-factor challenge")
+	// ErrRoleNotFound 角色不存在
+	ErrRoleNotFound = errors.New("role not found")
+)
+
+// TwoFactorService 管理基于 TOTP 的两步验证：密钥登记与确认、一次性恢复码、
+// 两步登录的质询，以及按角色强制启用两步验证的策略
+type TwoFactorService struct {
+	twoFactorStore  *store.TwoFactorStore
+	userStore       *store.UserStore
+	permissionStore *store.PermissionStore
+	issuer          string
+}
+
+func NewTwoFactorService(twoFactorStore *store.TwoFactorStore, userStore *store.UserStore, permissionStore *store.PermissionStore, cfg *config.Config) *TwoFactorService {
+	issuer := cfg.JWT.Issuer
+	if issuer == "" {
+		issuer = defaultTOTPIssuer
+	}
+	return &TwoFactorService{
+		twoFactorStore:  twoFactorStore,
+		userStore:       userStore,
+		permissionStore: permissionStore,
+		issuer:          issuer,
+	}
+}
+
+// TwoFactorStatus 用户的两步验证状态
+type TwoFactorStatus struct {
+	Enabled                bool       `json:"enabled"`
+	Pending                bool       `json:"pending"`  // 已生成密钥但尚未确认
+	Required               bool       `json:"required"` // 用户的角色要求启用
+	RecoveryCodesRemaining int64      `json:"recovery_codes_remaining"`
+	ConfirmedAt            *time.Time `json:"confirmed_at,omitempty"`
+}
+
+// TwoFactorEnrollment 新生成的密钥，用户将其添加到认证器应用
+type TwoFactorEnrollment struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+// LoginChallenge 密码验证通过后返回的质询，凭质询令牌完成第二步验证
+type LoginChallenge struct {
+	Token         string    `json:"challenge_token"`
+	SetupRequired bool      `json:"setup_required"` // 需要先登记两步验证
+	ExpiresAt     time.Time `json:"expires_at"`
+}
+
+// Status 获取用户的两步验证状态
+func (s *TwoFactorService) Status(userID uint) (*TwoFactorStatus, error) {
+	tf, err := s.twoFactorStore.GetTwoFactor(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get two-factor settings: %w", err)
+	}
+	required, err := s.IsRequired(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &TwoFactorStatus{Required: required}
+	if tf == nil {
+		return status, nil
+	}
+	status.Enabled = tf.Enabled
+	status.Pending = !tf.Enabled
+	status.ConfirmedAt = tf.ConfirmedAt
+	if tf.Enabled {
+		if status.RecoveryCodesRemaining, err = s.twoFactorStore.CountRecoveryCodes(userID); err != nil {
+			return nil, fmt.Errorf("failed to count recovery codes: %w", err)
+		}
+	}
+	return status, nil
+}
+
+// Enroll 生成新的 TOTP 密钥，确认首个验证码前两步验证不会生效。
+// 重复调用会替换尚未确认的密钥
+func (s *TwoFactorService) Enroll(userID uint) (*TwoFactorEnrollment, error) {
+	user, err := s.userStore.GetUserByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	tf, err := s.twoFactorStore.GetTwoFactor(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get two-factor settings: %w", err)
+	}
+	if tf != nil && tf.Enabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	if tf == nil {
+		tf = &models.TwoFactorAuth{UserID: userID}
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate secret: %w", err)
+	}
+	tf.Secret = secret
+	tf.LastUsedStep = 0
+	if err := s.twoFactorStore.SaveTwoFactor(tf); err != nil {
+		return nil, fmt.Errorf("failed to save two-factor settings: %w", err)
+	}
+
+	return &TwoFactorEnrollment{
+		Secret:     secret,
+		OTPAuthURI: utils.TOTPURI(s.issuer, user.Email, secret),
+	}, nil
+}
+
+// Confirm 使用认证器应用生成的首个验证码启用两步验证，返回一次性恢复码
+func (s *TwoFactorService) Confirm(userID uint, code string) ([]string, error) {
+	tf, err := s.twoFactorStore.GetTwoFactor(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get two-factor settings: %w", err)
+	}
+	if tf == nil {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+	if tf.Enabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	if err := s.verifyTOTP(tf, code); err != nil {
+		return nil, err
+	}
+	return s.enable(tf)
+}
+
+// RegenerateRecoveryCodes 验证当前验证码后生成新的恢复码，旧恢复码全部失效
+func (s *TwoFactorService) RegenerateRecoveryCodes(userID uint, code string) ([]string, error) {
+	tf, err := s.enabledTwoFactor(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verifyTOTP(tf, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.twoFactorStore.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, fmt.Errorf("failed to save recovery codes: %w", err)
+	}
+	return codes, nil
+}
+
+// Disable 验证验证码或恢复码后关闭两步验证，角色要求两步验证时不能关闭
+func (s *TwoFactorService) Disable(userID uint, code string) error {
+	tf, err := s.enabledTwoFactor(userID)
+	if err != nil {
+		return err
+	}
+	required, err := s.IsRequired(userID)
+	if err != nil {
+		return err
+	}
+	if required {
+		return ErrTwoFactorRequired
+	}
+	if err := s.verifySecondFactor(tf, code); err != nil {
+		return err
+	}
+	return s.twoFactorStore.DeleteTwoFactor(userID)
+}
+
+// Reset 由管理员清除用户的两步验证，用于用户丢失认证器和恢复码的情况。
+// 角色要求两步验证的用户下次登录时需要重新登记
+func (s *TwoFactorService) Reset(userID uint) error {
+	return s.twoFactorStore.DeleteTwoFactor(userID)
+}
+
+// IsRequired 检查用户是否拥有要求两步验证的角色
+func (s *TwoFactorService) IsRequired(userID uint) (bool, error) {
+	roles, err := s.permissionStore.GetUserRolesByID(userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get user roles: %w", err)
+	}
+	for _, role := range roles {
+		if role.RequireTwoFactor {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// SetRoleRequired 设置角色是否要求两步验证
+func (s *TwoFactorService) SetRoleRequired(roleID uint, required bool) (*models.Role, error) {
+	role, err := s.permissionStore.GetRoleByID(roleID)
+	if err != nil {
+		return nil, fmt.Errorf("获取角色失败: %v", err)
+	}
+	if role == nil {
+		return nil, ErrRoleNotFound
+	}
+
+	role.RequireTwoFactor = required
+	if err := s.permissionStore.UpdateRole(role); err != nil {
+		return nil, fmt.Errorf("更新角色失败: %v", err)
+	}
+	return role, nil
+}
+
+// BeginLogin 在密码验证通过后调用。用户启用了两步验证或角色要求两步验证时返回登录质询，
+// 否则返回 nil，可以直接签发令牌
+func (s *TwoFactorService) BeginLogin(user *models.User) (*LoginChallenge, error) {
+	tf, err := s.twoFactorStore.GetTwoFactor(user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get two-factor settings: %w", err)
+	}
+	enabled := tf != nil && tf.Enabled
+	if !enabled {
+		required, err := s.IsRequired(user.ID)
+		if err != nil {
+			return nil, err
+		}
+		if !required {
+			return nil, nil
+		}
+	}
+
+	token, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate challenge token: %w", err)
+	}
+	challenge := &models.TwoFactorChallenge{
+		UserID:        user.ID,
+		TokenHash:     hashSecret(token),
+		SetupRequired: !enabled,
+		ExpiresAt:     time.Now().Add(twoFactorChallengeTTL),
+	}
+	if err := s.twoFactorStore.CreateChallenge(challenge); err != nil {
+		return nil, fmt.Errorf("failed to create challenge: %w", err)
+	}
+
+	return &LoginChallenge{
+		Token:         token,
+		SetupRequired: challenge.SetupRequired,
+		ExpiresAt:     challenge.ExpiresAt,
+	}, nil
+}
+
+// EnrollWithChallenge 为角色要求两步验证但尚未登记的用户生成密钥，
+// 用户此时还没有访问令牌，凭登录质询调用
+func (s *TwoFactorService) EnrollWithChallenge(token string) (*TwoFactorEnrollment, error) {
+	challenge, err := s.getChallenge(token)
+	if err != nil {
+		return nil, err
+	}
+	if !challenge.SetupRequired {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	return s.Enroll(challenge.UserID)
+}
+
+// CompleteLogin 校验登录质询和验证码，返回登录的用户。
+// 已启用两步验证的用户可以使用验证码或恢复码；需要登记的用户使用新密钥的首个验证码，
+// 此时两步验证随之启用并返回恢复码
+func (s *TwoFactorService) CompleteLogin(token, code string) (*models.User, []string, error) {
+	challenge, err := s.getChallenge(token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tf, err := s.twoFactorStore.GetTwoFactor(challenge.UserID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get two-factor settings: %w", err)
+	}
+
+	var recoveryCodes []string
+	switch {
+	case tf != nil && tf.Enabled:
+		err = s.verifySecondFactor(tf, code)
+	case tf != nil && challenge.SetupRequired:
+		if err = s.verifyTOTP(tf, code); err == nil {
+			recoveryCodes, err = s.enable(tf)
+		}
+	default:
+		return nil, nil, ErrTwoFactorNotEnrolled
+	}
+	if errors.Is(err, ErrInvalidTwoFactorCode) {
+		if _, attemptErr := s.twoFactorStore.RecordChallengeAttempt(challenge.ID, twoFactorMaxAttempts); attemptErr != nil {
+			return nil, nil, fmt.Errorf("failed to record attempt: %w", attemptErr)
+		}
+		return nil, nil, err
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 删除质询，同一个质询只能完成一次登录
+	deleted, err := s.twoFactorStore.DeleteChallenge(challenge.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to delete challenge: %w", err)
+	}
+	if !deleted {
+		return nil, nil, ErrInvalidChallenge
+	}
+
+	user, err := s.userStore.GetUserByID(challenge.UserID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("database error: %w", err)
+	}
+	if user == nil {
+		return nil, nil, ErrInvalidChallenge
+	}
+	return user, recoveryCodes, nil
+}
+
+// getChallenge 获取未过期的登录质询
+func (s *TwoFactorService) getChallenge(token string) (*models.TwoFactorChallenge, error) {
+	challenge, err := s.twoFactorStore.GetChallengeByHash(hashSecret(token))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get challenge: %w", err)
+	}
+	if challenge == nil || time.Now().After(challenge.ExpiresAt) {
+		return nil, ErrInvalidChallenge
+	}
+	return challenge, nil
+}
+
+// enabledTwoFactor 获取已启用的两步验证设置
+func (s *TwoFactorService) enabledTwoFactor(userID uint) (*models.TwoFactorAuth, error) {
+	tf, err := s.twoFactorStore.GetTwoFactor(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get two-factor settings: %w", err)
+	}
+	if tf == nil || !tf.Enabled {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	return tf, nil
+}
+
+// enable 启用两步验证并生成恢复码
+func (s *TwoFactorService) enable(tf *models.TwoFactorAuth) ([]string, error) {
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.twoFactorStore.EnableTwoFactor(tf, hashes); err != nil {
+		return nil, fmt.Errorf("failed to enable two-factor authentication: %w", err)
+	}
+	return codes, nil
+}
+
+// verifyTOTP 校验 TOTP 验证码，每个验证码只能使用一次
+func (s *TwoFactorService) verifyTOTP(tf *models.TwoFactorAuth, code string) error {
+	step, ok := utils.ValidateTOTP(tf.Secret, strings.TrimSpace(code), time.Now())
+	if !ok {
+		return ErrInvalidTwoFactorCode
+	}
+	used, err := s.twoFactorStore.UseStep(tf.UserID, step)
+	if err != nil {
+		return fmt.Errorf("failed to record code use: %w", err)
+	}
+	if !used {
+		return ErrInvalidTwoFactorCode
+	}
+	// keep tf in sync so a later Save does not roll the step back
+	tf.LastUsedStep = step
+	return nil
+}
+
+// verifySecondFactor 校验 TOTP 验证码或一次性恢复码
+func (s *TwoFactorService) verifySecondFactor(tf *models.TwoFactorAuth, code string) error {
+	code = strings.TrimSpace(code)
+	if isTOTPCode(code) {
+		return s.verifyTOTP(tf, code)
+	}
+
+	used, err := s.twoFactorStore.UseRecoveryCode(tf.UserID, hashSecret(normalizeRecoveryCode(code)))
+	if err != nil {
+		return fmt.Errorf("failed to use recovery code: %w", err)
+	}
+	if !used {
+		return ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+// isTOTPCode 判断输入是否为 6 位数字验证码，其他输入按恢复码处理
+func isTOTPCode(code string) bool {
+	if len(code) != 6 {
+		return false
+	}
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// newRecoveryCodes 生成恢复码及其哈希，恢复码只在生成时返回给用户一次
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		raw := make([]byte, 5)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		code := hex.EncodeToString(raw)
+		codes[i] = code[:5] + "-" + code[5:]
+		hashes[i] = hashSecret(code)
+	}
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode 去掉恢复码中的分隔符并统一为小写
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// hashSecret 返回令牌或恢复码的 SHA-256 哈希，数据库中只保存哈希
+func hashSecret(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
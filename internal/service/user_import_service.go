@@ -0,0 +1,627 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/mail"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/minio/minio-go/v7"
+
+	"go-vibe-friend/internal/config"
+	"go-vibe-friend/internal/models"
+	"go-vibe-friend/internal/store"
+)
+
+// JobTypeUserImport 后台用户导入任务的类型
+const JobTypeUserImport = "user_import"
+
+// 不超过该行数的导入直接处理，更大的文件作为后台任务处理；
+// 发送邀请邮件较慢，因此需要发送邀请时阈值更低
+const (
+	userImportSyncLimit       = 500
+	userImportSyncInviteLimit = 50
+)
+
+// importObjectPrefix 后台导入文件在对象存储中的目录
+const importObjectPrefix = "imports/"
+
+// defaultImportRole 未指定角色时分配给导入用户的角色，与注册时相同
+const defaultImportRole = "user"
+
+var (
+	// ErrInvalidImportFormat 不支持的导入格式
+	ErrInvalidImportFormat = errors.New("invalid import format")
+	// ErrInvalidImportFile 导入文件无法解析
+	ErrInvalidImportFile = errors.New("invalid import file")
+)
+
+// userStatuses 用户可用的状态
+var userStatuses = []string{"active", "inactive", "banned"}
+
+// UserImportOptions 导入选项
+type UserImportOptions struct {
+	Format          string `json:"format"`           // csv, json, jsonl
+	DryRun          bool   `json:"dry_run"`          // 只校验不写入
+	SendInvitations bool   `json:"send_invitations"` // 导入后发送设置密码的邀请邮件
+}
+
+// UserImportRow 导入文件中的一行用户数据
+type UserImportRow struct {
+	Row      int      `json:"row"` // CSV 中的行号（表头为第 1 行），JSON 中的记录序号（从 1 开始）
+	Username string   `json:"username"`
+	Email    string   `json:"email"`
+	Status   string   `json:"status"`
+	Roles    []string `json:"roles"`
+}
+
+// UserImportError 一行数据的校验错误
+type UserImportError struct {
+	Row     int    `json:"row"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// UserImportReport 导入结果。存在无效行时不会导入任何用户
+type UserImportReport struct {
+	DryRun            bool              `json:"dry_run"`
+	Total             int               `json:"total"`
+	Valid             int               `json:"valid"`
+	Invalid           int               `json:"invalid"`
+	Imported          int               `json:"imported"`
+	InvitationsSent   int               `json:"invitations_sent"`
+	InvitationsFailed int               `json:"invitations_failed"`
+	Errors            []UserImportError `json:"errors"`
+}
+
+// ImportProgressFunc 校验每批记录后调用，报告已处理数量和总数；返回错误时中止导入
+type ImportProgressFunc func(done, total int) error
+
+type UserImportService struct {
+	userStore       *store.UserStore
+	permissionStore *store.PermissionStore
+	emailService    *EmailService
+	jobService      *JobService
+	minioClient     *minio.Client
+	bucket          string
+}
+
+func NewUserImportService(userStore *store.UserStore, permissionStore *store.PermissionStore, emailService *EmailService, jobService *JobService, minioClient *minio.Client, cfg *config.Config) *UserImportService {
+	return &UserImportService{
+		userStore:       userStore,
+		permissionStore: permissionStore,
+		emailService:    emailService,
+		jobService:      jobService,
+		minioClient:     minioClient,
+		bucket:          cfg.MinIO.BucketName,
+	}
+}
+
+// ImportUsers 解析导入文件。行数较少时直接校验并导入，返回导入结果；
+// 行数较多时上传文件并提交后台导入任务，返回任务记录
+func (s *UserImportService) ImportUsers(userID uint, opts *UserImportOptions, data []byte) (*UserImportReport, *models.Job, error) {
+	rows, err := ParseUserImport(opts.Format, bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	limit := userImportSyncLimit
+	if opts.SendInvitations && !opts.DryRun {
+		limit = userImportSyncInviteLimit
+	}
+	if len(rows) <= limit {
+		report, err := s.Run(opts, rows, nil)
+		return report, nil, err
+	}
+
+	job, err := s.submitImport(userID, opts, data, len(rows))
+	return nil, job, err
+}
+
+// submitImport 将导入文件上传到对象存储并提交后台导入任务，
+// 任何实例上的 worker 都可以读取该文件
+func (s *UserImportService) submitImport(userID uint, opts *UserImportOptions, data []byte, rowCount int) (*models.Job, error) {
+	if s.jobService == nil {
+		return nil, ErrQueueUnavailable
+	}
+
+	suffix, err := randomSuffix()
+	if err != nil {
+		return nil, err
+	}
+	objectKey := fmt.Sprintf("%s%s/%s_users.%s", importObjectPrefix, time.Now().Format("2006/01/02"), suffix, opts.Format)
+	_, err = s.minioClient.PutObject(context.Background(), s.bucket, objectKey, bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("上传导入文件到 MinIO 失败: %v", err)
+	}
+
+	title := fmt.Sprintf("导入用户 (%d 行)", rowCount)
+	if opts.DryRun {
+		title = fmt.Sprintf("校验用户导入 (%d 行)", rowCount)
+	}
+	job, err := s.jobService.SubmitJob(&SubmitJobRequest{
+		UserID:      userID,
+		Title:       title,
+		Description: "后台用户导入",
+		JobType:     JobTypeUserImport,
+		Payload: map[string]interface{}{
+			"format":           opts.Format,
+			"dry_run":          opts.DryRun,
+			"send_invitations": opts.SendInvitations,
+			"object_key":       objectKey,
+		},
+	})
+	if err != nil {
+		s.removeImportFile(objectKey)
+		return nil, err
+	}
+	return job, nil
+}
+
+// randomSuffix 随机后缀使同时上传的导入文件互不覆盖
+func randomSuffix() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// removeImportFile 删除对象存储中的导入文件，失败时只记录日志
+func (s *UserImportService) removeImportFile(objectKey string) {
+	if err := s.minioClient.RemoveObject(context.Background(), s.bucket, objectKey, minio.RemoveObjectOptions{}); err != nil {
+		log.Printf("Failed to remove import file %s: %v", objectKey, err)
+	}
+}
+
+// RunImportJob 执行后台导入任务，从对象存储流式读取导入文件，完成后删除该文件。
+// 失败时保留文件，任务重试时可以再次读取
+func (s *UserImportService) RunImportJob(ctx context.Context, payload map[string]interface{}, progress ImportProgressFunc) (*UserImportReport, error) {
+	opts := &UserImportOptions{}
+	opts.Format, _ = payload["format"].(string)
+	opts.DryRun, _ = payload["dry_run"].(bool)
+	opts.SendInvitations, _ = payload["send_invitations"].(bool)
+	objectKey, _ := payload["object_key"].(string)
+	if objectKey == "" {
+		return nil, fmt.Errorf("%w: missing object_key", ErrInvalidImportFile)
+	}
+
+	object, err := s.minioClient.GetObject(ctx, s.bucket, objectKey, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("读取导入文件失败: %v", err)
+	}
+	rows, err := ParseUserImport(opts.Format, object)
+	object.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := s.Run(opts, rows, progress)
+	if err != nil {
+		return nil, err
+	}
+	s.removeImportFile(objectKey)
+	return report, nil
+}
+
+// GetImportJob 获取导入任务，任务完成后同时返回导入结果
+func (s *UserImportService) GetImportJob(id uint) (*models.Job, *UserImportReport, error) {
+	if s.jobService == nil {
+		return nil, nil, ErrQueueUnavailable
+	}
+	job, err := s.jobService.GetJob(id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get job: %w", err)
+	}
+	if job == nil || job.JobType != JobTypeUserImport {
+		return nil, nil, ErrJobNotFound
+	}
+	if job.Status != models.JobStatusCompleted {
+		return job, nil, nil
+	}
+
+	var report UserImportReport
+	if err := json.Unmarshal([]byte(job.Result), &report); err != nil {
+		return job, nil, nil
+	}
+	return job, &report, nil
+}
+
+// Run 校验所有行，全部有效且不是试运行时在一个事务中创建用户，然后按需发送邀请邮件
+func (s *UserImportService) Run(opts *UserImportOptions, rows []UserImportRow, progress ImportProgressFunc) (*UserImportReport, error) {
+	report := &UserImportReport{
+		DryRun: opts.DryRun,
+		Total:  len(rows),
+		Errors: []UserImportError{},
+	}
+
+	users, roleIDs, err := s.validate(rows, report, progress)
+	if err != nil {
+		return nil, err
+	}
+	report.Valid = len(users)
+	report.Invalid = report.Total - report.Valid
+	if opts.DryRun || report.Invalid > 0 || len(users) == 0 {
+		return report, nil
+	}
+
+	if err := s.resolveDefaultRole(roleIDs); err != nil {
+		return nil, err
+	}
+	if err := s.userStore.CreateUsersWithRoles(users, roleIDs); err != nil {
+		return nil, fmt.Errorf("导入用户失败: %w", err)
+	}
+	report.Imported = len(users)
+
+	if opts.SendInvitations && s.emailService != nil {
+		for _, user := range users {
+			if err := s.emailService.SendInvitationEmail(user.ID, user.Email, user.Username); err != nil {
+				log.Printf("Failed to send invitation to imported user %d: %v", user.ID, err)
+				report.InvitationsFailed++
+				continue
+			}
+			report.InvitationsSent++
+		}
+	}
+	return report, nil
+}
+
+// resolveDefaultRole 将 validate 中默认角色的 0 占位替换为默认角色ID，默认角色不存在时创建
+func (s *UserImportService) resolveDefaultRole(roleIDs [][]uint) error {
+	var defaultID uint
+	for _, ids := range roleIDs {
+		for i, id := range ids {
+			if id != 0 {
+				continue
+			}
+			if defaultID == 0 {
+				role, err := s.permissionStore.GetRoleByName(defaultImportRole)
+				if err != nil {
+					return fmt.Errorf("failed to look up role %q: %w", defaultImportRole, err)
+				}
+				if role == nil {
+					role = &models.Role{
+						Name:        defaultImportRole,
+						Description: fmt.Sprintf("Default %s role", defaultImportRole),
+					}
+					if err := s.permissionStore.CreateRole(role); err != nil {
+						return fmt.Errorf("failed to create role %q: %w", defaultImportRole, err)
+					}
+				}
+				defaultID = role.ID
+			}
+			ids[i] = defaultID
+		}
+	}
+	return nil
+}
+
+// validate 校验每一行并返回可以创建的用户和各自的角色ID，错误记录在 report 中
+func (s *UserImportService) validate(rows []UserImportRow, report *UserImportReport, progress ImportProgressFunc) ([]models.User, [][]uint, error) {
+	emails := make([]string, 0, len(rows))
+	usernames := make([]string, 0, len(rows))
+	for _, row := range rows {
+		if row.Email != "" {
+			emails = append(emails, row.Email)
+		}
+		if row.Username != "" {
+			usernames = append(usernames, row.Username)
+		}
+	}
+	takenEmails, takenUsernames, err := s.userStore.GetTakenEmailsAndUsernames(emails, usernames)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to check existing users: %w", err)
+	}
+
+	roleIDs := make(map[string]uint)
+	seenEmails := make(map[string]int)
+	seenUsernames := make(map[string]int)
+	var users []models.User
+	var userRoles [][]uint
+
+	for i, row := range rows {
+		addError := func(field, message string) {
+			report.Errors = append(report.Errors, UserImportError{Row: row.Row, Field: field, Message: message})
+		}
+		errorCount := len(report.Errors)
+
+		email := strings.ToLower(row.Email)
+		switch {
+		case row.Email == "":
+			addError("email", "email is required")
+		case !validImportEmail(row.Email):
+			addError("email", "invalid email address")
+		case takenEmails[email]:
+			addError("email", "email already exists")
+		case seenEmails[email] > 0:
+			addError("email", fmt.Sprintf("duplicate email, first seen in row %d", seenEmails[email]))
+		default:
+			seenEmails[email] = row.Row
+		}
+
+		switch length := utf8.RuneCountInString(row.Username); {
+		case row.Username == "":
+			addError("username", "username is required")
+		case length < 3 || length > 20:
+			addError("username", "username must be 3 to 20 characters")
+		case takenUsernames[row.Username]:
+			addError("username", "username already exists")
+		case seenUsernames[row.Username] > 0:
+			addError("username", fmt.Sprintf("duplicate username, first seen in row %d", seenUsernames[row.Username]))
+		default:
+			seenUsernames[row.Username] = row.Row
+		}
+
+		status := row.Status
+		if status == "" {
+			status = "active"
+		}
+		if !containsString(userStatuses, status) {
+			addError("status", fmt.Sprintf("invalid status %q, must be one of %s", row.Status, strings.Join(userStatuses, ", ")))
+		}
+
+		roles := row.Roles
+		if len(roles) == 0 {
+			roles = []string{defaultImportRole}
+		}
+		ids := make([]uint, 0, len(roles))
+		seenRoles := make(map[uint]bool, len(roles)) // 同一行重复的角色只分配一次
+		for _, name := range roles {
+			id, ok := roleIDs[name]
+			if !ok {
+				role, err := s.permissionStore.GetRoleByName(name)
+				if err != nil {
+					return nil, nil, fmt.Errorf("failed to look up role %q: %w", name, err)
+				}
+				if role != nil {
+					id = role.ID
+				}
+				roleIDs[name] = id
+			}
+			// 与注册时相同，默认角色不存在时在导入时创建，这里先以 0 占位
+			if id == 0 && name != defaultImportRole {
+				addError("roles", fmt.Sprintf("unknown role %q", name))
+				continue
+			}
+			if !seenRoles[id] {
+				seenRoles[id] = true
+				ids = append(ids, id)
+			}
+		}
+
+		if len(report.Errors) == errorCount {
+			password, err := unusablePassword()
+			if err != nil {
+				return nil, nil, err
+			}
+			users = append(users, models.User{
+				Username: row.Username,
+				Email:    row.Email,
+				Password: password,
+				Status:   status,
+			})
+			userRoles = append(userRoles, ids)
+		}
+
+		if progress != nil && ((i+1)%exportProgressInterval == 0 || i+1 == len(rows)) {
+			if err := progress(i+1, len(rows)); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	return users, userRoles, nil
+}
+
+func validImportEmail(email string) bool {
+	addr, err := mail.ParseAddress(email)
+	return err == nil && addr.Address == email
+}
+
+// unusablePassword 导入用户的密码，不是有效的 bcrypt 哈希因此无法用于登录，
+// 用户需要通过邀请邮件或重置密码设置密码
+func unusablePassword() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate password: %w", err)
+	}
+	return "!" + hex.EncodeToString(b), nil
+}
+
+// ParseUserImport 解析导入文件。字段与用户导出一致（CSV 表头可以是列名或中文表头），
+// 另外可以通过 roles 字段指定角色，CSV 中多个角色以分号分隔；id 和时间等字段会被忽略
+func ParseUserImport(format string, r io.Reader) ([]UserImportRow, error) {
+	switch format {
+	case "csv":
+		return parseUserImportCSV(r)
+	case "json":
+		return parseUserImportJSON(r)
+	case "jsonl":
+		return parseUserImportJSONLines(r)
+	default:
+		return nil, ErrInvalidImportFormat
+	}
+}
+
+// userImportFields 可以导入的字段及其 CSV 表头
+var userImportFields = map[string]string{
+	"username": "用户名",
+	"email":    "邮箱",
+	"status":   "状态",
+	"roles":    "角色",
+}
+
+func parseUserImportCSV(r io.Reader) ([]UserImportRow, error) {
+	reader := csv.NewReader(bufio.NewReader(r))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%w: empty file", ErrInvalidImportFile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImportFile, err)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+		for field, title := range userImportFields {
+			if strings.EqualFold(name, field) || name == title {
+				columns[field] = i
+			}
+		}
+	}
+	for _, field := range []string{"username", "email"} {
+		if _, ok := columns[field]; !ok {
+			return nil, fmt.Errorf("%w: missing %s column", ErrInvalidImportFile, field)
+		}
+	}
+
+	var rows []UserImportRow
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidImportFile, err)
+		}
+		value := func(field string) string {
+			i, ok := columns[field]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+		rows = append(rows, UserImportRow{
+			Row:      line,
+			Username: value("username"),
+			Email:    value("email"),
+			Status:   value("status"),
+			Roles:    splitImportRoles(value("roles")),
+		})
+	}
+	return rows, nil
+}
+
+func splitImportRoles(value string) []string {
+	var roles []string
+	for _, role := range strings.Split(value, ";") {
+		if role = strings.TrimSpace(role); role != "" {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+// userImportRecord JSON 导入记录，roles 可以是字符串数组，也可以是分号分隔的字符串
+type userImportRecord struct {
+	Username string          `json:"username"`
+	Email    string          `json:"email"`
+	Status   string          `json:"status"`
+	Roles    json.RawMessage `json:"roles"`
+}
+
+func (rec *userImportRecord) toRow(n int) (UserImportRow, error) {
+	row := UserImportRow{
+		Row:      n,
+		Username: strings.TrimSpace(rec.Username),
+		Email:    strings.TrimSpace(rec.Email),
+		Status:   strings.TrimSpace(rec.Status),
+	}
+	if len(rec.Roles) == 0 || string(rec.Roles) == "null" {
+		return row, nil
+	}
+
+	var roles []string
+	if err := json.Unmarshal(rec.Roles, &roles); err == nil {
+		for _, role := range roles {
+			if role = strings.TrimSpace(role); role != "" {
+				row.Roles = append(row.Roles, role)
+			}
+		}
+		return row, nil
+	}
+	var value string
+	if err := json.Unmarshal(rec.Roles, &value); err != nil {
+		return row, fmt.Errorf("%w: record %d: roles must be a string or an array of strings", ErrInvalidImportFile, n)
+	}
+	row.Roles = splitImportRoles(value)
+	return row, nil
+}
+
+func parseUserImportJSON(r io.Reader) ([]UserImportRow, error) {
+	decoder := json.NewDecoder(bufio.NewReader(r))
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImportFile, err)
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return nil, fmt.Errorf("%w: expected a JSON array", ErrInvalidImportFile)
+	}
+
+	var rows []UserImportRow
+	for n := 1; decoder.More(); n++ {
+		var rec userImportRecord
+		if err := decoder.Decode(&rec); err != nil {
+			return nil, fmt.Errorf("%w: record %d: %v", ErrInvalidImportFile, n, err)
+		}
+		row, err := rec.toRow(n)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+	if _, err := decoder.Token(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImportFile, err)
+	}
+	return rows, nil
+}
+
+func parseUserImportJSONLines(r io.Reader) ([]UserImportRow, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var rows []UserImportRow
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		var rec userImportRecord
+		if err := json.Unmarshal(text, &rec); err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidImportFile, line, err)
+		}
+		row, err := rec.toRow(line)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImportFile, err)
+	}
+	return rows, nil
+}
+
+// ImportFormatFromFileName 根据文件扩展名判断导入格式
+func ImportFormatFromFileName(name string) string {
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(name), "."))
+	if ext == "ndjson" {
+		return "jsonl"
+	}
+	return ext
+}
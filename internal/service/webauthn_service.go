@@ -0,0 +1,539 @@
+package service
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"go-vibe-friend/internal/config"
+	"go-vibe-friend/internal/models"
+	"go-vibe-friend/internal/store"
+	"go-vibe-friend/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// webAuthnChallengeTTL 注册和登录仪式的有效期，同时作为浏览器的超时时间
+	webAuthnChallengeTTL = 5 * time.Minute
+	// defaultPasskeyName 未指定名称时通行密钥的名称
+	defaultPasskeyName = "Passkey"
+
+	webAuthnCeremonyRegistration = "registration"
+	webAuthnCeremonyLogin        = "login"
+)
+
+var (
+	// ErrInvalidWebAuthnChallenge 质询不存在、已过期或已被使用
+	ErrInvalidWebAuthnChallenge = errors.New("invalid or expired webauthn challenge")
+	// ErrWebAuthnVerificationFailed 凭据、签名或浏览器数据校验失败
+	ErrWebAuthnVerificationFailed = errors.New("webauthn verification failed")
+	// ErrPasskeyAlreadyRegistered 通行密钥已被登记
+	ErrPasskeyAlreadyRegistered = errors.New("passkey is already registered")
+	// ErrPasskeyNotFound 通行密钥不存在
+	ErrPasskeyNotFound = errors.New("passkey not found")
+	// ErrPasskeyCloned 签名计数器回退，认证器可能被复制，凭据已停用
+	ErrPasskeyCloned = errors.New("passkey sign count went backwards, the authenticator may be cloned")
+)
+
+// webAuthnTransports 浏览器可能返回的认证器传输方式
+var webAuthnTransports = map[string]bool{
+	"usb": true, "nfc": true, "ble": true, "smart-card": true, "hybrid": true, "internal": true,
+}
+
+// WebAuthnService 实现通行密钥（WebAuthn）的注册和登录仪式以及凭据管理。
+// 登录要求用户验证（指纹、PIN 等），因此通行密钥登录本身就是多因素认证，不再要求 TOTP
+type WebAuthnService struct {
+	webAuthnStore *store.WebAuthnStore
+	userStore     *store.UserStore
+	rpID          string
+	rpName        string
+	rpIDHash      [32]byte
+	origins       map[string]bool
+}
+
+func NewWebAuthnService(webAuthnStore *store.WebAuthnStore, userStore *store.UserStore, cfg *config.Config) *WebAuthnService {
+	origins := make(map[string]bool, len(cfg.WebAuthn.Origins))
+	for _, origin := range cfg.WebAuthn.Origins {
+		origins[strings.TrimRight(strings.TrimSpace(origin), "/")] = true
+	}
+	return &WebAuthnService{
+		webAuthnStore: webAuthnStore,
+		userStore:     userStore,
+		rpID:          cfg.WebAuthn.RPID,
+		rpName:        cfg.WebAuthn.RPName,
+		rpIDHash:      sha256.Sum256([]byte(cfg.WebAuthn.RPID)),
+		origins:       origins,
+	}
+}
+
+// 以下类型按 WebAuthn 的 JSON 格式序列化，二进制字段使用 base64url，
+// 前端可以直接交给 PublicKeyCredential.parseCreationOptionsFromJSON 等方法
+
+// RelyingPartyEntity 依赖方
+type RelyingPartyEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// WebAuthnUserEntity 注册时的用户信息，ID 为不含个人信息的用户句柄
+type WebAuthnUserEntity struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+// CredentialParameters 接受的公钥算法
+type CredentialParameters struct {
+	Type string `json:"type"`
+	Alg  int64  `json:"alg"`
+}
+
+// CredentialDescriptor 引用一个已登记的凭据
+type CredentialDescriptor struct {
+	Type       string   `json:"type"`
+	ID         string   `json:"id"`
+	Transports []string `json:"transports,omitempty"`
+}
+
+// AuthenticatorSelection 对认证器的要求
+type AuthenticatorSelection struct {
+	ResidentKey        string `json:"residentKey"`
+	RequireResidentKey bool   `json:"requireResidentKey"`
+	UserVerification   string `json:"userVerification"`
+}
+
+// CredentialCreationOptions 注册仪式的参数，对应 navigator.credentials.create 的 publicKey
+type CredentialCreationOptions struct {
+	Challenge              string                 `json:"challenge"`
+	RP                     RelyingPartyEntity     `json:"rp"`
+	User                   WebAuthnUserEntity     `json:"user"`
+	PubKeyCredParams       []CredentialParameters `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// CredentialRequestOptions 登录仪式的参数，对应 navigator.credentials.get 的 publicKey
+type CredentialRequestOptions struct {
+	Challenge        string                 `json:"challenge"`
+	Timeout          int64                  `json:"timeout"`
+	RPID             string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+// RegistrationCredential 注册仪式的结果，即 PublicKeyCredential.toJSON() 的输出
+type RegistrationCredential struct {
+	ID       string `json:"id" binding:"required"`
+	Type     string `json:"type" binding:"required"`
+	Response struct {
+		ClientDataJSON    string   `json:"clientDataJSON" binding:"required"`
+		AttestationObject string   `json:"attestationObject" binding:"required"`
+		Transports        []string `json:"transports"`
+	} `json:"response"`
+}
+
+// AssertionCredential 登录仪式的结果，即 PublicKeyCredential.toJSON() 的输出
+type AssertionCredential struct {
+	ID       string `json:"id" binding:"required"`
+	Type     string `json:"type" binding:"required"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON" binding:"required"`
+		AuthenticatorData string `json:"authenticatorData" binding:"required"`
+		Signature         string `json:"signature" binding:"required"`
+		UserHandle        string `json:"userHandle"`
+	} `json:"response"`
+}
+
+// BeginRegistration 开始为用户登记通行密钥，返回浏览器需要的参数
+func (s *WebAuthnService) BeginRegistration(userID uint) (*CredentialCreationOptions, error) {
+	user, err := s.userStore.GetUserByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	credentials, err := s.webAuthnStore.ListCredentials(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list passkeys: %w", err)
+	}
+
+	challenge, err := s.newChallenge(webAuthnCeremonyRegistration, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CredentialCreationOptions{
+		Challenge: challenge,
+		RP:        RelyingPartyEntity{ID: s.rpID, Name: s.rpName},
+		User: WebAuthnUserEntity{
+			ID:          utils.EncodeBase64URL(userHandle(userID)),
+			Name:        user.Email,
+			DisplayName: user.Username,
+		},
+		PubKeyCredParams: []CredentialParameters{
+			{Type: "public-key", Alg: utils.COSEAlgES256},
+			{Type: "public-key", Alg: utils.COSEAlgEdDSA},
+			{Type: "public-key", Alg: utils.COSEAlgRS256},
+		},
+		Timeout:            webAuthnChallengeTTL.Milliseconds(),
+		ExcludeCredentials: credentialDescriptors(credentials),
+		AuthenticatorSelection: AuthenticatorSelection{
+			ResidentKey:      "preferred",
+			UserVerification: "required",
+		},
+		Attestation: "none",
+	}, nil
+}
+
+// FinishRegistration 校验注册仪式的结果并保存通行密钥
+func (s *WebAuthnService) FinishRegistration(userID uint, name string, credential *RegistrationCredential) (*models.WebAuthnCredential, error) {
+	clientDataJSON, err := utils.DecodeBase64URL(credential.Response.ClientDataJSON)
+	if err != nil {
+		return nil, ErrWebAuthnVerificationFailed
+	}
+	challenge, err := s.consumeChallenge(clientDataJSON, "webauthn.create")
+	if err != nil {
+		return nil, err
+	}
+	if challenge.Ceremony != webAuthnCeremonyRegistration || challenge.UserID != userID {
+		return nil, ErrInvalidWebAuthnChallenge
+	}
+
+	attestationObject, err := utils.DecodeBase64URL(credential.Response.AttestationObject)
+	if err != nil {
+		return nil, ErrWebAuthnVerificationFailed
+	}
+	// Attestation is requested as "none": the authenticator model is not
+	// checked, so the attestation statement is not verified either
+	rawAuthData, _, err := utils.ParseAttestationObject(attestationObject)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrWebAuthnVerificationFailed, err)
+	}
+	authData, err := s.verifyAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+	if authData.CredentialID == nil {
+		return nil, fmt.Errorf("%w: no attested credential data", ErrWebAuthnVerificationFailed)
+	}
+	credentialID := utils.EncodeBase64URL(authData.CredentialID)
+	if credential.Type != "public-key" || normalizeCredentialID(credential.ID) != credentialID {
+		return nil, fmt.Errorf("%w: credential ID mismatch", ErrWebAuthnVerificationFailed)
+	}
+	algorithm, err := utils.COSEKeyAlgorithm(authData.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrWebAuthnVerificationFailed, err)
+	}
+
+	existing, err := s.webAuthnStore.GetCredentialByCredentialID(credentialID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get passkey: %w", err)
+	}
+	if existing != nil {
+		return nil, ErrPasskeyAlreadyRegistered
+	}
+
+	if name = strings.TrimSpace(name); name == "" {
+		name = defaultPasskeyName
+	}
+	passkey := &models.WebAuthnCredential{
+		UserID:         userID,
+		Name:           name,
+		CredentialID:   credentialID,
+		PublicKey:      authData.PublicKey,
+		Algorithm:      algorithm,
+		Transports:     joinTransports(credential.Response.Transports),
+		SignCount:      authData.SignCount,
+		BackupEligible: authData.BackupEligible(),
+		BackupState:    authData.BackupState(),
+	}
+	if aaguid, err := uuid.FromBytes(authData.AAGUID); err == nil {
+		passkey.AAGUID = aaguid.String()
+	}
+	if err := s.webAuthnStore.CreateCredential(passkey); err != nil {
+		return nil, fmt.Errorf("failed to save passkey: %w", err)
+	}
+	return passkey, nil
+}
+
+// BeginLogin 开始通行密钥登录。email 为空时使用可发现凭据，由用户在浏览器中选择账号。
+// 指定 email 时 allowCredentials 列出该用户的凭据，以支持不可发现的凭据；
+// 因此响应会暴露该邮箱是否已登记通行密钥，不希望暴露时前端应省略 email
+func (s *WebAuthnService) BeginLogin(email string) (*CredentialRequestOptions, error) {
+	var userID uint
+	allow := []CredentialDescriptor{}
+	if email != "" {
+		user, err := s.userStore.GetUserByEmail(email)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get user: %w", err)
+		}
+		if user != nil {
+			credentials, err := s.webAuthnStore.ListCredentials(user.ID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list passkeys: %w", err)
+			}
+			userID = user.ID
+			allow = credentialDescriptors(credentials)
+		}
+	}
+
+	challenge, err := s.newChallenge(webAuthnCeremonyLogin, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CredentialRequestOptions{
+		Challenge:        challenge,
+		Timeout:          webAuthnChallengeTTL.Milliseconds(),
+		RPID:             s.rpID,
+		AllowCredentials: allow,
+		UserVerification: "required",
+	}, nil
+}
+
+// FinishLogin 校验登录仪式的结果，返回登录的用户。签名计数器没有增加时视为认证器被复制，
+// 停用该通行密钥并记录审计日志
+func (s *WebAuthnService) FinishLogin(credential *AssertionCredential, ipAddress, userAgent string) (*models.User, error) {
+	clientDataJSON, err := utils.DecodeBase64URL(credential.Response.ClientDataJSON)
+	if err != nil {
+		return nil, ErrWebAuthnVerificationFailed
+	}
+	challenge, err := s.consumeChallenge(clientDataJSON, "webauthn.get")
+	if err != nil {
+		return nil, err
+	}
+	if challenge.Ceremony != webAuthnCeremonyLogin {
+		return nil, ErrInvalidWebAuthnChallenge
+	}
+
+	passkey, err := s.webAuthnStore.GetCredentialByCredentialID(normalizeCredentialID(credential.ID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get passkey: %w", err)
+	}
+	if passkey == nil || credential.Type != "public-key" {
+		return nil, ErrWebAuthnVerificationFailed
+	}
+	// A challenge issued for a given email only accepts that user's passkeys
+	if challenge.UserID != 0 && challenge.UserID != passkey.UserID {
+		return nil, ErrWebAuthnVerificationFailed
+	}
+	if credential.Response.UserHandle != "" {
+		handle, err := utils.DecodeBase64URL(credential.Response.UserHandle)
+		if err != nil || !bytes.Equal(handle, userHandle(passkey.UserID)) {
+			return nil, ErrWebAuthnVerificationFailed
+		}
+	}
+	if passkey.CloneDetected {
+		return nil, ErrPasskeyCloned
+	}
+
+	rawAuthData, err := utils.DecodeBase64URL(credential.Response.AuthenticatorData)
+	if err != nil {
+		return nil, ErrWebAuthnVerificationFailed
+	}
+	authData, err := s.verifyAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := utils.DecodeBase64URL(credential.Response.Signature)
+	if err != nil {
+		return nil, ErrWebAuthnVerificationFailed
+	}
+	if err := utils.VerifyWebAuthnSignature(passkey.PublicKey, rawAuthData, clientDataJSON, signature); err != nil {
+		return nil, ErrWebAuthnVerificationFailed
+	}
+
+	// Authenticators without a counter always report 0; otherwise the
+	// counter must increase on every use
+	if (authData.SignCount != 0 || passkey.SignCount != 0) && authData.SignCount <= passkey.SignCount {
+		return nil, s.disableClonedPasskey(passkey, authData.SignCount, ipAddress, userAgent)
+	}
+	applied, err := s.webAuthnStore.RecordUse(passkey.ID, passkey.SignCount, authData.SignCount, authData.BackupState())
+	if err != nil {
+		return nil, fmt.Errorf("failed to update passkey: %w", err)
+	}
+	if !applied {
+		// another login with the same passkey got in first
+		return nil, ErrWebAuthnVerificationFailed
+	}
+
+	user, err := s.userStore.GetUserByID(passkey.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrWebAuthnVerificationFailed
+	}
+	return user, nil
+}
+
+// ListCredentials 获取用户的通行密钥
+func (s *WebAuthnService) ListCredentials(userID uint) ([]models.WebAuthnCredential, error) {
+	return s.webAuthnStore.ListCredentials(userID)
+}
+
+// RenameCredential 重命名用户的通行密钥
+func (s *WebAuthnService) RenameCredential(userID, id uint, name string) (*models.WebAuthnCredential, error) {
+	if name = strings.TrimSpace(name); name == "" {
+		name = defaultPasskeyName
+	}
+	found, err := s.webAuthnStore.RenameCredential(userID, id, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rename passkey: %w", err)
+	}
+	if !found {
+		return nil, ErrPasskeyNotFound
+	}
+	return s.webAuthnStore.GetUserCredential(userID, id)
+}
+
+// DeleteCredential 删除用户的通行密钥
+func (s *WebAuthnService) DeleteCredential(userID, id uint) error {
+	found, err := s.webAuthnStore.DeleteCredential(userID, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete passkey: %w", err)
+	}
+	if !found {
+		return ErrPasskeyNotFound
+	}
+	return nil
+}
+
+// newChallenge 生成并保存一次性质询
+func (s *WebAuthnService) newChallenge(ceremony string, userID uint) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate challenge: %w", err)
+	}
+	challenge := utils.EncodeBase64URL(raw)
+
+	record := &models.WebAuthnChallenge{
+		UserID:        userID,
+		Ceremony:      ceremony,
+		ChallengeHash: hashSecret(challenge),
+		ExpiresAt:     time.Now().Add(webAuthnChallengeTTL),
+	}
+	if err := s.webAuthnStore.CreateChallenge(record); err != nil {
+		return "", fmt.Errorf("failed to save challenge: %w", err)
+	}
+	return challenge, nil
+}
+
+// consumeChallenge 校验 clientDataJSON 的类型和来源，并消耗其中的质询
+func (s *WebAuthnService) consumeChallenge(clientDataJSON []byte, clientDataType string) (*models.WebAuthnChallenge, error) {
+	clientData, err := utils.ParseClientData(clientDataJSON)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrWebAuthnVerificationFailed, err)
+	}
+	if clientData.Type != clientDataType {
+		return nil, fmt.Errorf("%w: unexpected client data type %q", ErrWebAuthnVerificationFailed, clientData.Type)
+	}
+	if !s.origins[clientData.Origin] || clientData.CrossOrigin {
+		return nil, fmt.Errorf("%w: origin %q is not allowed", ErrWebAuthnVerificationFailed, clientData.Origin)
+	}
+
+	challenge, err := s.webAuthnStore.ConsumeChallenge(hashSecret(clientData.Challenge))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get challenge: %w", err)
+	}
+	if challenge == nil || time.Now().After(challenge.ExpiresAt) {
+		return nil, ErrInvalidWebAuthnChallenge
+	}
+	return challenge, nil
+}
+
+// verifyAuthenticatorData 校验认证器数据属于本站点，且用户在场并完成了验证
+func (s *WebAuthnService) verifyAuthenticatorData(raw []byte) (*utils.AuthenticatorData, error) {
+	authData, err := utils.ParseAuthenticatorData(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrWebAuthnVerificationFailed, err)
+	}
+	if !bytes.Equal(authData.RPIDHash, s.rpIDHash[:]) {
+		return nil, fmt.Errorf("%w: relying party ID mismatch", ErrWebAuthnVerificationFailed)
+	}
+	if !authData.UserPresent() || !authData.UserVerified() {
+		return nil, fmt.Errorf("%w: user presence and verification are required", ErrWebAuthnVerificationFailed)
+	}
+	return authData, nil
+}
+
+// disableClonedPasskey 停用签名计数器回退的通行密钥并记录审计日志
+func (s *WebAuthnService) disableClonedPasskey(passkey *models.WebAuthnCredential, signCount uint32, ipAddress, userAgent string) error {
+	if err := s.webAuthnStore.MarkCloneDetected(passkey.ID); err != nil {
+		return fmt.Errorf("failed to disable passkey: %w", err)
+	}
+
+	log.Printf("Passkey %d of user %d reported sign count %d, stored %d; possible cloned authenticator, passkey disabled",
+		passkey.ID, passkey.UserID, signCount, passkey.SignCount)
+
+	details, _ := json.Marshal(map[string]interface{}{
+		"credential_id":     passkey.ID,
+		"stored_sign_count": passkey.SignCount,
+		"sign_count":        signCount,
+	})
+	auditLog := &models.AuditLog{
+		ActorID:   passkey.UserID,
+		Resource:  "passkey",
+		Action:    "clone_detected",
+		Details:   string(details),
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
+	}
+	if err := s.userStore.CreateAuditLog(auditLog); err != nil {
+		log.Printf("Failed to record cloned passkey %d: %v", passkey.ID, err)
+	}
+
+	return ErrPasskeyCloned
+}
+
+// userHandle 用户句柄，8 字节大端序的用户ID
+func userHandle(userID uint) []byte {
+	handle := make([]byte, 8)
+	binary.BigEndian.PutUint64(handle, uint64(userID))
+	return handle
+}
+
+// credentialDescriptors 将已登记的通行密钥转换为凭据引用
+func credentialDescriptors(credentials []models.WebAuthnCredential) []CredentialDescriptor {
+	descriptors := make([]CredentialDescriptor, 0, len(credentials))
+	for _, credential := range credentials {
+		descriptor := CredentialDescriptor{Type: "public-key", ID: credential.CredentialID}
+		if credential.Transports != "" {
+			descriptor.Transports = strings.Split(credential.Transports, ",")
+		}
+		descriptors = append(descriptors, descriptor)
+	}
+	return descriptors
+}
+
+// joinTransports 保留已知的传输方式，以逗号分隔保存
+func joinTransports(transports []string) string {
+	known := make([]string, 0, len(transports))
+	for _, transport := range transports {
+		if webAuthnTransports[transport] {
+			known = append(known, transport)
+		}
+	}
+	return strings.Join(known, ",")
+}
+
+// normalizeCredentialID 将凭据ID统一为不带填充的 base64url
+func normalizeCredentialID(id string) string {
+	raw, err := utils.DecodeBase64URL(id)
+	if err != nil {
+		return ""
+	}
+	return utils.EncodeBase64URL(raw)
+}
@@ -0,0 +1,369 @@
+package service
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"go-vibe-friend/internal/config"
+	"go-vibe-friend/internal/models"
+	"go-vibe-friend/internal/store"
+	"go-vibe-friend/internal/utils"
+)
+
+const (
+	testRPID   = "localhost"
+	testOrigin = "http://localhost:3000"
+
+	flagsUPUV    byte = 0x05 // 用户在场且已验证
+	flagsUV      byte = 0x04 // 只有用户验证
+	flagAttested byte = 0x40
+)
+
+// softAuthenticator 在内存中模拟 ES256 或 Ed25519 认证器
+type softAuthenticator struct {
+	credentialID []byte
+	ecKey        *ecdsa.PrivateKey
+	edKey        ed25519.PrivateKey
+}
+
+func newSoftAuthenticator(t *testing.T, alg int64) *softAuthenticator {
+	t.Helper()
+	a := &softAuthenticator{credentialID: make([]byte, 16)}
+	if _, err := rand.Read(a.credentialID); err != nil {
+		t.Fatal(err)
+	}
+	var err error
+	switch alg {
+	case utils.COSEAlgES256:
+		a.ecKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case utils.COSEAlgEdDSA:
+		_, a.edKey, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		t.Fatalf("unsupported algorithm %d", alg)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func (a *softAuthenticator) id() string {
+	return utils.EncodeBase64URL(a.credentialID)
+}
+
+func (a *softAuthenticator) coseKey() []byte {
+	if a.edKey != nil {
+		return cborEncode(map[interface{}]interface{}{
+			1: 1, 3: int(utils.COSEAlgEdDSA), -1: 6, -2: []byte(a.edKey.Public().(ed25519.PublicKey)),
+		})
+	}
+	return cborEncode(map[interface{}]interface{}{
+		1: 2, 3: int(utils.COSEAlgES256), -1: 1,
+		-2: a.ecKey.X.FillBytes(make([]byte, 32)),
+		-3: a.ecKey.Y.FillBytes(make([]byte, 32)),
+	})
+}
+
+func (a *softAuthenticator) sign(t *testing.T, authData, clientDataJSON []byte) []byte {
+	t.Helper()
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(append([]byte{}, authData...), clientDataHash[:]...)
+	if a.edKey != nil {
+		return ed25519.Sign(a.edKey, signed)
+	}
+	digest := sha256.Sum256(signed)
+	signature, err := ecdsa.SignASN1(rand.Reader, a.ecKey, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signature
+}
+
+// create 响应注册仪式，attestation 格式为 none
+func (a *softAuthenticator) create(t *testing.T, options *CredentialCreationOptions) *RegistrationCredential {
+	t.Helper()
+	attested := make([]byte, 16, 64) // AAGUID
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(a.credentialID)))
+	attested = append(attested, a.credentialID...)
+	attested = append(attested, a.coseKey()...)
+	authData := buildAuthenticatorData(options.RP.ID, flagsUPUV|flagAttested, 0, attested)
+
+	credential := &RegistrationCredential{ID: a.id(), Type: "public-key"}
+	credential.Response.ClientDataJSON = utils.EncodeBase64URL(buildClientData("webauthn.create", options.Challenge, testOrigin))
+	credential.Response.AttestationObject = utils.EncodeBase64URL(cborEncode(map[interface{}]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[interface{}]interface{}{},
+		"authData": authData,
+	}))
+	credential.Response.Transports = []string{"internal"}
+	return credential
+}
+
+// assertion 登录仪式的参数，默认值对应合法的响应，测试按需修改
+type assertion struct {
+	rpID      string
+	origin    string
+	flags     byte
+	signCount uint32
+}
+
+func validAssertion(signCount uint32) assertion {
+	return assertion{rpID: testRPID, origin: testOrigin, flags: flagsUPUV, signCount: signCount}
+}
+
+func (a *softAuthenticator) get(t *testing.T, options *CredentialRequestOptions, p assertion) *AssertionCredential {
+	t.Helper()
+	authData := buildAuthenticatorData(p.rpID, p.flags, p.signCount, nil)
+	clientDataJSON := buildClientData("webauthn.get", options.Challenge, p.origin)
+
+	credential := &AssertionCredential{ID: a.id(), Type: "public-key"}
+	credential.Response.ClientDataJSON = utils.EncodeBase64URL(clientDataJSON)
+	credential.Response.AuthenticatorData = utils.EncodeBase64URL(authData)
+	credential.Response.Signature = utils.EncodeBase64URL(a.sign(t, authData, clientDataJSON))
+	return credential
+}
+
+func buildAuthenticatorData(rpID string, flags byte, signCount uint32, attested []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	data := append(rpIDHash[:], flags)
+	data = binary.BigEndian.AppendUint32(data, signCount)
+	return append(data, attested...)
+}
+
+func buildClientData(clientDataType, challenge, origin string) []byte {
+	data, _ := json.Marshal(map[string]interface{}{
+		"type":        clientDataType,
+		"challenge":   challenge,
+		"origin":      origin,
+		"crossOrigin": false,
+	})
+	return data
+}
+
+// cborEncode 只支持测试用到的类型：int、[]byte、string 和 map
+func cborEncode(v interface{}) []byte {
+	head := func(major byte, n uint64) []byte {
+		switch {
+		case n < 24:
+			return []byte{major<<5 | byte(n)}
+		case n < 1<<8:
+			return []byte{major<<5 | 24, byte(n)}
+		case n < 1<<16:
+			return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(n))
+		default:
+			return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(n))
+		}
+	}
+
+	switch x := v.(type) {
+	case int:
+		if x >= 0 {
+			return head(0, uint64(x))
+		}
+		return head(1, uint64(-1-x))
+	case []byte:
+		return append(head(2, uint64(len(x))), x...)
+	case string:
+		return append(head(3, uint64(len(x))), x...)
+	case map[interface{}]interface{}:
+		keys := make([]interface{}, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+		out := head(5, uint64(len(x)))
+		for _, k := range keys {
+			out = append(out, cborEncode(k)...)
+			out = append(out, cborEncode(x[k])...)
+		}
+		return out
+	}
+	panic(fmt.Sprintf("cborEncode: unsupported type %T", v))
+}
+
+// newTestWebAuthnService 使用临时 SQLite 数据库创建服务和一个测试用户
+func newTestWebAuthnService(t *testing.T) (*WebAuthnService, *store.Database, *models.User) {
+	t.Helper()
+	cfg := &config.Config{
+		Database: config.DatabaseConfig{Driver: "sqlite", Name: filepath.Join(t.TempDir(), "test.db")},
+		WebAuthn: config.WebAuthnConfig{RPID: testRPID, RPName: "go-vibe-friend", Origins: []string{testOrigin}},
+	}
+	db, err := store.NewDatabase(cfg)
+	if err != nil {
+		t.Fatalf("NewDatabase: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	userStore := store.NewUserStore(db)
+	user := &models.User{Username: "alice", Email: "alice@example.com", Password: "x", Status: "active"}
+	if err := userStore.CreateUser(user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	return NewWebAuthnService(store.NewWebAuthnStore(db), userStore, cfg), db, user
+}
+
+func registerPasskey(t *testing.T, svc *WebAuthnService, user *models.User, a *softAuthenticator) *models.WebAuthnCredential {
+	t.Helper()
+	options, err := svc.BeginRegistration(user.ID)
+	if err != nil {
+		t.Fatalf("BeginRegistration: %v", err)
+	}
+	passkey, err := svc.FinishRegistration(user.ID, "Laptop", a.create(t, options))
+	if err != nil {
+		t.Fatalf("FinishRegistration: %v", err)
+	}
+	return passkey
+}
+
+func beginLogin(t *testing.T, svc *WebAuthnService, email string) *CredentialRequestOptions {
+	t.Helper()
+	options, err := svc.BeginLogin(email)
+	if err != nil {
+		t.Fatalf("BeginLogin: %v", err)
+	}
+	return options
+}
+
+func TestWebAuthnRegisterAndLogin(t *testing.T) {
+	tests := []struct {
+		name      string
+		alg       int64
+		signCount uint32 // 0 表示认证器没有签名计数器
+	}{
+		{"ES256", utils.COSEAlgES256, 1},
+		{"Ed25519", utils.COSEAlgEdDSA, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, _, user := newTestWebAuthnService(t)
+			a := newSoftAuthenticator(t, tt.alg)
+
+			passkey := registerPasskey(t, svc, user, a)
+			if passkey.Algorithm != tt.alg || passkey.CredentialID != a.id() || passkey.Name != "Laptop" {
+				t.Fatalf("unexpected passkey: %+v", passkey)
+			}
+
+			for _, email := range []string{user.Email, ""} {
+				options := beginLogin(t, svc, email)
+				credential := a.get(t, options, validAssertion(tt.signCount))
+				got, err := svc.FinishLogin(credential, "127.0.0.1", "test")
+				if err != nil {
+					t.Fatalf("FinishLogin(email=%q): %v", email, err)
+				}
+				if got.ID != user.ID {
+					t.Fatalf("FinishLogin returned user %d, want %d", got.ID, user.ID)
+				}
+
+				// 质询只能使用一次
+				if _, err := svc.FinishLogin(credential, "127.0.0.1", "test"); !errors.Is(err, ErrInvalidWebAuthnChallenge) {
+					t.Fatalf("replayed assertion: got %v, want ErrInvalidWebAuthnChallenge", err)
+				}
+				if tt.signCount != 0 {
+					tt.signCount++
+				}
+			}
+		})
+	}
+}
+
+func TestWebAuthnRejectsInvalidAssertions(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(p *assertion)
+		tamper func(c *AssertionCredential)
+	}{
+		{name: "wrong origin", modify: func(p *assertion) { p.origin = "https://evil.example" }},
+		{name: "wrong rpId hash", modify: func(p *assertion) { p.rpID = "evil.example" }},
+		{name: "missing user presence", modify: func(p *assertion) { p.flags = flagsUV }},
+		{name: "bad signature", tamper: func(c *AssertionCredential) {
+			signature, _ := utils.DecodeBase64URL(c.Response.Signature)
+			signature[len(signature)-1] ^= 0xff
+			c.Response.Signature = utils.EncodeBase64URL(signature)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, _, user := newTestWebAuthnService(t)
+			a := newSoftAuthenticator(t, utils.COSEAlgES256)
+			registerPasskey(t, svc, user, a)
+
+			p := validAssertion(1)
+			if tt.modify != nil {
+				tt.modify(&p)
+			}
+			credential := a.get(t, beginLogin(t, svc, user.Email), p)
+			if tt.tamper != nil {
+				tt.tamper(credential)
+			}
+
+			if _, err := svc.FinishLogin(credential, "127.0.0.1", "test"); !errors.Is(err, ErrWebAuthnVerificationFailed) {
+				t.Fatalf("got %v, want ErrWebAuthnVerificationFailed", err)
+			}
+		})
+	}
+}
+
+func TestWebAuthnRejectsRegistrationFromWrongOrigin(t *testing.T) {
+	svc, _, user := newTestWebAuthnService(t)
+	a := newSoftAuthenticator(t, utils.COSEAlgEdDSA)
+
+	options, err := svc.BeginRegistration(user.ID)
+	if err != nil {
+		t.Fatalf("BeginRegistration: %v", err)
+	}
+	credential := a.create(t, options)
+	credential.Response.ClientDataJSON = utils.EncodeBase64URL(buildClientData("webauthn.create", options.Challenge, "https://evil.example"))
+
+	if _, err := svc.FinishRegistration(user.ID, "", credential); !errors.Is(err, ErrWebAuthnVerificationFailed) {
+		t.Fatalf("got %v, want ErrWebAuthnVerificationFailed", err)
+	}
+}
+
+func TestWebAuthnSignCountRegressionDisablesPasskey(t *testing.T) {
+	svc, db, user := newTestWebAuthnService(t)
+	a := newSoftAuthenticator(t, utils.COSEAlgES256)
+	passkey := registerPasskey(t, svc, user, a)
+
+	credential := a.get(t, beginLogin(t, svc, user.Email), validAssertion(5))
+	if _, err := svc.FinishLogin(credential, "127.0.0.1", "test"); err != nil {
+		t.Fatalf("FinishLogin: %v", err)
+	}
+
+	// 克隆的认证器报告的计数器小于已记录的值
+	credential = a.get(t, beginLogin(t, svc, user.Email), validAssertion(3))
+	if _, err := svc.FinishLogin(credential, "203.0.113.7", "cloned"); !errors.Is(err, ErrPasskeyCloned) {
+		t.Fatalf("got %v, want ErrPasskeyCloned", err)
+	}
+
+	var stored models.WebAuthnCredential
+	if err := db.DB.First(&stored, passkey.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !stored.CloneDetected || stored.SignCount != 5 {
+		t.Fatalf("passkey not disabled: clone_detected=%v sign_count=%d", stored.CloneDetected, stored.SignCount)
+	}
+
+	var auditLog models.AuditLog
+	if err := db.DB.Where("resource = ? AND action = ?", "passkey", "clone_detected").First(&auditLog).Error; err != nil {
+		t.Fatalf("clone audit log not written: %v", err)
+	}
+	if auditLog.ActorID != user.ID || auditLog.IPAddress != "203.0.113.7" {
+		t.Fatalf("unexpected audit log: %+v", auditLog)
+	}
+
+	// 停用后即使计数器继续增加也不能登录
+	credential = a.get(t, beginLogin(t, svc, user.Email), validAssertion(50))
+	if _, err := svc.FinishLogin(credential, "127.0.0.1", "test"); !errors.Is(err, ErrPasskeyCloned) {
+		t.Fatalf("got %v, want ErrPasskeyCloned", err)
+	}
+}
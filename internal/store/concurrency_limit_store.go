@@ -0,0 +1,50 @@
+package store
+
+import (
+	"errors"
+
+	"go-vibe-friend/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ConcurrencyLimitStore struct {
+	db *Database
+}
+
+func NewConcurrencyLimitStore(db *Database) *ConcurrencyLimitStore {
+	return &ConcurrencyLimitStore{db: db}
+}
+
+func (s *ConcurrencyLimitStore) ListLimits() ([]models.ConcurrencyLimit, error) {
+	var limits []models.ConcurrencyLimit
+	err := s.db.DB.Order("scope ASC, target ASC").Find(&limits).Error
+	return limits, err
+}
+
+func (s *ConcurrencyLimitStore) GetLimitByID(id uint) (*models.ConcurrencyLimit, error) {
+	var limit models.ConcurrencyLimit
+	err := s.db.DB.First(&limit, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &limit, err
+}
+
+// UpsertLimit creates the limit or updates the existing one for the same
+// scope and target
+func (s *ConcurrencyLimitStore) UpsertLimit(limit *models.ConcurrencyLimit) error {
+	err := s.db.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "scope"}, {Name: "target"}},
+		DoUpdates: clause.AssignmentColumns([]string{"max_running", "updated_at"}),
+	}).Create(limit).Error
+	if err != nil {
+		return err
+	}
+	return s.db.DB.Where("scope = ? AND target = ?", limit.Scope, limit.Target).First(limit).Error
+}
+
+func (s *ConcurrencyLimitStore) DeleteLimit(id uint) error {
+	return s.db.DB.Delete(&models.ConcurrencyLimit{}, id).Error
+}
@@ -55,6 +55,10 @@ func NewDatabase(cfg *config.Config) (*Database, error) {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
+	if err := renameIdempotencyKeyColumn(db); err != nil {
+		return nil, fmt.Errorf("failed to migrate idempotency keys: %w", err)
+	}
+
 	// Auto-migrate models
 	if err := db.AutoMigrate(
 		&models.User{},
@@ -63,6 +67,7 @@ func NewDatabase(cfg *config.Config) (*Database, error) {
 		&models.UserRole{},
 		&models.Session{},
 		&models.Job{},
+		&models.JobDependency{},
 		&models.AuditLog{},
 		&models.Setting{},
 		&models.File{},
@@ -76,6 +81,18 @@ func NewDatabase(cfg *config.Config) (*Database, error) {
 		&models.UserPermission{},
 		&models.ResourcePolicy{},
 		&models.APIRateLimit{},
+		&models.RecurringJob{},
+		&models.QueueJob{},
+		&models.IdempotencyKey{},
+		&models.ConcurrencyLimit{},
+		&models.ExportFile{},
+		&models.RevokedToken{},
+		&models.TokenWatermark{},
+		&models.TwoFactorAuth{},
+		&models.TwoFactorRecoveryCode{},
+		&models.TwoFactorChallenge{},
+		&models.WebAuthnCredential{},
+		&models.WebAuthnChallenge{},
 	); err != nil {
 		return nil, fmt.Errorf("failed to auto-migrate models: %w", err)
 	}
@@ -83,6 +100,29 @@ func NewDatabase(cfg *config.Config) (*Database, error) {
 	return &Database{db}, nil
 }
 
+// renameIdempotencyKeyColumn renames the idempotency_keys.key column, a
+// reserved word in MySQL, to idempotency_key. The unique index is named after
+// the Key field and follows the column, so it needs no change.
+func renameIdempotencyKeyColumn(db *gorm.DB) error {
+	migrator := db.Migrator()
+	model := &models.IdempotencyKey{}
+	if !migrator.HasTable(model) {
+		return nil
+	}
+	// HasColumn matches "key" against the table SQL on sqlite, which also
+	// hits PRIMARY KEY, so look the column up by name
+	columns, err := migrator.ColumnTypes(model)
+	if err != nil {
+		return err
+	}
+	for _, column := range columns {
+		if column.Name() == "key" {
+			return migrator.RenameColumn(model, "key", "idempotency_key")
+		}
+	}
+	return nil
+}
+
 func (d *Database) Close() error {
 	sqlDB, err := d.DB.DB()
 	if err != nil {
@@ -0,0 +1,561 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go-vibe-friend/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+)
+
+// DatabaseQueueService implements JobQueue on the queue_jobs table so jobs
+// keep flowing without Redis.
+//
+// Rows move between the ready, inflight and dead states. Ready rows with a
+// future run_at are delayed jobs; inflight rows use run_at as their lease
+// deadline. On Postgres workers claim rows with SELECT ... FOR UPDATE SKIP
+// LOCKED; SQLite has no row locks, so claims are serialized in-process and
+// made safe across processes with a conditional update on the row state.
+type DatabaseQueueService struct {
+	db         *Database
+	skipLocked bool
+
+	// claimMu serializes claims when the database cannot skip locked rows
+	claimMu sync.Mutex
+}
+
+// NewDatabaseQueueService creates a database-backed job queue
+func NewDatabaseQueueService(db *Database) *DatabaseQueueService {
+	return &DatabaseQueueService{
+		db:         db,
+		skipLocked: db.Dialector.Name() == "postgres",
+	}
+}
+
+// dequeuePollInterval is how often DequeueBlocking checks for new jobs
+const dequeuePollInterval = 250 * time.Millisecond
+
+// claimAttempts bounds retries when another process claims the same row
+const claimAttempts = 3
+
+// poll returns a session without SQL logging for the frequent polling queries
+func (d *DatabaseQueueService) poll() *gorm.DB {
+	return d.db.DB.Session(&gorm.Session{Logger: d.db.DB.Logger.LogMode(logger.Silent)})
+}
+
+func decodeQueueRow(row *models.QueueJob) (*QueuedJob, error) {
+	var job QueuedJob
+	if err := json.Unmarshal([]byte(row.Data), &job); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal job: %w", err)
+	}
+	job.rowID = row.ID
+	return &job, nil
+}
+
+func decodeDeadRow(row *models.QueueJob) (*DeadJob, error) {
+	job, err := decodeQueueRow(row)
+	if err != nil {
+		return nil, err
+	}
+	return &DeadJob{
+		Job:       *job,
+		Queue:     row.Queue,
+		LastError: job.LastError,
+		FailedAt:  row.RunAt,
+	}, nil
+}
+
+func (d *DatabaseQueueService) insert(queueName, state string, job QueuedJob, runAt time.Time) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %w", err)
+	}
+
+	return d.db.DB.Create(&models.QueueJob{
+		Queue:    queueName,
+		State:    state,
+		Priority: job.Priority,
+		RunAt:    runAt,
+		JobKey:   job.ID,
+		UserID:   job.UserID,
+		JobType:  job.Type,
+		Data:     string(data),
+	}).Error
+}
+
+// Enqueue adds a job to the queue; jobs with a future RunAt are not claimed
+// before they are due
+func (d *DatabaseQueueService) Enqueue(queueName string, job QueuedJob) error {
+	applyEnqueueDefaults(&job)
+
+	runAt := time.Now()
+	if job.RunAt.After(runAt) {
+		runAt = job.RunAt
+	}
+	return d.insert(queueName, models.QueueJobStateReady, job, runAt)
+}
+
+// claim moves the next due ready row not excluded by filter to inflight with
+// the given lease deadline
+func (d *DatabaseQueueService) claim(queueName string, deadline time.Time, filter ReserveFilter) (*models.QueueJob, error) {
+	pick := func(tx *gorm.DB) *gorm.DB {
+		tx = tx.Where("queue = ? AND state = ? AND run_at <= ?", queueName, models.QueueJobStateReady, time.Now())
+		if len(filter.SkipUsers) > 0 {
+			tx = tx.Where("user_id NOT IN ?", filter.SkipUsers)
+		}
+		if len(filter.SkipTypes) > 0 {
+			tx = tx.Where("job_type NOT IN ?", filter.SkipTypes)
+		}
+		return tx.Order("priority DESC, run_at ASC, id ASC").Limit(1)
+	}
+	lease := map[string]interface{}{
+		"state":  models.QueueJobStateInflight,
+		"run_at": deadline,
+	}
+
+	if d.skipLocked {
+		var row models.QueueJob
+		err := d.poll().Transaction(func(tx *gorm.DB) error {
+			err := pick(tx).Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).Take(&row).Error
+			if err != nil {
+				return err
+			}
+			return tx.Model(&row).Updates(lease).Error
+		})
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrQueueEmpty
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &row, nil
+	}
+
+	d.claimMu.Lock()
+	defer d.claimMu.Unlock()
+
+	for i := 0; i < claimAttempts; i++ {
+		var row models.QueueJob
+		err := pick(d.poll()).Take(&row).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrQueueEmpty
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		result := d.poll().Model(&models.QueueJob{}).
+			Where("id = ? AND state = ?", row.ID, models.QueueJobStateReady).
+			Updates(lease)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 1 {
+			row.State = models.QueueJobStateInflight
+			row.RunAt = deadline
+			return &row, nil
+		}
+		// Claimed by another process sharing the database, try the next row
+	}
+
+	return nil, ErrQueueEmpty
+}
+
+// Dequeue removes and returns the next due job without leasing it
+func (d *DatabaseQueueService) Dequeue(queueName string) (*QueuedJob, error) {
+	row, err := d.claim(queueName, time.Now(), ReserveFilter{})
+	if err != nil {
+		return nil, err
+	}
+	if err := d.db.DB.Delete(&models.QueueJob{}, row.ID).Error; err != nil {
+		return nil, err
+	}
+	return decodeQueueRow(row)
+}
+
+// DequeueBlocking polls for the next job until timeout
+func (d *DatabaseQueueService) DequeueBlocking(queueName string, timeout time.Duration) (*QueuedJob, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		job, err := d.Dequeue(queueName)
+		if !errors.Is(err, ErrQueueEmpty) {
+			return job, err
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return nil, ErrQueueEmpty
+		}
+		if remaining > dequeuePollInterval {
+			remaining = dequeuePollInterval
+		}
+		time.Sleep(remaining)
+	}
+}
+
+// RequeueJob schedules a retry of a failed job, or dead-letters it and
+// returns ErrMaxRetriesExceeded when it has no retries left
+func (d *DatabaseQueueService) RequeueJob(queueName string, job QueuedJob) error {
+	if !recordFailure(&job) {
+		if err := d.MoveToDeadLetter(queueName, job); err != nil {
+			return fmt.Errorf("failed to dead-letter job %s: %w", job.ID, err)
+		}
+		return fmt.Errorf("job %s (%d retries): %w", job.ID, job.MaxRetries, ErrMaxRetriesExceeded)
+	}
+
+	return d.Enqueue(queueName, job)
+}
+
+// RemoveQueuedJob removes a waiting job by its queue ID
+func (d *DatabaseQueueService) RemoveQueuedJob(queueName, id string) (bool, error) {
+	result := d.db.DB.Where("queue = ? AND state = ? AND job_key = ?", queueName, models.QueueJobStateReady, id).
+		Delete(&models.QueueJob{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// PromoteDue is a no-op: delayed rows are claimed directly once due
+func (d *DatabaseQueueService) PromoteDue(queueName string, limit int64) (int, error) {
+	return 0, nil
+}
+
+// Reserve claims the next due job not excluded by filter and leases it for
+// the given duration
+func (d *DatabaseQueueService) Reserve(queueName string, lease time.Duration, filter ReserveFilter) (*QueuedJob, error) {
+	row, err := d.claim(queueName, time.Now().Add(lease), filter)
+	if err != nil {
+		return nil, err
+	}
+	return decodeQueueRow(row)
+}
+
+// Ack removes a finished job
+func (d *DatabaseQueueService) Ack(queueName string, job *QueuedJob) error {
+	result := d.db.DB.Where("id = ? AND state = ?", job.rowID, models.QueueJobStateInflight).
+		Delete(&models.QueueJob{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// Nack releases a failed job and requeues it with an incremented retry count
+func (d *DatabaseQueueService) Nack(queueName string, job *QueuedJob) error {
+	if err := d.Ack(queueName, job); err != nil {
+		return err
+	}
+	return d.RequeueJob(queueName, *job)
+}
+
+// Release returns a leased job to the queue without counting an attempt.
+// Like Enqueue, a future RunAt (e.g. the delay after hitting a concurrency
+// limit) keeps the job from being claimed before it is due.
+func (d *DatabaseQueueService) Release(queueName string, job *QueuedJob) error {
+	runAt := time.Now()
+	if job.RunAt.After(runAt) {
+		runAt = job.RunAt
+	}
+	return d.updateInflight(job, map[string]interface{}{
+		"state":  models.QueueJobStateReady,
+		"run_at": runAt,
+	})
+}
+
+// ExtendLease pushes the lease deadline of an in-flight job forward
+func (d *DatabaseQueueService) ExtendLease(queueName string, job *QueuedJob, lease time.Duration) error {
+	return d.updateInflight(job, map[string]interface{}{
+		"run_at": time.Now().Add(lease),
+	})
+}
+
+func (d *DatabaseQueueService) updateInflight(job *QueuedJob, updates map[string]interface{}) error {
+	result := d.poll().Model(&models.QueueJob{}).
+		Where("id = ? AND state = ?", job.rowID, models.QueueJobStateInflight).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// ReapExpired returns jobs with expired leases to the queue, see
+// RedisQueueService.ReapExpired
+func (d *DatabaseQueueService) ReapExpired(queueName string, onExpired func(job QueuedJob, exhausted bool)) (int, error) {
+	var rows []models.QueueJob
+	err := d.poll().Where("queue = ? AND state = ? AND run_at <= ?", queueName, models.QueueJobStateInflight, time.Now()).
+		Find(&rows).Error
+	if err != nil {
+		return 0, err
+	}
+
+	reaped := 0
+	for i := range rows {
+		// Only the reaper that deletes the row may requeue it
+		result := d.db.DB.Where("id = ? AND state = ?", rows[i].ID, models.QueueJobStateInflight).
+			Delete(&models.QueueJob{})
+		if result.Error != nil {
+			return reaped, result.Error
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+		reaped++
+
+		job, err := decodeQueueRow(&rows[i])
+		if err != nil {
+			continue // Skip malformed jobs
+		}
+		job.LastError = "job lease expired"
+
+		exhausted := job.RetryCount >= job.MaxRetries
+		if onExpired != nil {
+			onExpired(*job, exhausted)
+		}
+
+		if err := d.RequeueJob(queueName, *job); err != nil && !errors.Is(err, ErrMaxRetriesExceeded) {
+			return reaped, err
+		}
+	}
+
+	return reaped, nil
+}
+
+// MoveToDeadLetter parks a job in the dead-letter state
+func (d *DatabaseQueueService) MoveToDeadLetter(queueName string, job QueuedJob) error {
+	return d.insert(queueName, models.QueueJobStateDead, job, time.Now())
+}
+
+func (d *DatabaseQueueService) deadJobs(queueName string) *gorm.DB {
+	return d.db.DB.Where("queue = ? AND state = ?", queueName, models.QueueJobStateDead)
+}
+
+// GetDeadJobs returns dead-lettered jobs, most recent failure first
+func (d *DatabaseQueueService) GetDeadJobs(queueName string, start, stop int64) ([]DeadJob, error) {
+	query := d.deadJobs(queueName).Order("run_at DESC, id DESC").Offset(int(start))
+	if stop >= 0 {
+		query = query.Limit(int(stop - start + 1))
+	}
+
+	var rows []models.QueueJob
+	if err := query.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	jobs := make([]DeadJob, 0, len(rows))
+	for i := range rows {
+		dead, err := decodeDeadRow(&rows[i])
+		if err != nil {
+			continue // Skip malformed jobs
+		}
+		jobs = append(jobs, *dead)
+	}
+	return jobs, nil
+}
+
+// GetDeadLetterLength returns the number of dead-lettered jobs for the queue
+func (d *DatabaseQueueService) GetDeadLetterLength(queueName string) (int64, error) {
+	var count int64
+	err := d.deadJobs(queueName).Model(&models.QueueJob{}).Count(&count).Error
+	return count, err
+}
+
+func (d *DatabaseQueueService) getDeadRow(queueName, id string) (*models.QueueJob, error) {
+	var row models.QueueJob
+	err := d.deadJobs(queueName).Where("job_key = ?", id).Order("run_at DESC").Take(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &row, nil
+}
+
+// GetDeadJob finds a dead-lettered job by its queue ID, returning nil if absent
+func (d *DatabaseQueueService) GetDeadJob(queueName, id string) (*DeadJob, error) {
+	row, err := d.getDeadRow(queueName, id)
+	if err != nil || row == nil {
+		return nil, err
+	}
+	return decodeDeadRow(row)
+}
+
+// RemoveDeadJob deletes a dead-lettered job and returns it, or nil if absent
+func (d *DatabaseQueueService) RemoveDeadJob(queueName, id string) (*DeadJob, error) {
+	row, err := d.getDeadRow(queueName, id)
+	if err != nil || row == nil {
+		return nil, err
+	}
+
+	result := d.db.DB.Where("id = ? AND state = ?", row.ID, models.QueueJobStateDead).Delete(&models.QueueJob{})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil // Removed concurrently
+	}
+	return decodeDeadRow(row)
+}
+
+// ReplayDeadJob moves a dead-lettered job back to the queue with a fresh
+// retry budget, keeping its attempt history
+func (d *DatabaseQueueService) ReplayDeadJob(queueName, id string) (*DeadJob, error) {
+	row, err := d.getDeadRow(queueName, id)
+	if err != nil || row == nil {
+		return nil, err
+	}
+	dead, err := decodeDeadRow(row)
+	if err != nil {
+		return nil, err
+	}
+
+	job := dead.Job
+	job.RetryCount = 0
+	job.LastError = ""
+	data, err := json.Marshal(job)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal job: %w", err)
+	}
+
+	result := d.db.DB.Model(&models.QueueJob{}).
+		Where("id = ? AND state = ?", row.ID, models.QueueJobStateDead).
+		Updates(map[string]interface{}{
+			"state":  models.QueueJobStateReady,
+			"run_at": time.Now(),
+			"data":   string(data),
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil // Removed concurrently
+	}
+	return dead, nil
+}
+
+// PurgeDeadLetter deletes all dead-lettered jobs for the queue
+func (d *DatabaseQueueService) PurgeDeadLetter(queueName string) (int64, error) {
+	result := d.deadJobs(queueName).Delete(&models.QueueJob{})
+	return result.RowsAffected, result.Error
+}
+
+// ListQueues returns the names of all queues with rows in the table
+func (d *DatabaseQueueService) ListQueues() ([]string, error) {
+	var names []string
+	err := d.db.DB.Model(&models.QueueJob{}).Distinct("queue").Order("queue").Pluck("queue", &names).Error
+	return names, err
+}
+
+// readyJobs selects waiting rows that are due; delayed selects those that are not
+func (d *DatabaseQueueService) readyJobs(queueName string, delayed bool) *gorm.DB {
+	op := "<="
+	if delayed {
+		op = ">"
+	}
+	return d.db.DB.Model(&models.QueueJob{}).
+		Where("queue = ? AND state = ? AND run_at "+op+" ?", queueName, models.QueueJobStateReady, time.Now())
+}
+
+func (d *DatabaseQueueService) listRows(query *gorm.DB, start, stop int64) ([]QueuedJob, error) {
+	query = query.Offset(int(start))
+	if stop >= 0 {
+		query = query.Limit(int(stop - start + 1))
+	}
+
+	var rows []models.QueueJob
+	if err := query.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	jobs := make([]QueuedJob, 0, len(rows))
+	for i := range rows {
+		job, err := decodeQueueRow(&rows[i])
+		if err != nil {
+			continue // Skip malformed jobs
+		}
+		jobs = append(jobs, *job)
+	}
+	return jobs, nil
+}
+
+// GetQueueLength returns the number of due jobs waiting to be claimed
+func (d *DatabaseQueueService) GetQueueLength(queueName string) (int64, error) {
+	var count int64
+	err := d.readyJobs(queueName, false).Count(&count).Error
+	return count, err
+}
+
+// GetJobs returns due jobs in the order workers claim them
+func (d *DatabaseQueueService) GetJobs(queueName string, start, stop int64) ([]QueuedJob, error) {
+	return d.listRows(d.readyJobs(queueName, false).Order("priority DESC, run_at ASC, id ASC"), start, stop)
+}
+
+// GetDelayedLength returns the number of jobs that are not due yet
+func (d *DatabaseQueueService) GetDelayedLength(queueName string) (int64, error) {
+	var count int64
+	err := d.readyJobs(queueName, true).Count(&count).Error
+	return count, err
+}
+
+// GetDelayedJobs returns jobs that are not due yet, soonest due first
+func (d *DatabaseQueueService) GetDelayedJobs(queueName string, start, stop int64) ([]QueuedJob, error) {
+	return d.listRows(d.readyJobs(queueName, true).Order("run_at ASC, id ASC"), start, stop)
+}
+
+// GetInflightLength returns the number of leased jobs for the queue
+func (d *DatabaseQueueService) GetInflightLength(queueName string) (int64, error) {
+	var count int64
+	err := d.db.DB.Model(&models.QueueJob{}).
+		Where("queue = ? AND state = ?", queueName, models.QueueJobStateInflight).
+		Count(&count).Error
+	return count, err
+}
+
+// SetQueuedJobPriority changes the priority of a waiting job and returns the
+// updated job, or nil if it is not waiting
+func (d *DatabaseQueueService) SetQueuedJobPriority(queueName, id string, priority int) (*QueuedJob, error) {
+	var row models.QueueJob
+	err := d.db.DB.Where("queue = ? AND state = ? AND job_key = ?", queueName, models.QueueJobStateReady, id).
+		Order("id ASC").Take(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	job, err := decodeQueueRow(&row)
+	if err != nil {
+		return nil, err
+	}
+	job.Priority = priority
+	data, err := json.Marshal(job)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal job: %w", err)
+	}
+
+	result := d.db.DB.Model(&models.QueueJob{}).
+		Where("id = ? AND state = ?", row.ID, models.QueueJobStateReady).
+		Updates(map[string]interface{}{
+			"priority": priority,
+			"data":     string(data),
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil // Claimed or removed concurrently
+	}
+	return job, nil
+}
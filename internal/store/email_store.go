@@ -2,6 +2,7 @@ package store
 
 import (
 	"errors"
+	"time"
 
 	"go-vibe-friend/internal/models"
 
@@ -58,6 +59,41 @@ func (s *EmailStore) GetPasswordResetByToken(token string) (*models.PasswordRese
 	return &reset, err
 }
 
+// ResetPassword 在一个事务中使用重置令牌并更新用户密码，同时作废该用户其他未使用的重置令牌。
+// 令牌已被使用时返回 false，并发请求只有一个能成功
+func (s *EmailStore) ResetPassword(reset *models.PasswordReset, passwordHash string) (bool, error) {
+	used := false
+	err := s.db.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&models.PasswordReset{}).
+			Where("id = ? AND is_used = ?", reset.ID, false).
+			Updates(map[string]interface{}{"is_used": true, "used_at": now})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		result = tx.Model(&models.User{}).Where("id = ?", reset.UserID).Update("password", passwordHash)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		if err := tx.Model(&models.PasswordReset{}).
+			Where("user_id = ? AND is_used = ?", reset.UserID, false).
+			Updates(map[string]interface{}{"is_used": true, "used_at": now}).Error; err != nil {
+			return err
+		}
+		used = true
+		return nil
+	})
+	return used, err
+}
+
 // UpdatePasswordReset 更新密码重置记录
 func (s *EmailStore) UpdatePasswordReset(reset *models.PasswordReset) error {
 	return s.db.DB.Save(reset).Error
@@ -175,14 +211,15 @@ func (s *EmailStore) GetEmailStats() (map[string]interface{}, error) {
 // CleanupExpiredTokens 清理过期的令牌
 func (s *EmailStore) CleanupExpiredTokens() error {
 	// 清理过期的邮箱验证令牌
-	err := s.db.DB.Where("expires_at < ? AND is_verified = ?", "NOW()", false).
+	now := time.Now()
+	err := s.db.DB.Where("expires_at < ? AND is_verified = ?", now, false).
 		Delete(&models.EmailVerification{}).Error
 	if err != nil {
 		return err
 	}
 	
 	// 清理过期的密码重置令牌
-	err = s.db.DB.Where("expires_at < ? AND is_used = ?", "NOW()", false).
+	err = s.db.DB.Where("expires_at < ? AND is_used = ?", now, false).
 		Delete(&models.PasswordReset{}).Error
 	if err != nil {
 		return err
@@ -0,0 +1,103 @@
+package store
+
+import (
+	"sort"
+	"time"
+
+	"go-vibe-friend/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ExportFilter narrows the rows read for a data export. Conditions maps
+// column names, which callers must validate, to the value they must equal.
+type ExportFilter struct {
+	UserID     *uint
+	From       *time.Time // created_at >= From
+	To         *time.Time // created_at < To
+	Conditions map[string]string
+}
+
+// apply adds the filter to query; userColumn is the column UserID is matched against
+func (f ExportFilter) apply(query *gorm.DB, userColumn string) *gorm.DB {
+	if f.UserID != nil {
+		query = query.Where(clause.Eq{Column: clause.Column{Name: userColumn}, Value: *f.UserID})
+	}
+	if f.From != nil {
+		query = query.Where("created_at >= ?", *f.From)
+	}
+	if f.To != nil {
+		query = query.Where("created_at < ?", *f.To)
+	}
+
+	// Sorted so the generated SQL is stable
+	columns := make([]string, 0, len(f.Conditions))
+	for column := range f.Conditions {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	for _, column := range columns {
+		query = query.Where(clause.Eq{Column: clause.Column{Name: column}, Value: f.Conditions[column]})
+	}
+
+	return query
+}
+
+// ExportCursor reads the rows matching an export query in primary key order,
+// one batch at a time, so exports use constant memory whatever the table size
+type ExportCursor[T any] struct {
+	query *gorm.DB
+}
+
+func newExportCursor[T any](query *gorm.DB) ExportCursor[T] {
+	return ExportCursor[T]{query: query.Session(&gorm.Session{})}
+}
+
+// Count returns the number of matching rows
+func (c ExportCursor[T]) Count() (int64, error) {
+	var count int64
+	err := c.query.Count(&count).Error
+	return count, err
+}
+
+// Each calls fn with successive batches of at most batchSize rows. The batch
+// slice is reused between calls and must not be retained; an error returned
+// by fn stops the iteration.
+func (c ExportCursor[T]) Each(batchSize int, fn func(batch []T) error) error {
+	var batch []T
+	return c.query.FindInBatches(&batch, batchSize, func(tx *gorm.DB, _ int) error {
+		return fn(batch)
+	}).Error
+}
+
+// ExportUsers returns a cursor over the users matching the filter; UserID matches the user ID
+func (s *UserStore) ExportUsers(filter ExportFilter) ExportCursor[models.User] {
+	return newExportCursor[models.User](filter.apply(s.db.DB.Model(&models.User{}), "id"))
+}
+
+// ExportJobs returns a cursor over the jobs matching the filter
+func (s *JobStore) ExportJobs(filter ExportFilter) ExportCursor[models.Job] {
+	return newExportCursor[models.Job](filter.apply(s.db.DB.Model(&models.Job{}), "user_id"))
+}
+
+// ExportFiles returns a cursor over the files matching the filter
+func (s *FileStore) ExportFiles(filter ExportFilter) ExportCursor[models.File] {
+	return newExportCursor[models.File](filter.apply(s.db.DB.Model(&models.File{}), "user_id"))
+}
+
+// ExportEmailLogs returns a cursor over the email logs matching the filter
+func (s *EmailStore) ExportEmailLogs(filter ExportFilter) ExportCursor[models.EmailLog] {
+	return newExportCursor[models.EmailLog](filter.apply(s.db.DB.Model(&models.EmailLog{}), "user_id"))
+}
+
+// ExportPermissions returns a cursor over all permissions
+func (s *PermissionStore) ExportPermissions() ExportCursor[models.Permission] {
+	return newExportCursor[models.Permission](s.db.DB.Model(&models.Permission{}))
+}
+
+// ExportAuditLogs returns a cursor over the audit logs matching the filter;
+// UserID matches the user who performed the action
+func (s *UserStore) ExportAuditLogs(filter ExportFilter) ExportCursor[models.AuditLog] {
+	return newExportCursor[models.AuditLog](filter.apply(s.db.DB.Model(&models.AuditLog{}), "actor_id"))
+}
@@ -0,0 +1,62 @@
+package store
+
+import (
+	"errors"
+	"time"
+
+	"go-vibe-friend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// ExportStore keeps the metadata of export files stored in object storage
+type ExportStore struct {
+	db *Database
+}
+
+func NewExportStore(db *Database) *ExportStore {
+	return &ExportStore{db: db}
+}
+
+func (s *ExportStore) CreateExportFile(file *models.ExportFile) error {
+	return s.db.DB.Create(file).Error
+}
+
+func (s *ExportStore) GetExportFileByID(id uint) (*models.ExportFile, error) {
+	var file models.ExportFile
+	err := s.db.DB.First(&file, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &file, err
+}
+
+// ListExportFiles returns export files newest first; a nil userID lists the
+// files of all users. Expired files are included until they are cleaned up.
+func (s *ExportStore) ListExportFiles(userID *uint, limit, offset int) ([]models.ExportFile, int64, error) {
+	query := s.db.DB.Model(&models.ExportFile{})
+	if userID != nil {
+		query = query.Where("user_id = ?", *userID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var files []models.ExportFile
+	err := query.Order("id DESC").Limit(limit).Offset(offset).Find(&files).Error
+	return files, total, err
+}
+
+// GetExpiredExportFiles returns up to limit files that expired before now,
+// oldest first
+func (s *ExportStore) GetExpiredExportFiles(now time.Time, limit int) ([]models.ExportFile, error) {
+	var files []models.ExportFile
+	err := s.db.DB.Where("expires_at < ?", now).Order("expires_at ASC").Limit(limit).Find(&files).Error
+	return files, err
+}
+
+func (s *ExportStore) DeleteExportFile(id uint) error {
+	return s.db.DB.Delete(&models.ExportFile{}, id).Error
+}
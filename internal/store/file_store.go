@@ -55,6 +55,24 @@ func (s *FileStore) GetFilesByUserID(userID uint, category string, limit, offset
 	return files, err
 }
 
+// GetFilesByJobID 获取任务的产物文件
+func (s *FileStore) GetFilesByJobID(jobID uint) ([]models.File, error) {
+	var files []models.File
+	err := s.db.DB.Where("job_id = ? AND status = ?", jobID, "active").
+		Order("created_at ASC").Find(&files).Error
+	return files, err
+}
+
+// GetJobFileByName 根据原始文件名获取任务的产物文件
+func (s *FileStore) GetJobFileByName(jobID uint, name string) (*models.File, error) {
+	var file models.File
+	err := s.db.DB.Where("job_id = ? AND original_name = ?", jobID, name).First(&file).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &file, err
+}
+
 // GetFilesByCategory 根据分类获取文件
 func (s *FileStore) GetFilesByCategory(category string, limit, offset int) ([]models.File, error) {
 	var files []models.File
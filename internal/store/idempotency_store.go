@@ -0,0 +1,125 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"go-vibe-friend/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// IdempotencyRecord is the response stored for an idempotency key
+type IdempotencyRecord struct {
+	Fingerprint string              `json:"fingerprint"`
+	StatusCode  int                 `json:"status_code"` // 0 while the first request is in flight
+	Header      map[string][]string `json:"header,omitempty"`
+	Body        []byte              `json:"body,omitempty"`
+}
+
+// InFlight reports whether the first request for the key has not finished
+func (r *IdempotencyRecord) InFlight() bool {
+	return r.StatusCode == 0
+}
+
+// IdempotencyStore defines common idempotency key operations
+type IdempotencyStore interface {
+	// Reserve claims key for lockTTL. It returns nil if the caller now owns
+	// the key, or the record already stored for it.
+	Reserve(key string, lockTTL time.Duration) (*IdempotencyRecord, error)
+	// Save stores the final response for key, keeping it for ttl
+	Save(key string, record *IdempotencyRecord, ttl time.Duration) error
+	// Release drops a claim so the request can be retried
+	Release(key string) error
+}
+
+// reserveAttempts bounds retries when a key expires or is released while
+// it is being reserved
+const reserveAttempts = 3
+
+// DatabaseIdempotencyStore implements IdempotencyStore using the database
+type DatabaseIdempotencyStore struct {
+	db *Database
+}
+
+// NewDatabaseIdempotencyStore creates a database-backed idempotency store
+func NewDatabaseIdempotencyStore(db *Database) *DatabaseIdempotencyStore {
+	return &DatabaseIdempotencyStore{db: db}
+}
+
+// Reserve claims key by inserting an in-flight row, replacing an expired one
+func (s *DatabaseIdempotencyStore) Reserve(key string, lockTTL time.Duration) (*IdempotencyRecord, error) {
+	for attempt := 0; attempt < reserveAttempts; attempt++ {
+		now := time.Now()
+		if err := s.db.DB.Where("idempotency_key = ? AND expires_at <= ?", key, now).Delete(&models.IdempotencyKey{}).Error; err != nil {
+			return nil, err
+		}
+
+		row := models.IdempotencyKey{Key: key, ExpiresAt: now.Add(lockTTL)}
+		result := s.db.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&row)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected > 0 {
+			return nil, nil
+		}
+
+		var existing models.IdempotencyKey
+		err := s.db.DB.Where("idempotency_key = ?", key).First(&existing).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue // Released in the meantime, try to claim it again
+		}
+		if err != nil {
+			return nil, err
+		}
+		return idempotencyRecordFromRow(&existing)
+	}
+	return nil, errors.New("failed to reserve idempotency key")
+}
+
+// Save stores the final response, creating the row if the claim was lost
+func (s *DatabaseIdempotencyStore) Save(key string, record *IdempotencyRecord, ttl time.Duration) error {
+	headers, err := json.Marshal(record.Header)
+	if err != nil {
+		return err
+	}
+
+	row := models.IdempotencyKey{
+		Key:         key,
+		Fingerprint: record.Fingerprint,
+		StatusCode:  record.StatusCode,
+		Headers:     string(headers),
+		Body:        record.Body,
+		ExpiresAt:   time.Now().Add(ttl),
+	}
+	return s.db.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "idempotency_key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"fingerprint", "status_code", "headers", "body", "expires_at"}),
+	}).Create(&row).Error
+}
+
+// Release deletes the row for key
+func (s *DatabaseIdempotencyStore) Release(key string) error {
+	return s.db.DB.Where("idempotency_key = ?", key).Delete(&models.IdempotencyKey{}).Error
+}
+
+// CleanupExpired deletes expired idempotency keys
+func (s *DatabaseIdempotencyStore) CleanupExpired() error {
+	return s.db.DB.Where("expires_at <= ?", time.Now()).Delete(&models.IdempotencyKey{}).Error
+}
+
+func idempotencyRecordFromRow(row *models.IdempotencyKey) (*IdempotencyRecord, error) {
+	record := &IdempotencyRecord{
+		Fingerprint: row.Fingerprint,
+		StatusCode:  row.StatusCode,
+		Body:        row.Body,
+	}
+	if row.Headers != "" {
+		if err := json.Unmarshal([]byte(row.Headers), &record.Header); err != nil {
+			return nil, err
+		}
+	}
+	return record, nil
+}
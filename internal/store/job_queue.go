@@ -0,0 +1,80 @@
+package store
+
+import "time"
+
+// JobQueue is the job queue used by the job service and worker pool. It is
+// implemented by RedisQueueService, DatabaseQueueService and
+// FailoverJobQueue, which prefers Redis and falls back to the database.
+type JobQueue interface {
+	// Enqueue adds a job; jobs with a future RunAt wait until they are due
+	Enqueue(queueName string, job QueuedJob) error
+	// DequeueBlocking pops the next job, waiting up to timeout
+	DequeueBlocking(queueName string, timeout time.Duration) (*QueuedJob, error)
+	// RequeueJob schedules a retry of a failed, popped job
+	RequeueJob(queueName string, job QueuedJob) error
+	// RemoveQueuedJob removes a waiting job by its queue ID
+	RemoveQueuedJob(queueName, id string) (bool, error)
+	// PromoteDue makes due delayed jobs available to workers
+	PromoteDue(queueName string, limit int64) (int, error)
+
+	// Reliable delivery
+	Reserve(queueName string, lease time.Duration, filter ReserveFilter) (*QueuedJob, error)
+	Ack(queueName string, job *QueuedJob) error
+	Nack(queueName string, job *QueuedJob) error
+	Release(queueName string, job *QueuedJob) error
+	ExtendLease(queueName string, job *QueuedJob, lease time.Duration) error
+	ReapExpired(queueName string, onExpired func(job QueuedJob, exhausted bool)) (int, error)
+
+	// Dead-letter queue
+	MoveToDeadLetter(queueName string, job QueuedJob) error
+	GetDeadJobs(queueName string, start, stop int64) ([]DeadJob, error)
+	GetDeadLetterLength(queueName string) (int64, error)
+	GetDeadJob(queueName, id string) (*DeadJob, error)
+	RemoveDeadJob(queueName, id string) (*DeadJob, error)
+	ReplayDeadJob(queueName, id string) (*DeadJob, error)
+	PurgeDeadLetter(queueName string) (int64, error)
+
+	// Inspection
+	ListQueues() ([]string, error)
+	GetQueueLength(queueName string) (int64, error)
+	GetJobs(queueName string, start, stop int64) ([]QueuedJob, error)
+	GetDelayedLength(queueName string) (int64, error)
+	GetDelayedJobs(queueName string, start, stop int64) ([]QueuedJob, error)
+	GetInflightLength(queueName string) (int64, error)
+	// SetQueuedJobPriority changes the priority of a waiting job and returns
+	// it, or nil if no waiting job has the ID
+	SetQueuedJobPriority(queueName, id string, priority int) (*QueuedJob, error)
+}
+
+// ReserveFilter lists the users and job types whose jobs Reserve must skip,
+// usually because they reached their concurrency limit
+type ReserveFilter struct {
+	SkipUsers []uint
+	SkipTypes []string
+}
+
+// IsEmpty reports whether the filter skips nothing
+func (f ReserveFilter) IsEmpty() bool {
+	return len(f.SkipUsers) == 0 && len(f.SkipTypes) == 0
+}
+
+// Skips reports whether the filter excludes job
+func (f ReserveFilter) Skips(job *QueuedJob) bool {
+	for _, userID := range f.SkipUsers {
+		if job.UserID == userID {
+			return true
+		}
+	}
+	for _, jobType := range f.SkipTypes {
+		if job.Type == jobType {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	_ JobQueue = (*RedisQueueService)(nil)
+	_ JobQueue = (*DatabaseQueueService)(nil)
+	_ JobQueue = (*FailoverJobQueue)(nil)
+)
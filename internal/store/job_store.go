@@ -20,6 +20,22 @@ func (s *JobStore) CreateJob(job *models.Job) error {
 	return s.db.DB.Create(job).Error
 }
 
+// CreateJobWithDependencies creates a job and records the jobs it depends on
+// in a single transaction
+func (s *JobStore) CreateJobWithDependencies(job *models.Job, parentIDs []uint) error {
+	return s.db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(job).Error; err != nil {
+			return err
+		}
+		for _, parentID := range parentIDs {
+			if err := tx.Create(&models.JobDependency{JobID: job.ID, ParentID: parentID}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
 func (s *JobStore) GetJobByID(id uint) (*models.Job, error) {
 	var job models.Job
 	err := s.db.DB.First(&job, id).Error
@@ -29,10 +45,119 @@ func (s *JobStore) GetJobByID(id uint) (*models.Job, error) {
 	return &job, err
 }
 
+// GetJobsByIDs returns the jobs with the given IDs; missing IDs are skipped
+func (s *JobStore) GetJobsByIDs(ids []uint) ([]models.Job, error) {
+	var jobs []models.Job
+	if len(ids) == 0 {
+		return jobs, nil
+	}
+	err := s.db.DB.Where("id IN ?", ids).Order("id").Find(&jobs).Error
+	return jobs, err
+}
+
+// GetParentJobs returns the jobs that jobID depends on
+func (s *JobStore) GetParentJobs(jobID uint) ([]models.Job, error) {
+	var jobs []models.Job
+	err := s.db.DB.
+		Joins("JOIN job_dependencies ON job_dependencies.parent_id = jobs.id").
+		Where("job_dependencies.job_id = ?", jobID).
+		Order("jobs.id").
+		Find(&jobs).Error
+	return jobs, err
+}
+
+// GetDependentJobs returns the jobs that depend on parentID
+func (s *JobStore) GetDependentJobs(parentID uint) ([]models.Job, error) {
+	var jobs []models.Job
+	err := s.db.DB.
+		Joins("JOIN job_dependencies ON job_dependencies.job_id = jobs.id").
+		Where("job_dependencies.parent_id = ?", parentID).
+		Order("jobs.id").
+		Find(&jobs).Error
+	return jobs, err
+}
+
+// GetDependencies returns every dependency edge touching one of the jobs
+func (s *JobStore) GetDependencies(jobIDs []uint) ([]models.JobDependency, error) {
+	var deps []models.JobDependency
+	if len(jobIDs) == 0 {
+		return deps, nil
+	}
+	err := s.db.DB.Where("job_id IN ? OR parent_id IN ?", jobIDs, jobIDs).Find(&deps).Error
+	return deps, err
+}
+
 func (s *JobStore) UpdateJob(job *models.Job) error {
 	return s.db.DB.Save(job).Error
 }
 
+// UpdateJobIfStatus saves the job only while its stored status is one of
+// statuses, reporting whether it was saved
+func (s *JobStore) UpdateJobIfStatus(job *models.Job, statuses ...string) (bool, error) {
+	result := s.db.DB.Model(job).Where("status IN ?", statuses).Select("*").Updates(job)
+	return result.RowsAffected > 0, result.Error
+}
+
+// Advisory lock namespaces that serialise job starts per user and per job
+// type on PostgreSQL
+const (
+	jobLimitLockUser int32 = 0x6a6c7501
+	jobLimitLockType int32 = 0x6a6c7402
+)
+
+// StartJobWithinLimits saves a pending or running job only while fewer than
+// userLimit other jobs of the same user and fewer than typeLimit other jobs of
+// the same type are running; a negative limit is not checked.
+//
+// Under READ COMMITTED the count cannot see running rows other workers have
+// not committed yet, so on PostgreSQL starts for the same user and type are
+// serialised with transaction-scoped advisory locks, always taken user first.
+// SQLite allows only one writer at a time and needs no lock.
+func (s *JobStore) StartJobWithinLimits(job *models.Job, userLimit, typeLimit int) (bool, error) {
+	started := false
+	err := s.db.DB.Transaction(func(tx *gorm.DB) error {
+		if tx.Dialector.Name() == "postgres" {
+			if userLimit >= 0 {
+				if err := tx.Exec("SELECT pg_advisory_xact_lock(?, ?)", jobLimitLockUser, int32(job.UserID)).Error; err != nil {
+					return err
+				}
+			}
+			if typeLimit >= 0 {
+				if err := tx.Exec("SELECT pg_advisory_xact_lock(?, hashtext(?))", jobLimitLockType, job.JobType).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		query := tx.Model(job).Where("status IN ?", []string{models.JobStatusPending, models.JobStatusRunning})
+		if userLimit >= 0 {
+			query = query.Where("(SELECT COUNT(*) FROM jobs AS other WHERE other.user_id = ? AND other.status = ? AND other.id <> ? AND other.deleted_at IS NULL) < ?",
+				job.UserID, models.JobStatusRunning, job.ID, userLimit)
+		}
+		if typeLimit >= 0 {
+			query = query.Where("(SELECT COUNT(*) FROM jobs AS other WHERE other.job_type = ? AND other.status = ? AND other.id <> ? AND other.deleted_at IS NULL) < ?",
+				job.JobType, models.JobStatusRunning, job.ID, typeLimit)
+		}
+		result := query.Select("*").Updates(job)
+		started = result.RowsAffected > 0
+		return result.Error
+	})
+	return started, err
+}
+
+// UpdateJobProgress updates the progress of a running job
+func (s *JobStore) UpdateJobProgress(id uint, progress int) (bool, error) {
+	result := s.db.DB.Model(&models.Job{}).
+		Where("id = ? AND status = ?", id, models.JobStatusRunning).
+		Update("progress", progress)
+	return result.RowsAffected > 0, result.Error
+}
+
+// UpdateJobPriority updates the priority of a job
+func (s *JobStore) UpdateJobPriority(id uint, priority int) error {
+	return s.db.DB.Model(&models.Job{}).Where("id = ?", id).Update("priority", priority).Error
+}
+
 func (s *JobStore) DeleteJob(id uint) error {
 	return s.db.DB.Delete(&models.Job{}, id).Error
 }
@@ -49,6 +174,19 @@ func (s *JobStore) GetJobsByUserID(userID uint, limit, offset int) ([]models.Job
 	return jobs, err
 }
 
+// GetActiveUserJob returns the user's most recent job of the given type that
+// has not finished yet, or nil if there is none
+func (s *JobStore) GetActiveUserJob(userID uint, jobType string) (*models.Job, error) {
+	var job models.Job
+	err := s.db.DB.Where("user_id = ? AND job_type = ? AND status IN ?", userID, jobType,
+		[]string{models.JobStatusWaiting, models.JobStatusPending, models.JobStatusRunning}).
+		Order("id DESC").First(&job).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &job, err
+}
+
 func (s *JobStore) GetJobsByStatus(status string, limit, offset int) ([]models.Job, error) {
 	var jobs []models.Job
 	err := s.db.DB.Where("status = ?", status).Order("created_at DESC").Limit(limit).Offset(offset).Find(&jobs).Error
@@ -82,6 +220,50 @@ func (s *JobStore) GetJobStatusBreakdown() ([]StatusBreakdown, error) {
 	return results, err
 }
 
+// CountRunningByUser returns the number of running jobs per user
+func (s *JobStore) CountRunningByUser() (map[uint]int64, error) {
+	var rows []struct {
+		UserID uint
+		Count  int64
+	}
+	err := s.db.DB.Model(&models.Job{}).
+		Select("user_id, count(*) as count").
+		Where("status = ?", models.JobStatusRunning).
+		Group("user_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[uint]int64, len(rows))
+	for _, row := range rows {
+		counts[row.UserID] = row.Count
+	}
+	return counts, nil
+}
+
+// CountRunningByType returns the number of running jobs per job type
+func (s *JobStore) CountRunningByType() (map[string]int64, error) {
+	var rows []struct {
+		JobType string
+		Count   int64
+	}
+	err := s.db.DB.Model(&models.Job{}).
+		Select("job_type, count(*) as count").
+		Where("status = ?", models.JobStatusRunning).
+		Group("job_type").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.JobType] = row.Count
+	}
+	return counts, nil
+}
+
 type StatusBreakdown struct {
 	Status string `json:"status"`
 	Count  int64  `json:"count"`
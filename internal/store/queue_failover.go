@@ -0,0 +1,391 @@
+package store
+
+import (
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// FailoverJobQueue uses Redis while it is healthy and the database queue
+// otherwise. Workers drain both backends, so jobs enqueued during an outage
+// are still processed after Redis recovers, and jobs left in Redis are picked
+// up again once it is back. Acknowledgements are routed to the backend that
+// delivered the job.
+type FailoverJobQueue struct {
+	redis    *RedisQueueService // nil when Redis is not configured
+	client   *RedisClient
+	database *DatabaseQueueService
+
+	mu        sync.Mutex
+	healthy   bool
+	checkedAt time.Time
+}
+
+// redisHealthTTL is how long a Redis health check result is reused
+const redisHealthTTL = 5 * time.Second
+
+// NewFailoverJobQueue creates a queue that falls back to the database when
+// Redis is unavailable; redis and client may be nil
+func NewFailoverJobQueue(redis *RedisQueueService, client *RedisClient, database *DatabaseQueueService) *FailoverJobQueue {
+	return &FailoverJobQueue{
+		redis:    redis,
+		client:   client,
+		database: database,
+	}
+}
+
+// redisAvailable reports whether Redis should be used, re-checking its
+// health at most every redisHealthTTL
+func (f *FailoverJobQueue) redisAvailable() bool {
+	if f.redis == nil {
+		return false
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if time.Since(f.checkedAt) < redisHealthTTL {
+		return f.healthy
+	}
+
+	healthy := f.client.HealthCheck() == nil
+	if healthy != f.healthy && !f.checkedAt.IsZero() {
+		if healthy {
+			log.Println("Job queue: Redis is available again, switching back from database queue")
+		} else {
+			log.Println("Job queue: Redis is unavailable, falling back to database queue")
+		}
+	}
+	f.healthy = healthy
+	f.checkedAt = time.Now()
+	return healthy
+}
+
+// markUnhealthy switches to the database until the next health check
+func (f *FailoverJobQueue) markUnhealthy(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.healthy {
+		log.Printf("Job queue: Redis error, falling back to database queue: %v", err)
+	}
+	f.healthy = false
+	f.checkedAt = time.Now()
+}
+
+// backends returns the queues to drain, Redis first
+func (f *FailoverJobQueue) backends() []JobQueue {
+	if f.redisAvailable() {
+		return []JobQueue{f.redis, f.database}
+	}
+	return []JobQueue{f.database}
+}
+
+// write runs op against Redis if available, falling back to the database
+func (f *FailoverJobQueue) write(op func(q JobQueue) error) error {
+	if f.redisAvailable() {
+		err := op(f.redis)
+		if err == nil || errors.Is(err, ErrMaxRetriesExceeded) {
+			return err
+		}
+		f.markUnhealthy(err)
+	}
+	return op(f.database)
+}
+
+// origin returns the backend that delivered the job
+func (f *FailoverJobQueue) origin(job *QueuedJob) JobQueue {
+	if job.origin != nil {
+		return job.origin
+	}
+	if job.rowID != 0 || f.redis == nil {
+		return f.database
+	}
+	return f.redis
+}
+
+// Enqueue adds a job to Redis, or to the database when Redis is unavailable
+func (f *FailoverJobQueue) Enqueue(queueName string, job QueuedJob) error {
+	return f.write(func(q JobQueue) error { return q.Enqueue(queueName, job) })
+}
+
+// RequeueJob schedules a retry on the currently preferred backend
+func (f *FailoverJobQueue) RequeueJob(queueName string, job QueuedJob) error {
+	return f.write(func(q JobQueue) error { return q.RequeueJob(queueName, job) })
+}
+
+// MoveToDeadLetter parks a job on the currently preferred backend
+func (f *FailoverJobQueue) MoveToDeadLetter(queueName string, job QueuedJob) error {
+	return f.write(func(q JobQueue) error { return q.MoveToDeadLetter(queueName, job) })
+}
+
+// DequeueBlocking drains jobs left in the database before waiting on Redis
+func (f *FailoverJobQueue) DequeueBlocking(queueName string, timeout time.Duration) (*QueuedJob, error) {
+	if !f.redisAvailable() {
+		return f.database.DequeueBlocking(queueName, timeout)
+	}
+
+	job, err := f.database.Dequeue(queueName)
+	if !errors.Is(err, ErrQueueEmpty) {
+		return job, err
+	}
+
+	job, err = f.redis.DequeueBlocking(queueName, timeout)
+	if err != nil && !errors.Is(err, ErrQueueEmpty) {
+		f.markUnhealthy(err)
+	}
+	return job, err
+}
+
+// Reserve leases the next job from Redis, then from the database
+func (f *FailoverJobQueue) Reserve(queueName string, lease time.Duration, filter ReserveFilter) (*QueuedJob, error) {
+	for _, q := range f.backends() {
+		job, err := q.Reserve(queueName, lease, filter)
+		if err == nil {
+			job.origin = q
+			return job, nil
+		}
+		if errors.Is(err, ErrQueueEmpty) {
+			continue
+		}
+		if q == JobQueue(f.redis) {
+			f.markUnhealthy(err)
+			continue
+		}
+		return nil, err
+	}
+	return nil, ErrQueueEmpty
+}
+
+// Ack removes a finished job from the backend that delivered it
+func (f *FailoverJobQueue) Ack(queueName string, job *QueuedJob) error {
+	return f.origin(job).Ack(queueName, job)
+}
+
+// Nack releases a failed job from its backend and schedules a retry
+func (f *FailoverJobQueue) Nack(queueName string, job *QueuedJob) error {
+	if err := f.Ack(queueName, job); err != nil {
+		return err
+	}
+	return f.RequeueJob(queueName, *job)
+}
+
+// Release returns a leased job to the backend that delivered it
+func (f *FailoverJobQueue) Release(queueName string, job *QueuedJob) error {
+	return f.origin(job).Release(queueName, job)
+}
+
+// ExtendLease extends the lease on the backend that delivered the job
+func (f *FailoverJobQueue) ExtendLease(queueName string, job *QueuedJob, lease time.Duration) error {
+	return f.origin(job).ExtendLease(queueName, job, lease)
+}
+
+// ReapExpired reaps expired leases on every available backend
+func (f *FailoverJobQueue) ReapExpired(queueName string, onExpired func(job QueuedJob, exhausted bool)) (int, error) {
+	total := 0
+	var firstErr error
+	for _, q := range f.backends() {
+		reaped, err := q.ReapExpired(queueName, onExpired)
+		total += reaped
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return total, firstErr
+}
+
+// PromoteDue promotes due delayed jobs on every available backend
+func (f *FailoverJobQueue) PromoteDue(queueName string, limit int64) (int, error) {
+	total := 0
+	for _, q := range f.backends() {
+		promoted, err := q.PromoteDue(queueName, limit)
+		total += promoted
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// RemoveQueuedJob removes a waiting job from whichever backend holds it
+func (f *FailoverJobQueue) RemoveQueuedJob(queueName, id string) (bool, error) {
+	for _, q := range f.backends() {
+		removed, err := q.RemoveQueuedJob(queueName, id)
+		if err != nil || removed {
+			return removed, err
+		}
+	}
+	return false, nil
+}
+
+// GetDeadJobs pages over the dead-letter queues of all available backends,
+// Redis entries first
+func (f *FailoverJobQueue) GetDeadJobs(queueName string, start, stop int64) ([]DeadJob, error) {
+	return pageBackends(f.backends(), start, stop,
+		func(q JobQueue) (int64, error) { return q.GetDeadLetterLength(queueName) },
+		func(q JobQueue, start, stop int64) ([]DeadJob, error) { return q.GetDeadJobs(queueName, start, stop) },
+	)
+}
+
+// pageBackends pages over a listing split across backends as if it were one
+// list, each backend continuing where the previous one ended
+func pageBackends[T any](backends []JobQueue, start, stop int64,
+	length func(q JobQueue) (int64, error),
+	page func(q JobQueue, start, stop int64) ([]T, error),
+) ([]T, error) {
+	var items []T
+	unbounded := stop < 0
+	for _, q := range backends {
+		if !unbounded && stop < start {
+			break
+		}
+
+		count, err := length(q)
+		if err != nil {
+			return nil, err
+		}
+		if start >= count {
+			start -= count
+			if !unbounded {
+				stop -= count
+			}
+			continue
+		}
+
+		part, err := page(q, start, stop)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, part...)
+
+		// The next backend continues where this one ended
+		if !unbounded {
+			stop -= count
+		}
+		start = 0
+	}
+	return items, nil
+}
+
+// GetDeadLetterLength counts dead-lettered jobs on all available backends
+func (f *FailoverJobQueue) GetDeadLetterLength(queueName string) (int64, error) {
+	return f.sumLengths(func(q JobQueue) (int64, error) { return q.GetDeadLetterLength(queueName) })
+}
+
+// GetDeadJob finds a dead-lettered job on any available backend
+func (f *FailoverJobQueue) GetDeadJob(queueName, id string) (*DeadJob, error) {
+	return f.findDead(func(q JobQueue) (*DeadJob, error) { return q.GetDeadJob(queueName, id) })
+}
+
+// RemoveDeadJob deletes a dead-lettered job from the backend holding it
+func (f *FailoverJobQueue) RemoveDeadJob(queueName, id string) (*DeadJob, error) {
+	return f.findDead(func(q JobQueue) (*DeadJob, error) { return q.RemoveDeadJob(queueName, id) })
+}
+
+// ReplayDeadJob requeues a dead-lettered job on the backend holding it
+func (f *FailoverJobQueue) ReplayDeadJob(queueName, id string) (*DeadJob, error) {
+	return f.findDead(func(q JobQueue) (*DeadJob, error) { return q.ReplayDeadJob(queueName, id) })
+}
+
+func (f *FailoverJobQueue) findDead(op func(q JobQueue) (*DeadJob, error)) (*DeadJob, error) {
+	for _, q := range f.backends() {
+		dead, err := op(q)
+		if err != nil || dead != nil {
+			return dead, err
+		}
+	}
+	return nil, nil
+}
+
+// PurgeDeadLetter deletes dead-lettered jobs on all available backends
+func (f *FailoverJobQueue) PurgeDeadLetter(queueName string) (int64, error) {
+	var total int64
+	for _, q := range f.backends() {
+		purged, err := q.PurgeDeadLetter(queueName)
+		if err != nil {
+			return total, err
+		}
+		total += purged
+	}
+	return total, nil
+}
+
+// ListQueues returns the queues known to any available backend
+func (f *FailoverJobQueue) ListQueues() ([]string, error) {
+	seen := make(map[string]bool)
+	for _, q := range f.backends() {
+		names, err := q.ListQueues()
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			seen[name] = true
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// sumLengths adds up a count over all available backends
+func (f *FailoverJobQueue) sumLengths(length func(q JobQueue) (int64, error)) (int64, error) {
+	var total int64
+	for _, q := range f.backends() {
+		count, err := length(q)
+		if err != nil {
+			return 0, err
+		}
+		total += count
+	}
+	return total, nil
+}
+
+// GetQueueLength counts ready jobs on all available backends
+func (f *FailoverJobQueue) GetQueueLength(queueName string) (int64, error) {
+	return f.sumLengths(func(q JobQueue) (int64, error) { return q.GetQueueLength(queueName) })
+}
+
+// GetJobs pages over the ready jobs of all available backends, Redis first
+func (f *FailoverJobQueue) GetJobs(queueName string, start, stop int64) ([]QueuedJob, error) {
+	return pageBackends(f.backends(), start, stop,
+		func(q JobQueue) (int64, error) { return q.GetQueueLength(queueName) },
+		func(q JobQueue, start, stop int64) ([]QueuedJob, error) { return q.GetJobs(queueName, start, stop) },
+	)
+}
+
+// GetDelayedLength counts delayed jobs on all available backends
+func (f *FailoverJobQueue) GetDelayedLength(queueName string) (int64, error) {
+	return f.sumLengths(func(q JobQueue) (int64, error) { return q.GetDelayedLength(queueName) })
+}
+
+// GetDelayedJobs pages over the delayed jobs of all available backends, Redis first
+func (f *FailoverJobQueue) GetDelayedJobs(queueName string, start, stop int64) ([]QueuedJob, error) {
+	return pageBackends(f.backends(), start, stop,
+		func(q JobQueue) (int64, error) { return q.GetDelayedLength(queueName) },
+		func(q JobQueue, start, stop int64) ([]QueuedJob, error) {
+			return q.GetDelayedJobs(queueName, start, stop)
+		},
+	)
+}
+
+// GetInflightLength counts leased jobs on all available backends
+func (f *FailoverJobQueue) GetInflightLength(queueName string) (int64, error) {
+	return f.sumLengths(func(q JobQueue) (int64, error) { return q.GetInflightLength(queueName) })
+}
+
+// SetQueuedJobPriority changes the priority of a waiting job on whichever
+// backend holds it
+func (f *FailoverJobQueue) SetQueuedJobPriority(queueName, id string, priority int) (*QueuedJob, error) {
+	for _, q := range f.backends() {
+		job, err := q.SetQueuedJobPriority(queueName, id, priority)
+		if err != nil || job != nil {
+			return job, err
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,96 @@
+package store
+
+import (
+	"errors"
+	"time"
+
+	"go-vibe-friend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type RecurringJobStore struct {
+	db *Database
+}
+
+func NewRecurringJobStore(db *Database) *RecurringJobStore {
+	return &RecurringJobStore{db: db}
+}
+
+func (s *RecurringJobStore) CreateRecurringJob(job *models.RecurringJob) error {
+	return s.db.DB.Create(job).Error
+}
+
+func (s *RecurringJobStore) GetRecurringJobByID(id uint) (*models.RecurringJob, error) {
+	var job models.RecurringJob
+	err := s.db.DB.First(&job, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &job, err
+}
+
+func (s *RecurringJobStore) GetRecurringJobByName(name string) (*models.RecurringJob, error) {
+	var job models.RecurringJob
+	err := s.db.DB.Where("name = ?", name).First(&job).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &job, err
+}
+
+func (s *RecurringJobStore) UpdateRecurringJob(job *models.RecurringJob) error {
+	return s.db.DB.Save(job).Error
+}
+
+func (s *RecurringJobStore) DeleteRecurringJob(id uint) error {
+	return s.db.DB.Delete(&models.RecurringJob{}, id).Error
+}
+
+func (s *RecurringJobStore) ListRecurringJobs() ([]models.RecurringJob, error) {
+	var jobs []models.RecurringJob
+	err := s.db.DB.Order("name ASC").Find(&jobs).Error
+	return jobs, err
+}
+
+// GetDueRecurringJobs 获取已到执行时间的启用任务
+func (s *RecurringJobStore) GetDueRecurringJobs(now time.Time) ([]models.RecurringJob, error) {
+	var jobs []models.RecurringJob
+	err := s.db.DB.Where("enabled = ? AND next_run_at IS NOT NULL AND next_run_at <= ?", true, now).
+		Order("next_run_at ASC").
+		Find(&jobs).Error
+	return jobs, err
+}
+
+// ClaimRun 以 run_count 作为版本号推进到下一次执行时间，
+// 只有一个调用方能够成功，保证同一时刻只触发一次
+func (s *RecurringJobStore) ClaimRun(job *models.RecurringJob, runAt, nextRunAt time.Time) (bool, error) {
+	result := s.db.DB.Model(&models.RecurringJob{}).
+		Where("id = ? AND run_count = ?", job.ID, job.RunCount).
+		Updates(map[string]interface{}{
+			"next_run_at": nextRunAt,
+			"last_run_at": runAt,
+			"run_count":   gorm.Expr("run_count + 1"),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+
+	job.NextRunAt = &nextRunAt
+	job.LastRunAt = &runAt
+	job.RunCount++
+	return true, nil
+}
+
+// RecordRunResult 记录最近一次触发提交的任务和错误
+func (s *RecurringJobStore) RecordRunResult(id uint, jobID *uint, errMsg string) error {
+	return s.db.DB.Model(&models.RecurringJob{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"last_job_id": jobID,
+			"last_error":  errMsg,
+		}).Error
+}
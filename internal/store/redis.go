@@ -3,6 +3,7 @@ package store
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -77,6 +78,9 @@ const (
 	CacheKeyPrefix   = "gvf:cache:"
 	QueueKeyPrefix   = "gvf:queue:"
 	NotifyKeyPrefix  = "gvf:notify:"
+	LockKeyPrefix    = "gvf:lock:"
+	IdemKeyPrefix    = "gvf:idempotency:"
+	RevokedKeyPrefix = "gvf:revoked:"
 )
 
 // BuildSessionKey builds a session key with prefix
@@ -97,4 +101,24 @@ func BuildQueueKey(queueName string) string {
 // BuildNotifyKey builds a notification key with prefix
 func BuildNotifyKey(channel string) string {
 	return NotifyKeyPrefix + channel
+}
+
+// BuildLockKey builds a distributed lock key with prefix
+func BuildLockKey(name string) string {
+	return LockKeyPrefix + name
+}
+
+// BuildIdempotencyKey builds an idempotency key with prefix
+func BuildIdempotencyKey(key string) string {
+	return IdemKeyPrefix + key
+}
+
+// BuildRevokedTokenKey builds the denylist key for an access token ID
+func BuildRevokedTokenKey(jti string) string {
+	return RevokedKeyPrefix + "jti:" + jti
+}
+
+// BuildTokenWatermarkKey builds the key holding a user's token watermark
+func BuildTokenWatermarkKey(userID uint) string {
+	return RevokedKeyPrefix + "user:" + strconv.FormatUint(uint64(userID), 10)
 }
\ No newline at end of file
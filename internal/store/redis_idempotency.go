@@ -0,0 +1,75 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisIdempotencyStore implements IdempotencyStore using Redis keys that
+// expire on their own
+type RedisIdempotencyStore struct {
+	redis *RedisClient
+}
+
+// NewRedisIdempotencyStore creates a new Redis idempotency store
+func NewRedisIdempotencyStore(redis *RedisClient) *RedisIdempotencyStore {
+	return &RedisIdempotencyStore{
+		redis: redis,
+	}
+}
+
+// Reserve claims key with SET NX, or returns the record already stored
+func (s *RedisIdempotencyStore) Reserve(key string, lockTTL time.Duration) (*IdempotencyRecord, error) {
+	ctx := context.Background()
+	redisKey := BuildIdempotencyKey(key)
+
+	claim, err := json.Marshal(IdempotencyRecord{})
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; attempt < reserveAttempts; attempt++ {
+		claimed, err := s.redis.client.SetNX(ctx, redisKey, claim, lockTTL).Result()
+		if err != nil {
+			return nil, err
+		}
+		if claimed {
+			return nil, nil
+		}
+
+		data, err := s.redis.client.Get(ctx, redisKey).Bytes()
+		if err == redis.Nil {
+			continue // Expired or released in the meantime, try to claim it again
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var record IdempotencyRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			return nil, err
+		}
+		return &record, nil
+	}
+	return nil, errors.New("failed to reserve idempotency key")
+}
+
+// Save stores the final response for key
+func (s *RedisIdempotencyStore) Save(key string, record *IdempotencyRecord, ttl time.Duration) error {
+	ctx := context.Background()
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return s.redis.client.Set(ctx, BuildIdempotencyKey(key), data, ttl).Err()
+}
+
+// Release deletes the key
+func (s *RedisIdempotencyStore) Release(key string) error {
+	ctx := context.Background()
+	return s.redis.client.Del(ctx, BuildIdempotencyKey(key)).Err()
+}
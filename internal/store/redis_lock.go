@@ -0,0 +1,67 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisLockService provides simple distributed locks with an owner token,
+// used for leader election between server replicas
+type RedisLockService struct {
+	redis *RedisClient
+}
+
+// NewRedisLockService creates a new Redis lock service
+func NewRedisLockService(redis *RedisClient) *RedisLockService {
+	return &RedisLockService{
+		redis: redis,
+	}
+}
+
+// acquireLockScript takes the lock if it is free, or extends it if the
+// caller already owns it
+var acquireLockScript = redis.NewScript(`
+local owner = redis.call('GET', KEYS[1])
+if owner == false then
+	redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+	return 1
+end
+if owner == ARGV[1] then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+	return 1
+end
+return 0
+`)
+
+// releaseLockScript only deletes the lock if it is still held by the caller
+var releaseLockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// Acquire takes or renews the named lock for owner and reports whether
+// owner holds it afterwards
+func (l *RedisLockService) Acquire(name, owner string, ttl time.Duration) (bool, error) {
+	ctx := context.Background()
+	held, err := acquireLockScript.Run(ctx, l.redis.client,
+		[]string{BuildLockKey(name)},
+		owner, ttl.Milliseconds(),
+	).Int()
+	if err != nil {
+		return false, err
+	}
+	return held == 1, nil
+}
+
+// Release gives up the named lock if owner still holds it
+func (l *RedisLockService) Release(name, owner string) error {
+	ctx := context.Background()
+	return releaseLockScript.Run(ctx, l.redis.client,
+		[]string{BuildLockKey(name)},
+		owner,
+	).Err()
+}
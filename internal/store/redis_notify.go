@@ -0,0 +1,74 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// RedisNotifyService publishes notifications over Redis pub/sub so that
+// subscribers on every server replica receive them
+type RedisNotifyService struct {
+	redis *RedisClient
+}
+
+// NewRedisNotifyService creates a new Redis notification service
+func NewRedisNotifyService(redis *RedisClient) *RedisNotifyService {
+	return &RedisNotifyService{
+		redis: redis,
+	}
+}
+
+// Publish sends a JSON-encoded message to a notification channel
+func (n *RedisNotifyService) Publish(channel string, message interface{}) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+
+	ctx := context.Background()
+	return n.redis.client.Publish(ctx, BuildNotifyKey(channel), data).Err()
+}
+
+// Subscribe listens on the given notification channels until ctx is done.
+// The returned channel yields raw message payloads and is closed when the
+// subscription ends.
+func (n *RedisNotifyService) Subscribe(ctx context.Context, channels ...string) (<-chan string, error) {
+	keys := make([]string, len(channels))
+	for i, channel := range channels {
+		keys[i] = BuildNotifyKey(channel)
+	}
+
+	pubsub := n.redis.client.Subscribe(ctx, keys...)
+	// Wait for the subscription to be confirmed so no message published
+	// after Subscribe returns is missed
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+
+	out := make(chan string, 16)
+	go func() {
+		defer close(out)
+		defer pubsub.Close()
+
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				select {
+				case out <- msg.Payload:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return out, nil
+}
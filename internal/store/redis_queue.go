@@ -3,12 +3,20 @@ package store
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
+var (
+	// ErrQueueEmpty is returned when no job is available to dequeue
+	ErrQueueEmpty = errors.New("no jobs available")
+	// ErrMaxRetriesExceeded is returned when a job cannot be retried any more
+	ErrMaxRetriesExceeded = errors.New("job exceeded max retries")
+)
+
 // RedisQueueService provides task queue functionality
 type RedisQueueService struct {
 	redis *RedisClient
@@ -24,28 +32,72 @@ func NewRedisQueueService(redis *RedisClient) *RedisQueueService {
 // QueuedJob represents a job in the queue
 type QueuedJob struct {
 	ID         string                 `json:"id"`
+	JobID      uint                   `json:"job_id"`      // models.Job row backing this entry
 	UserID     uint                   `json:"user_id"`
 	Type       string                 `json:"type"`
 	Priority   int                    `json:"priority"`    // 1-10, higher is more priority
 	Payload    map[string]interface{} `json:"payload"`
 	CreatedAt  time.Time              `json:"created_at"`
+	RunAt      time.Time              `json:"run_at,omitempty"` // not before this time; zero means immediately
 	RetryCount int                    `json:"retry_count"`
 	MaxRetries int                    `json:"max_retries"`
+	LastError  string                 `json:"last_error,omitempty"`
+	Attempts   []JobAttempt           `json:"attempts,omitempty"`
+
+	raw    string   // exact member encoding, set when read back from Redis
+	rowID  uint     // queue_jobs row, set when read back from the database
+	origin JobQueue // backend that delivered the job, set by FailoverJobQueue
 }
 
-// Enqueue adds a job to the queue
-func (q *RedisQueueService) Enqueue(queueName string, job QueuedJob) error {
-	queueKey := BuildQueueKey(queueName)
-	
+// JobAttempt records a failed execution of a queued job
+type JobAttempt struct {
+	Attempt  int       `json:"attempt"`
+	Error    string    `json:"error"`
+	FailedAt time.Time `json:"failed_at"`
+}
+
+// maxRecordedAttempts caps the attempt history kept on a queued job
+const maxRecordedAttempts = 20
+
+// maxRetryBackoff caps the delay between retries of a failed job
+const maxRetryBackoff = time.Hour
+
+// readyScore orders the ready queue: higher priority first, then the oldest
+// job. Priorities are spaced further apart than any millisecond timestamp so
+// age never outranks priority.
+func readyScore(job QueuedJob) float64 {
+	return -float64(job.Priority)*1e13 + float64(job.CreatedAt.UnixMilli())
+}
+
+// RetryBackoff returns the delay before the given retry of a failed job:
+// retryCount² seconds (1s, 4s, 9s, ...), capped at maxRetryBackoff
+func RetryBackoff(retryCount int) time.Duration {
+	delay := time.Duration(retryCount*retryCount) * time.Second
+	if delay > maxRetryBackoff {
+		delay = maxRetryBackoff
+	}
+	return delay
+}
+
+// applyEnqueueDefaults fills in the creation time and retry budget of a new job
+func applyEnqueueDefaults(job *QueuedJob) {
 	// Set creation time if not set
 	if job.CreatedAt.IsZero() {
 		job.CreatedAt = time.Now()
 	}
-	
+
 	// Set default max retries if not set
 	if job.MaxRetries == 0 {
 		job.MaxRetries = 3
 	}
+}
+
+// Enqueue adds a job to the queue. Jobs with a RunAt in the future are held
+// in the delayed set until PromoteDue moves them to the ready queue.
+func (q *RedisQueueService) Enqueue(queueName string, job QueuedJob) error {
+	queueKey := BuildQueueKey(queueName)
+	
+	applyEnqueueDefaults(&job)
 
 	jobData, err := json.Marshal(job)
 	if err != nil {
@@ -54,8 +106,15 @@ func (q *RedisQueueService) Enqueue(queueName string, job QueuedJob) error {
 
 	ctx := context.Background()
 	
+	if job.RunAt.After(time.Now()) {
+		return q.redis.client.ZAdd(ctx, buildDelayedKey(queueName), redis.Z{
+			Score:  float64(job.RunAt.UnixMilli()),
+			Member: string(jobData),
+		}).Err()
+	}
+	
 	// Use priority queue (sorted set) for jobs with different priorities
-	score := float64(job.Priority)*1000000 - float64(job.CreatedAt.Unix()) // Higher priority and older jobs first
+	score := readyScore(job) // Higher priority and older jobs first
 	return q.redis.client.ZAdd(ctx, queueKey, redis.Z{
 		Score:  score,
 		Member: string(jobData),
@@ -76,7 +135,7 @@ func (q *RedisQueueService) Dequeue(queueName string, timeout time.Duration) (*Q
 	}
 
 	if len(result) == 0 {
-		return nil, fmt.Errorf("no jobs available")
+		return nil, ErrQueueEmpty
 	}
 
 	var job QueuedJob
@@ -97,6 +156,9 @@ func (q *RedisQueueService) DequeueBlocking(queueName string, timeout time.Durat
 	// Use BZPOPMIN for blocking operation
 	result, err := q.redis.client.BZPopMin(ctx, timeout, queueKey).Result()
 	if err != nil {
+		if errors.Is(err, redis.Nil) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, ErrQueueEmpty
+		}
 		return nil, err
 	}
 
@@ -119,7 +181,7 @@ func (q *RedisQueueService) Peek(queueName string) (*QueuedJob, error) {
 	}
 
 	if len(result) == 0 {
-		return nil, fmt.Errorf("no jobs available")
+		return nil, ErrQueueEmpty
 	}
 
 	var job QueuedJob
@@ -173,20 +235,67 @@ func (q *RedisQueueService) RemoveJob(queueName string, job QueuedJob) error {
 	return q.redis.client.ZRem(ctx, queueKey, string(jobData)).Err()
 }
 
-// RequeueJob adds a failed job back to the queue with incremented retry count
+// RemoveQueuedJob removes a waiting job, ready or delayed, by its queue ID
+// and reports whether it was found. Leased jobs are not affected.
+func (q *RedisQueueService) RemoveQueuedJob(queueName, id string) (bool, error) {
+	ctx := context.Background()
+	for _, key := range []string{BuildQueueKey(queueName), buildDelayedKey(queueName)} {
+		member, job, err := q.findQueuedMember(ctx, key, id)
+		if err != nil {
+			return false, err
+		}
+		if job == nil {
+			continue
+		}
+
+		removed, err := q.redis.client.ZRem(ctx, key, member).Result()
+		if err != nil {
+			return false, err
+		}
+		if removed > 0 {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// RequeueJob adds a failed job back to the queue with incremented retry count.
+// Set job.LastError before calling so the attempt is recorded. Jobs that have
+// exhausted their retries are moved to the dead-letter queue and
+// ErrMaxRetriesExceeded is returned.
 func (q *RedisQueueService) RequeueJob(queueName string, job QueuedJob) error {
+	// If max retries exceeded, park the job in the dead-letter queue
+	if !recordFailure(&job) {
+		if err := q.MoveToDeadLetter(queueName, job); err != nil {
+			return fmt.Errorf("failed to dead-letter job %s: %w", job.ID, err)
+		}
+		return fmt.Errorf("job %s (%d retries): %w", job.ID, job.MaxRetries, ErrMaxRetriesExceeded)
+	}
+
+	return q.Enqueue(queueName, job)
+}
+
+// recordFailure increments the retry count, records the failed attempt and
+// schedules the next attempt after RetryBackoff. It returns false when
+// the job has exhausted its retries.
+func recordFailure(job *QueuedJob) bool {
 	job.RetryCount++
-	
-	// If max retries exceeded, don't requeue
-	if job.RetryCount > job.MaxRetries {
-		return fmt.Errorf("job %s exceeded max retries (%d)", job.ID, job.MaxRetries)
+	job.Attempts = append(job.Attempts, JobAttempt{
+		Attempt:  job.RetryCount,
+		Error:    job.LastError,
+		FailedAt: time.Now(),
+	})
+	if len(job.Attempts) > maxRecordedAttempts {
+		job.Attempts = job.Attempts[len(job.Attempts)-maxRecordedAttempts:]
 	}
 
-	// Add delay for retries (exponential backoff)
-	delay := time.Duration(job.RetryCount*job.RetryCount) * time.Second
-	job.CreatedAt = time.Now().Add(delay)
+	if job.RetryCount > job.MaxRetries {
+		return false
+	}
 
-	return q.Enqueue(queueName, job)
+	job.RunAt = time.Now().Add(RetryBackoff(job.RetryCount))
+	return true
 }
 
 // ClearQueue removes all jobs from the queue
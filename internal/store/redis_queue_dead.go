@@ -0,0 +1,157 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DeadJob is a job parked in a queue's dead-letter set after exhausting its retries
+type DeadJob struct {
+	Job       QueuedJob `json:"job"`
+	Queue     string    `json:"queue"`
+	LastError string    `json:"last_error"`
+	FailedAt  time.Time `json:"failed_at"`
+
+	raw string
+}
+
+// buildDeadKey builds the dead-letter set key for a queue
+func buildDeadKey(queueName string) string {
+	return BuildQueueKey(queueName) + ":dead"
+}
+
+func decodeDeadJob(member string) (*DeadJob, error) {
+	var dead DeadJob
+	if err := json.Unmarshal([]byte(member), &dead); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal dead job: %w", err)
+	}
+	dead.raw = member
+	return &dead, nil
+}
+
+// MoveToDeadLetter parks a job in the queue's dead-letter set
+func (q *RedisQueueService) MoveToDeadLetter(queueName string, job QueuedJob) error {
+	job.raw = ""
+	dead := DeadJob{
+		Job:       job,
+		Queue:     queueName,
+		LastError: job.LastError,
+		FailedAt:  time.Now(),
+	}
+
+	data, err := json.Marshal(dead)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dead job: %w", err)
+	}
+
+	ctx := context.Background()
+	return q.redis.client.ZAdd(ctx, buildDeadKey(queueName), redis.Z{
+		Score:  float64(dead.FailedAt.UnixMilli()),
+		Member: string(data),
+	}).Err()
+}
+
+// GetDeadJobs returns dead-lettered jobs, most recent failure first
+func (q *RedisQueueService) GetDeadJobs(queueName string, start, stop int64) ([]DeadJob, error) {
+	ctx := context.Background()
+	members, err := q.redis.client.ZRevRange(ctx, buildDeadKey(queueName), start, stop).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]DeadJob, 0, len(members))
+	for _, member := range members {
+		dead, err := decodeDeadJob(member)
+		if err != nil {
+			continue // Skip malformed jobs
+		}
+		jobs = append(jobs, *dead)
+	}
+
+	return jobs, nil
+}
+
+// GetDeadLetterLength returns the number of dead-lettered jobs for the queue
+func (q *RedisQueueService) GetDeadLetterLength(queueName string) (int64, error) {
+	ctx := context.Background()
+	return q.redis.client.ZCard(ctx, buildDeadKey(queueName)).Result()
+}
+
+// GetDeadJob finds a dead-lettered job by its queue ID, returning nil if absent
+func (q *RedisQueueService) GetDeadJob(queueName, id string) (*DeadJob, error) {
+	ctx := context.Background()
+	iter := q.redis.client.ZScan(ctx, buildDeadKey(queueName), 0, "", 100).Iterator()
+	for iter.Next(ctx) {
+		member := iter.Val()
+		// ZSCAN yields member/score pairs; skip the scores
+		if !iter.Next(ctx) {
+			break
+		}
+
+		dead, err := decodeDeadJob(member)
+		if err != nil {
+			continue
+		}
+		if dead.Job.ID == id {
+			return dead, nil
+		}
+	}
+
+	return nil, iter.Err()
+}
+
+// RemoveDeadJob deletes a dead-lettered job and returns it, or nil if absent
+func (q *RedisQueueService) RemoveDeadJob(queueName, id string) (*DeadJob, error) {
+	dead, err := q.GetDeadJob(queueName, id)
+	if err != nil || dead == nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	removed, err := q.redis.client.ZRem(ctx, buildDeadKey(queueName), dead.raw).Result()
+	if err != nil {
+		return nil, err
+	}
+	if removed == 0 {
+		return nil, nil // Removed concurrently
+	}
+
+	return dead, nil
+}
+
+// ReplayDeadJob moves a dead-lettered job back to the queue with a fresh
+// retry budget, keeping its attempt history
+func (q *RedisQueueService) ReplayDeadJob(queueName, id string) (*DeadJob, error) {
+	dead, err := q.RemoveDeadJob(queueName, id)
+	if err != nil || dead == nil {
+		return nil, err
+	}
+
+	job := dead.Job
+	job.RetryCount = 0
+	job.LastError = ""
+	if err := q.Enqueue(queueName, job); err != nil {
+		// Put it back so the job is not lost
+		_ = q.MoveToDeadLetter(queueName, dead.Job)
+		return nil, err
+	}
+
+	return dead, nil
+}
+
+// PurgeDeadLetter deletes all dead-lettered jobs for the queue
+func (q *RedisQueueService) PurgeDeadLetter(queueName string) (int64, error) {
+	ctx := context.Background()
+	count, err := q.redis.client.ZCard(ctx, buildDeadKey(queueName)).Result()
+	if err != nil {
+		return 0, err
+	}
+	if err := q.redis.client.Del(ctx, buildDeadKey(queueName)).Err(); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
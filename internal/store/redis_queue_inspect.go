@@ -0,0 +1,119 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Queue inspection
+//
+// Admin operations on waiting jobs: discovering queues, paging and
+// re-prioritising jobs that have not been leased yet.
+
+// queueKeySuffixes are the per-queue sets stored next to the ready queue
+var queueKeySuffixes = []string{":inflight", ":dead", ":delayed"}
+
+// replaceScript swaps a sorted set member for a new encoding, unless another
+// process already removed it
+var replaceScript = redis.NewScript(`
+if redis.call('ZREM', KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+redis.call('ZADD', KEYS[1], ARGV[2], ARGV[3])
+return 1
+`)
+
+// ListQueues returns the names of all queues with keys under QueueKeyPrefix
+func (q *RedisQueueService) ListQueues() ([]string, error) {
+	ctx := context.Background()
+	seen := make(map[string]bool)
+
+	iter := q.redis.client.Scan(ctx, 0, QueueKeyPrefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		name := strings.TrimPrefix(iter.Val(), QueueKeyPrefix)
+		for _, suffix := range queueKeySuffixes {
+			name = strings.TrimSuffix(name, suffix)
+		}
+		seen[name] = true
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// findQueuedMember looks up a waiting job by its queue ID in one sorted set,
+// returning an empty member if it is absent
+func (q *RedisQueueService) findQueuedMember(ctx context.Context, key, id string) (string, *QueuedJob, error) {
+	iter := q.redis.client.ZScan(ctx, key, 0, fmt.Sprintf(`*"id":%q*`, id), 100).Iterator()
+	for iter.Next(ctx) {
+		member := iter.Val()
+		// ZSCAN yields member/score pairs; skip the scores
+		if !iter.Next(ctx) {
+			break
+		}
+
+		job, err := decodeQueuedJob(member)
+		if err != nil || job.ID != id {
+			continue
+		}
+		return member, job, nil
+	}
+	return "", nil, iter.Err()
+}
+
+// SetQueuedJobPriority changes the priority of a waiting job, ready or
+// delayed, and returns the updated job or nil if it is not waiting
+func (q *RedisQueueService) SetQueuedJobPriority(queueName, id string, priority int) (*QueuedJob, error) {
+	ctx := context.Background()
+	for _, key := range []string{BuildQueueKey(queueName), buildDelayedKey(queueName)} {
+		member, job, err := q.findQueuedMember(ctx, key, id)
+		if err != nil {
+			return nil, err
+		}
+		if job == nil {
+			continue
+		}
+
+		// Delayed jobs keep their due time, ready jobs move to their new place
+		score, err := q.redis.client.ZScore(ctx, key, member).Result()
+		if err == redis.Nil {
+			continue // Leased or removed in the meantime
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		job.raw = ""
+		job.Priority = priority
+		if key == BuildQueueKey(queueName) {
+			score = readyScore(*job)
+		}
+		data, err := job.member()
+		if err != nil {
+			return nil, err
+		}
+
+		replaced, err := replaceScript.Run(ctx, q.redis.client, []string{key}, member, score, data).Int()
+		if err != nil {
+			return nil, err
+		}
+		if replaced == 0 {
+			continue
+		}
+		job.raw = data
+		return job, nil
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,263 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Reliable queue mode
+//
+// Reserve moves a job from the ready queue into a per-queue in-flight sorted
+// set scored by its lease deadline instead of deleting it. The worker must Ack
+// the job once it is finished or Nack it to schedule a retry. Jobs whose lease
+// expires (because the worker crashed or the process was killed) are returned
+// to the queue by ReapExpired, giving at-least-once delivery.
+
+// ErrLeaseLost is returned when a job is no longer in the in-flight set,
+// usually because its lease expired and it was reaped
+var ErrLeaseLost = errors.New("job lease lost")
+
+// reserveScript atomically pops the highest priority job and records it as in-flight
+var reserveScript = redis.NewScript(`
+local item = redis.call('ZPOPMIN', KEYS[1])
+if #item == 0 then
+	return false
+end
+redis.call('ZADD', KEYS[2], ARGV[1], item[1])
+return item[1]
+`)
+
+// reserveFilteredScript leases the first of the next ARGV[2] ready jobs whose
+// user and type are not in the skip lists. Skipped jobs are parked in the
+// delayed set until ARGV[4] so later calls do not scan them again; promotion
+// restores their original position in the queue.
+var reserveFilteredScript = redis.NewScript(`
+local skip = cjson.decode(ARGV[3])
+local members = redis.call('ZRANGE', KEYS[1], 0, tonumber(ARGV[2]) - 1)
+for _, member in ipairs(members) do
+	redis.call('ZREM', KEYS[1], member)
+	local ok, job = pcall(cjson.decode, member)
+	if ok and (skip.users[tostring(job.user_id)] or skip.types[tostring(job.type)]) then
+		redis.call('ZADD', KEYS[3], ARGV[4], member)
+	else
+		redis.call('ZADD', KEYS[2], ARGV[1], member)
+		return member
+	end
+end
+return false
+`)
+
+// reserveScanLimit bounds how many ready jobs a filtered Reserve inspects
+const reserveScanLimit = 100
+
+// skippedJobDelay is how long jobs skipped by a filtered Reserve are parked
+const skippedJobDelay = 5 * time.Second
+
+// extendLeaseScript only extends leases that are still held
+var extendLeaseScript = redis.NewScript(`
+if redis.call('ZSCORE', KEYS[1], ARGV[2]) == false then
+	return 0
+end
+redis.call('ZADD', KEYS[1], ARGV[1], ARGV[2])
+return 1
+`)
+
+// buildInflightKey builds the in-flight set key for a queue
+func buildInflightKey(queueName string) string {
+	return BuildQueueKey(queueName) + ":inflight"
+}
+
+// decodeQueuedJob unmarshals a queue member and remembers its raw encoding
+func decodeQueuedJob(member string) (*QueuedJob, error) {
+	var job QueuedJob
+	if err := json.Unmarshal([]byte(member), &job); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal job: %w", err)
+	}
+	job.raw = member
+	return &job, nil
+}
+
+// member returns the exact sorted set member for the job
+func (job *QueuedJob) member() (string, error) {
+	if job.raw != "" {
+		return job.raw, nil
+	}
+	data, err := json.Marshal(job)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal job: %w", err)
+	}
+	return string(data), nil
+}
+
+// Reserve takes the highest priority job not excluded by filter and leases
+// it for the given duration
+func (q *RedisQueueService) Reserve(queueName string, lease time.Duration, filter ReserveFilter) (*QueuedJob, error) {
+	ctx := context.Background()
+	deadline := time.Now().Add(lease).UnixMilli()
+
+	var result string
+	var err error
+	if filter.IsEmpty() {
+		result, err = reserveScript.Run(ctx, q.redis.client,
+			[]string{BuildQueueKey(queueName), buildInflightKey(queueName)},
+			deadline,
+		).Text()
+	} else {
+		skip, encodeErr := encodeReserveFilter(filter)
+		if encodeErr != nil {
+			return nil, encodeErr
+		}
+		result, err = reserveFilteredScript.Run(ctx, q.redis.client,
+			[]string{BuildQueueKey(queueName), buildInflightKey(queueName), buildDelayedKey(queueName)},
+			deadline, reserveScanLimit, skip, time.Now().Add(skippedJobDelay).UnixMilli(),
+		).Text()
+	}
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrQueueEmpty
+		}
+		return nil, err
+	}
+
+	return decodeQueuedJob(result)
+}
+
+// encodeReserveFilter encodes the skip lists as JSON sets for reserveFilteredScript
+func encodeReserveFilter(filter ReserveFilter) (string, error) {
+	skip := struct {
+		Users map[string]bool `json:"users"`
+		Types map[string]bool `json:"types"`
+	}{
+		Users: make(map[string]bool, len(filter.SkipUsers)),
+		Types: make(map[string]bool, len(filter.SkipTypes)),
+	}
+	for _, userID := range filter.SkipUsers {
+		skip.Users[strconv.FormatUint(uint64(userID), 10)] = true
+	}
+	for _, jobType := range filter.SkipTypes {
+		skip.Types[jobType] = true
+	}
+
+	data, err := json.Marshal(skip)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal reserve filter: %w", err)
+	}
+	return string(data), nil
+}
+
+// Ack removes a finished job from the in-flight set
+func (q *RedisQueueService) Ack(queueName string, job *QueuedJob) error {
+	member, err := job.member()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	removed, err := q.redis.client.ZRem(ctx, buildInflightKey(queueName), member).Result()
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// Nack releases a failed job and requeues it with an incremented retry count.
+// It returns ErrMaxRetriesExceeded when the job has no retries left.
+func (q *RedisQueueService) Nack(queueName string, job *QueuedJob) error {
+	if err := q.Ack(queueName, job); err != nil {
+		return err
+	}
+	return q.RequeueJob(queueName, *job)
+}
+
+// Release returns a leased job to the queue without counting an attempt,
+// e.g. when a worker is shutting down
+func (q *RedisQueueService) Release(queueName string, job *QueuedJob) error {
+	if err := q.Ack(queueName, job); err != nil {
+		return err
+	}
+	return q.Enqueue(queueName, *job)
+}
+
+// ExtendLease pushes the lease deadline of an in-flight job forward
+func (q *RedisQueueService) ExtendLease(queueName string, job *QueuedJob, lease time.Duration) error {
+	member, err := job.member()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	deadline := time.Now().Add(lease).UnixMilli()
+	extended, err := extendLeaseScript.Run(ctx, q.redis.client,
+		[]string{buildInflightKey(queueName)},
+		deadline, member,
+	).Int()
+	if err != nil {
+		return err
+	}
+	if extended == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// ReapExpired returns jobs with expired leases to the queue. onExpired is
+// called for every reaped job before it is requeued; exhausted reports
+// whether the job has used up its retries and is dead-lettered instead.
+func (q *RedisQueueService) ReapExpired(queueName string, onExpired func(job QueuedJob, exhausted bool)) (int, error) {
+	inflightKey := buildInflightKey(queueName)
+
+	ctx := context.Background()
+	members, err := q.redis.client.ZRangeByScore(ctx, inflightKey, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(time.Now().UnixMilli(), 10),
+	}).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	reaped := 0
+	for _, member := range members {
+		// Only the reaper that removes the member may requeue it
+		removed, err := q.redis.client.ZRem(ctx, inflightKey, member).Result()
+		if err != nil {
+			return reaped, err
+		}
+		if removed == 0 {
+			continue
+		}
+		reaped++
+
+		job, err := decodeQueuedJob(member)
+		if err != nil {
+			continue // Skip malformed jobs
+		}
+		job.LastError = "job lease expired"
+
+		exhausted := job.RetryCount >= job.MaxRetries
+		if onExpired != nil {
+			onExpired(*job, exhausted)
+		}
+
+		// Exhausted jobs end up in the dead-letter queue
+		if err := q.RequeueJob(queueName, *job); err != nil && !errors.Is(err, ErrMaxRetriesExceeded) {
+			return reaped, err
+		}
+	}
+
+	return reaped, nil
+}
+
+// GetInflightLength returns the number of leased jobs for the queue
+func (q *RedisQueueService) GetInflightLength(queueName string) (int64, error) {
+	ctx := context.Background()
+	return q.redis.client.ZCard(ctx, buildInflightKey(queueName)).Result()
+}
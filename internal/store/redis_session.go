@@ -3,9 +3,12 @@ package store
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/redis/go-redis/v9"
+
 	"go-vibe-friend/internal/models"
 )
 
@@ -31,6 +34,11 @@ type SessionData struct {
 	IPAddress  string    `json:"ip_address"`
 	UserAgent  string    `json:"user_agent"`
 	ExpiresAt  time.Time `json:"expires_at"`
+
+	// Refresh token family; rotated sessions are kept until their TTL runs
+	// out so that a replayed refresh token can be detected
+	FamilyID  string     `json:"family_id,omitempty"`
+	RotatedAt *time.Time `json:"rotated_at,omitempty"`
 }
 
 // Set stores session data in Redis
@@ -172,28 +180,31 @@ func (r *RedisSessionStore) UpdateLastAccess(sessionID string) error {
 
 // Database-style session operations (for compatibility with AuthService)
 func (r *RedisSessionStore) CreateSession(session *models.Session) error {
-	// Convert models.Session to SessionData and store in Redis
-	sessionData := SessionData{
+	// Use refresh token as session ID for compatibility
+	return r.Set(session.RefreshToken, newSessionData(session))
+}
+
+// newSessionData converts models.Session to the SessionData stored in Redis
+func newSessionData(session *models.Session) SessionData {
+	return SessionData{
 		UserID:     session.UserID,
 		IPAddress:  session.IPAddress,
 		UserAgent:  session.UserAgent,
 		LoginTime:  time.Now(),
 		LastAccess: time.Now(),
 		ExpiresAt:  session.ExpiresAt,
+		FamilyID:   session.FamilyID,
 	}
-	
-	// Use refresh token as session ID for compatibility
-	return r.Set(session.RefreshToken, sessionData)
 }
 
 func (r *RedisSessionStore) GetSessionByToken(refreshToken string) (*models.Session, error) {
 	sessionData, err := r.Get(refreshToken)
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
-	if sessionData == nil {
-		return nil, nil
-	}
 	
 	// Convert SessionData back to models.Session
 	session := &models.Session{
@@ -202,7 +213,10 @@ func (r *RedisSessionStore) GetSessionByToken(refreshToken string) (*models.Sess
 		IPAddress:    sessionData.IPAddress,
 		UserAgent:    sessionData.UserAgent,
 		ExpiresAt:    sessionData.ExpiresAt,
-		IsRevoked:    false, // Sessions in Redis are not revoked, they're deleted
+		FamilyID:     sessionData.FamilyID,
+		RotatedAt:    sessionData.RotatedAt,
+		// Revoked sessions are deleted, only rotated ones are kept
+		IsRevoked: sessionData.RotatedAt != nil,
 	}
 	
 	return session, nil
@@ -216,4 +230,123 @@ func (r *RedisSessionStore) RevokeSession(refreshToken string) error {
 func (r *RedisSessionStore) RevokeAllUserSessions(userID uint) error {
 	// Use the existing DeleteUserSessions method
 	return r.DeleteUserSessions(userID)
-}
\ No newline at end of file
+}
+
+// ListUserSessions returns the user's live and rotated sessions; revoked and
+// expired sessions are deleted from Redis and cannot be listed
+func (r *RedisSessionStore) ListUserSessions(userID uint) ([]models.Session, error) {
+	tokens, err := r.GetUserSessions(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]models.Session, 0, len(tokens))
+	for _, token := range tokens {
+		data, err := r.Get(token)
+		if err != nil {
+			// Expired between the scan and the read
+			continue
+		}
+		session := models.Session{
+			UserID:       data.UserID,
+			RefreshToken: token,
+			ExpiresAt:    data.ExpiresAt,
+			UserAgent:    data.UserAgent,
+			IPAddress:    data.IPAddress,
+			IsRevoked:    data.RotatedAt != nil,
+			FamilyID:     data.FamilyID,
+			RotatedAt:    data.RotatedAt,
+		}
+		session.CreatedAt = data.LoginTime
+		session.UpdatedAt = data.LastAccess
+		sessions = append(sessions, session)
+	}
+	return sessions, nil
+}
+
+// rotateSessionScript replaces the old session with its rotated form and
+// stores the next session, unless the old session changed since it was read
+var rotateSessionScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[2], 'KEEPTTL')
+redis.call('SET', KEYS[2], ARGV[3], 'PX', ARGV[4])
+return 1
+`)
+
+// RotateSession marks the old session as rotated and stores next in the same
+// family. It returns ErrSessionRotated if the old session was already rotated
+// or revoked, or was rotated concurrently.
+func (r *RedisSessionStore) RotateSession(oldRefreshToken string, next *models.Session) error {
+	ctx := context.Background()
+	key := BuildSessionKey(oldRefreshToken)
+
+	current, err := r.redis.client.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return ErrSessionRotated
+	}
+	if err != nil {
+		return err
+	}
+
+	var data SessionData
+	if err := json.Unmarshal([]byte(current), &data); err != nil {
+		return fmt.Errorf("failed to unmarshal session data: %w", err)
+	}
+	if data.RotatedAt != nil {
+		return ErrSessionRotated
+	}
+
+	now := time.Now()
+	data.RotatedAt = &now
+	data.FamilyID = next.FamilyID
+	rotated, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal session data: %w", err)
+	}
+	nextData, err := json.Marshal(newSessionData(next))
+	if err != nil {
+		return fmt.Errorf("failed to marshal session data: %w", err)
+	}
+
+	swapped, err := rotateSessionScript.Run(ctx, r.redis.client,
+		[]string{key, BuildSessionKey(next.RefreshToken)},
+		current, rotated, nextData, r.redis.config.SessionTTL.Milliseconds(),
+	).Int()
+	if err != nil {
+		return err
+	}
+	if swapped == 0 {
+		return ErrSessionRotated
+	}
+	return nil
+}
+
+// RevokeSessionFamily deletes every session in the family
+func (r *RedisSessionStore) RevokeSessionFamily(familyID string) error {
+	ctx := context.Background()
+
+	iter := r.redis.client.Scan(ctx, 0, SessionKeyPrefix+"*", 0).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+
+		result, err := r.redis.client.Get(ctx, key).Result()
+		if err != nil {
+			continue
+		}
+
+		var data SessionData
+		if err := json.Unmarshal([]byte(result), &data); err != nil {
+			continue
+		}
+
+		if data.FamilyID == familyID {
+			if err := r.redis.client.Del(ctx, key).Err(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return iter.Err()
+}
@@ -0,0 +1,64 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisTokenRevocationStore implements TokenRevocationStore using Redis keys
+// that expire together with the tokens they revoke
+type RedisTokenRevocationStore struct {
+	redis *RedisClient
+}
+
+// NewRedisTokenRevocationStore creates a new Redis token revocation store
+func NewRedisTokenRevocationStore(redis *RedisClient) *RedisTokenRevocationStore {
+	return &RedisTokenRevocationStore{
+		redis: redis,
+	}
+}
+
+// RevokeToken sets the denylist key for jti until the token expires
+func (s *RedisTokenRevocationStore) RevokeToken(jti string, userID uint, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil // Already expired, nothing to revoke
+	}
+	ctx := context.Background()
+	return s.redis.client.Set(ctx, BuildRevokedTokenKey(jti), userID, ttl).Err()
+}
+
+// RevokeUserTokens stores the watermark as Unix milliseconds
+func (s *RedisTokenRevocationStore) RevokeUserTokens(userID uint, before time.Time, ttl time.Duration) error {
+	ctx := context.Background()
+	return s.redis.client.Set(ctx, BuildTokenWatermarkKey(userID), before.UnixMilli(), ttl).Err()
+}
+
+// IsRevoked reads the watermark and the denylist key in one round trip
+func (s *RedisTokenRevocationStore) IsRevoked(jti string, userID uint, issuedAt time.Time) (bool, error) {
+	ctx := context.Background()
+	keys := []string{BuildTokenWatermarkKey(userID)}
+	if jti != "" {
+		keys = append(keys, BuildRevokedTokenKey(jti))
+	}
+
+	values, err := s.redis.client.MGet(ctx, keys...).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return false, err
+	}
+
+	if watermark, ok := values[0].(string); ok {
+		millis, err := strconv.ParseInt(watermark, 10, 64)
+		if err != nil {
+			return false, err
+		}
+		if issuedBefore(issuedAt, time.UnixMilli(millis)) {
+			return true, nil
+		}
+	}
+	return len(values) > 1 && values[1] != nil, nil
+}
@@ -1,10 +1,17 @@
 package store
 
 import (
+	"errors"
+	"time"
+
 	"go-vibe-friend/internal/models"
 	"gorm.io/gorm"
 )
 
+// ErrSessionRotated is returned by RotateSession when the session was
+// already rotated or revoked
+var ErrSessionRotated = errors.New("session already rotated")
+
 type SessionStore struct {
 	db *gorm.DB
 }
@@ -40,6 +47,34 @@ func (s *SessionStore) RevokeSession(refreshToken string) error {
 		Update("is_revoked", true).Error
 }
 
+// RotateSession 将旧会话标记为已轮换并创建同一令牌族的新会话。
+// 旧会话已被轮换或撤销时返回 ErrSessionRotated，同一令牌的并发刷新只有一个能成功
+func (s *SessionStore) RotateSession(oldRefreshToken string, next *models.Session) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Session{}).
+			Where("refresh_token = ? AND is_revoked = ?", oldRefreshToken, false).
+			Updates(map[string]interface{}{
+				"is_revoked": true,
+				"rotated_at": time.Now(),
+				"family_id":  next.FamilyID,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrSessionRotated
+		}
+		return tx.Create(next).Error
+	})
+}
+
+// RevokeSessionFamily 撤销令牌族中的所有会话
+func (s *SessionStore) RevokeSessionFamily(familyID string) error {
+	return s.db.Model(&models.Session{}).
+		Where("family_id = ?", familyID).
+		Update("is_revoked", true).Error
+}
+
 // RevokeAllUserSessions 撤销用户的所有会话
 func (s *SessionStore) RevokeAllUserSessions(userID uint) error {
 	return s.db.Model(&models.Session{}).
@@ -49,7 +84,7 @@ func (s *SessionStore) RevokeAllUserSessions(userID uint) error {
 
 // CleanExpiredSessions 清理过期的会话
 func (s *SessionStore) CleanExpiredSessions() error {
-	return s.db.Delete(&models.Session{}, "expires_at < NOW()").Error
+	return s.db.Delete(&models.Session{}, "expires_at < ?", time.Now()).Error
 }
 
 // GetUserSessions 获取用户的所有会话
@@ -57,4 +92,11 @@ func (s *SessionStore) GetUserSessions(userID uint) ([]models.Session, error) {
 	var sessions []models.Session
 	err := s.db.Where("user_id = ? AND is_revoked = false", userID).Find(&sessions).Error
 	return sessions, err
-}
\ No newline at end of file
+}
+
+// ListUserSessions 获取用户的所有会话，包括已撤销和已过期的会话
+func (s *SessionStore) ListUserSessions(userID uint) ([]models.Session, error) {
+	var sessions []models.Session
+	err := s.db.Where("user_id = ?", userID).Order("created_at").Find(&sessions).Error
+	return sessions, err
+}
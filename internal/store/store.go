@@ -13,17 +13,29 @@ type Store struct {
 	Redis    *RedisClient
 	
 	// Redis-based services
-	Session  *RedisSessionStore
-	Cache    *RedisCacheService
-	Queue    *RedisQueueService
+	Session         *RedisSessionStore
+	Cache           *RedisCacheService
+	Queue           *RedisQueueService
+	Lock            *RedisLockService
+	Notify          *RedisNotifyService
+	Idempotency     *RedisIdempotencyStore
+	TokenRevocation *RedisTokenRevocationStore
 	
 	// Database-based stores (existing)
-	User       *UserStore
-	Profile    *ProfileStore
-	Job        *JobStore
-	Permission *PermissionStore
-	Email      *EmailStore
-	File       *FileStore
+	User        *UserStore
+	Profile     *ProfileStore
+	Job         *JobStore
+	Permission  *PermissionStore
+	Email       *EmailStore
+	File        *FileStore
+	Recurring   *RecurringJobStore
+	Concurrency *ConcurrencyLimitStore
+	Export      *ExportStore
+	TwoFactor   *TwoFactorStore
+	WebAuthn    *WebAuthnStore
+
+	// JobQueue prefers Redis and falls back to the database queue
+	JobQueue JobQueue
 }
 
 // NewStore creates a new Store with all services initialized
@@ -50,6 +62,10 @@ func NewStore(cfg *config.Config) (*Store, error) {
 		store.Session = NewRedisSessionStore(redisClient)
 		store.Cache = NewRedisCacheService(redisClient)
 		store.Queue = NewRedisQueueService(redisClient)
+		store.Lock = NewRedisLockService(redisClient)
+		store.Notify = NewRedisNotifyService(redisClient)
+		store.Idempotency = NewRedisIdempotencyStore(redisClient)
+		store.TokenRevocation = NewRedisTokenRevocationStore(redisClient)
 	}
 
 	// Initialize database-based stores
@@ -59,6 +75,12 @@ func NewStore(cfg *config.Config) (*Store, error) {
 	store.Permission = NewPermissionStore(db)
 	store.Email = NewEmailStore(db)
 	store.File = NewFileStore(db)
+	store.Recurring = NewRecurringJobStore(db)
+	store.Concurrency = NewConcurrencyLimitStore(db)
+	store.Export = NewExportStore(db)
+	store.TwoFactor = NewTwoFactorStore(db)
+	store.WebAuthn = NewWebAuthnStore(db)
+	store.JobQueue = NewFailoverJobQueue(store.Queue, store.Redis, NewDatabaseQueueService(db))
 
 	return store, nil
 }
@@ -92,6 +114,27 @@ func (s *Store) GetSessionStore() SessionStoreInterface {
 	return NewDatabaseSessionStore(s.DB)
 }
 
+// GetIdempotencyStore returns appropriate idempotency store (Redis preferred, fallback to DB)
+func (s *Store) GetIdempotencyStore() IdempotencyStore {
+	if s.IsRedisAvailable() {
+		return s.Idempotency
+	}
+	return NewDatabaseIdempotencyStore(s.DB)
+}
+
+// GetTokenRevocationStore returns appropriate token revocation store (Redis preferred, fallback to DB)
+func (s *Store) GetTokenRevocationStore() TokenRevocationStore {
+	if s.IsRedisAvailable() {
+		return s.TokenRevocation
+	}
+	return NewDatabaseTokenRevocationStore(s.DB)
+}
+
+// GetJobQueue returns the job queue (Redis preferred, fallback to DB)
+func (s *Store) GetJobQueue() JobQueue {
+	return s.JobQueue
+}
+
 // SessionStoreInterface defines common session operations
 type SessionStoreInterface interface {
 	// Redis-style session operations (for generic session data)
@@ -106,6 +149,12 @@ type SessionStoreInterface interface {
 	GetSessionByToken(refreshToken string) (*models.Session, error)
 	RevokeSession(refreshToken string) error
 	RevokeAllUserSessions(userID uint) error
+	ListUserSessions(userID uint) ([]models.Session, error)
+
+	// Refresh token rotation: the old session is kept, marked as rotated, so
+	// a replayed token can be recognised and its whole family revoked
+	RotateSession(oldRefreshToken string, next *models.Session) error
+	RevokeSessionFamily(familyID string) error
 }
 
 // DatabaseSessionStore implements SessionStoreInterface using database
@@ -169,4 +218,19 @@ func (d *DatabaseSessionStore) RevokeSession(refreshToken string) error {
 func (d *DatabaseSessionStore) RevokeAllUserSessions(userID uint) error {
 	sessionStore := NewSessionStore(d.db)
 	return sessionStore.RevokeAllUserSessions(userID)
-}
\ No newline at end of file
+}
+
+func (d *DatabaseSessionStore) ListUserSessions(userID uint) ([]models.Session, error) {
+	sessionStore := NewSessionStore(d.db)
+	return sessionStore.ListUserSessions(userID)
+}
+
+func (d *DatabaseSessionStore) RotateSession(oldRefreshToken string, next *models.Session) error {
+	sessionStore := NewSessionStore(d.db)
+	return sessionStore.RotateSession(oldRefreshToken, next)
+}
+
+func (d *DatabaseSessionStore) RevokeSessionFamily(familyID string) error {
+	sessionStore := NewSessionStore(d.db)
+	return sessionStore.RevokeSessionFamily(familyID)
+}
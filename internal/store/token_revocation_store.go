@@ -0,0 +1,87 @@
+package store
+
+import (
+	"errors"
+	"time"
+
+	"go-vibe-friend/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TokenRevocationStore defines the access token denylist and the per-user
+// watermark that revokes every token issued up to a point in time
+type TokenRevocationStore interface {
+	// RevokeToken denylists the token with the given jti until it expires
+	RevokeToken(jti string, userID uint, expiresAt time.Time) error
+	// RevokeUserTokens revokes the user's tokens issued up to before. The
+	// watermark is kept for ttl, after which those tokens have expired anyway.
+	RevokeUserTokens(userID uint, before time.Time, ttl time.Duration) error
+	// IsRevoked reports whether the token is denylisted or was issued at or
+	// before the user's watermark
+	IsRevoked(jti string, userID uint, issuedAt time.Time) (bool, error)
+}
+
+// issuedBefore reports whether a token issued at issuedAt is covered by the
+// watermark. iat has millisecond precision, so only a token issued in the
+// same millisecond as the watermark counts as issued before it.
+func issuedBefore(issuedAt, watermark time.Time) bool {
+	return issuedAt.UnixMilli() <= watermark.UnixMilli()
+}
+
+// DatabaseTokenRevocationStore implements TokenRevocationStore using the database
+type DatabaseTokenRevocationStore struct {
+	db *Database
+}
+
+// NewDatabaseTokenRevocationStore creates a database-backed token revocation store
+func NewDatabaseTokenRevocationStore(db *Database) *DatabaseTokenRevocationStore {
+	return &DatabaseTokenRevocationStore{db: db}
+}
+
+// RevokeToken inserts a denylist row, ignoring tokens that are already revoked
+func (s *DatabaseTokenRevocationStore) RevokeToken(jti string, userID uint, expiresAt time.Time) error {
+	row := models.RevokedToken{JTI: jti, UserID: userID, ExpiresAt: expiresAt}
+	return s.db.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&row).Error
+}
+
+// RevokeUserTokens creates or moves the user's watermark
+func (s *DatabaseTokenRevocationStore) RevokeUserTokens(userID uint, before time.Time, ttl time.Duration) error {
+	row := models.TokenWatermark{UserID: userID, RevokedBefore: before, ExpiresAt: before.Add(ttl)}
+	return s.db.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"revoked_before", "expires_at", "updated_at"}),
+	}).Create(&row).Error
+}
+
+// IsRevoked checks the user's watermark and the denylist
+func (s *DatabaseTokenRevocationStore) IsRevoked(jti string, userID uint, issuedAt time.Time) (bool, error) {
+	var watermark models.TokenWatermark
+	err := s.db.DB.Where("user_id = ?", userID).First(&watermark).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, err
+	}
+	if err == nil && issuedBefore(issuedAt, watermark.RevokedBefore) {
+		return true, nil
+	}
+
+	if jti == "" {
+		return false, nil
+	}
+	var count int64
+	if err := s.db.DB.Model(&models.RevokedToken{}).Where("jti = ?", jti).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// CleanupExpired deletes denylist rows and watermarks that no longer cover
+// any unexpired token
+func (s *DatabaseTokenRevocationStore) CleanupExpired() error {
+	now := time.Now()
+	if err := s.db.DB.Where("expires_at <= ?", now).Delete(&models.RevokedToken{}).Error; err != nil {
+		return err
+	}
+	return s.db.DB.Where("expires_at <= ?", now).Delete(&models.TokenWatermark{}).Error
+}
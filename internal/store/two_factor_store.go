@@ -0,0 +1,147 @@
+package store
+
+import (
+	"errors"
+	"time"
+
+	"go-vibe-friend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type TwoFactorStore struct {
+	db *Database
+}
+
+func NewTwoFactorStore(db *Database) *TwoFactorStore {
+	return &TwoFactorStore{db: db}
+}
+
+// GetTwoFactor returns the user's two-factor settings, or nil if the user
+// never enrolled
+func (s *TwoFactorStore) GetTwoFactor(userID uint) (*models.TwoFactorAuth, error) {
+	var tf models.TwoFactorAuth
+	err := s.db.DB.Where("user_id = ?", userID).First(&tf).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &tf, err
+}
+
+// SaveTwoFactor creates or updates the user's two-factor settings
+func (s *TwoFactorStore) SaveTwoFactor(tf *models.TwoFactorAuth) error {
+	return s.db.DB.Save(tf).Error
+}
+
+// DeleteTwoFactor removes the user's two-factor settings and recovery codes
+func (s *TwoFactorStore) DeleteTwoFactor(userID uint) error {
+	return s.db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.TwoFactorRecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.TwoFactorAuth{}).Error
+	})
+}
+
+// UseStep records step as the last used TOTP time step. It reports false if
+// the step, or a later one, was already used, so each code works only once.
+func (s *TwoFactorStore) UseStep(userID uint, step int64) (bool, error) {
+	result := s.db.DB.Model(&models.TwoFactorAuth{}).
+		Where("user_id = ? AND last_used_step < ?", userID, step).
+		Update("last_used_step", step)
+	return result.RowsAffected > 0, result.Error
+}
+
+// EnableTwoFactor enables two-factor authentication and replaces the
+// recovery codes in one transaction
+func (s *TwoFactorStore) EnableTwoFactor(tf *models.TwoFactorAuth, codeHashes []string) error {
+	return s.db.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		tf.Enabled = true
+		tf.ConfirmedAt = &now
+		if err := tx.Save(tf).Error; err != nil {
+			return err
+		}
+		return replaceRecoveryCodes(tx, tf.UserID, codeHashes)
+	})
+}
+
+// ReplaceRecoveryCodes discards the user's recovery codes and stores new ones
+func (s *TwoFactorStore) ReplaceRecoveryCodes(userID uint, codeHashes []string) error {
+	return s.db.DB.Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, userID, codeHashes)
+	})
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userID uint, codeHashes []string) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.TwoFactorRecoveryCode{}).Error; err != nil {
+		return err
+	}
+	codes := make([]models.TwoFactorRecoveryCode, len(codeHashes))
+	for i, hash := range codeHashes {
+		codes[i] = models.TwoFactorRecoveryCode{UserID: userID, CodeHash: hash}
+	}
+	if len(codes) == 0 {
+		return nil
+	}
+	return tx.Create(&codes).Error
+}
+
+// UseRecoveryCode marks the unused recovery code with the given hash as used
+// and reports whether there was one
+func (s *TwoFactorStore) UseRecoveryCode(userID uint, codeHash string) (bool, error) {
+	result := s.db.DB.Model(&models.TwoFactorRecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	return result.RowsAffected > 0, result.Error
+}
+
+// CountRecoveryCodes returns how many unused recovery codes the user has left
+func (s *TwoFactorStore) CountRecoveryCodes(userID uint) (int64, error) {
+	var count int64
+	err := s.db.DB.Model(&models.TwoFactorRecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
+
+// CreateChallenge stores a login challenge
+func (s *TwoFactorStore) CreateChallenge(challenge *models.TwoFactorChallenge) error {
+	return s.db.DB.Create(challenge).Error
+}
+
+// GetChallengeByHash returns the challenge with the given token hash, or nil
+func (s *TwoFactorStore) GetChallengeByHash(tokenHash string) (*models.TwoFactorChallenge, error) {
+	var challenge models.TwoFactorChallenge
+	err := s.db.DB.Where("token_hash = ?", tokenHash).First(&challenge).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &challenge, err
+}
+
+// RecordChallengeAttempt counts a failed attempt against the challenge and
+// deletes it once maxAttempts is reached. It reports whether the challenge
+// can still be used.
+func (s *TwoFactorStore) RecordChallengeAttempt(id uint, maxAttempts int) (bool, error) {
+	err := s.db.DB.Model(&models.TwoFactorChallenge{}).
+		Where("id = ?", id).
+		Update("attempts", gorm.Expr("attempts + 1")).Error
+	if err != nil {
+		return false, err
+	}
+	result := s.db.DB.Where("id = ? AND attempts >= ?", id, maxAttempts).Delete(&models.TwoFactorChallenge{})
+	return result.RowsAffected == 0, result.Error
+}
+
+// DeleteChallenge deletes a challenge and reports whether it still existed,
+// so a challenge can only be completed once
+func (s *TwoFactorStore) DeleteChallenge(id uint) (bool, error) {
+	result := s.db.DB.Delete(&models.TwoFactorChallenge{}, id)
+	return result.RowsAffected > 0, result.Error
+}
+
+// CleanupExpiredChallenges deletes expired login challenges
+func (s *TwoFactorStore) CleanupExpiredChallenges() error {
+	return s.db.DB.Where("expires_at <= ?", time.Now()).Delete(&models.TwoFactorChallenge{}).Error
+}
@@ -3,6 +3,7 @@ package store
 import (
 	"errors"
 	"fmt"
+	"strings"
 
 	"go-vibe-friend/internal/models"
 
@@ -156,4 +157,77 @@ func (s *UserStore) CreateProfile(userID uint, displayName, bio, location, websi
 type TimeSeriesData struct {
 	Date  string `json:"date"`
 	Count int64  `json:"count"`
-}
\ No newline at end of file
+}
+
+// GetTakenEmailsAndUsernames reports which of the given emails and usernames
+// are already used, including by deleted users since they still hold the
+// unique index. Emails are compared case-insensitively and returned lowercased.
+func (s *UserStore) GetTakenEmailsAndUsernames(emails, usernames []string) (map[string]bool, map[string]bool, error) {
+	takenEmails := make(map[string]bool)
+	takenUsernames := make(map[string]bool)
+
+	for start := 0; start < len(emails); start += lookupBatchSize {
+		batch := lowerAll(emails[start:min(start+lookupBatchSize, len(emails))])
+		var found []string
+		err := s.db.DB.Unscoped().Model(&models.User{}).Where("LOWER(email) IN ?", batch).Pluck("LOWER(email)", &found).Error
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, email := range found {
+			takenEmails[email] = true
+		}
+	}
+
+	for start := 0; start < len(usernames); start += lookupBatchSize {
+		batch := usernames[start:min(start+lookupBatchSize, len(usernames))]
+		var found []string
+		err := s.db.DB.Unscoped().Model(&models.User{}).Where("username IN ?", batch).Pluck("username", &found).Error
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, username := range found {
+			takenUsernames[username] = true
+		}
+	}
+
+	return takenEmails, takenUsernames, nil
+}
+
+// lookupBatchSize caps the number of values bound in a single IN query
+const lookupBatchSize = 500
+
+func lowerAll(values []string) []string {
+	lowered := make([]string, len(values))
+	for i, value := range values {
+		lowered[i] = strings.ToLower(value)
+	}
+	return lowered
+}
+
+// CreateUsersWithRoles creates the users together with their role
+// assignments and default profiles in a single transaction; roleIDs[i] lists
+// the roles of users[i]. Nothing is created if any insert fails.
+func (s *UserStore) CreateUsersWithRoles(users []models.User, roleIDs [][]uint) error {
+	return s.db.DB.Transaction(func(tx *gorm.DB) error {
+		for i := range users {
+			if err := tx.Create(&users[i]).Error; err != nil {
+				return fmt.Errorf("failed to create user %q: %w", users[i].Email, err)
+			}
+			for _, roleID := range roleIDs[i] {
+				if err := tx.Create(&models.UserRole{UserID: users[i].ID, RoleID: roleID}).Error; err != nil {
+					return fmt.Errorf("failed to assign role to user %q: %w", users[i].Email, err)
+				}
+			}
+			profile := &models.Profile{UserID: users[i].ID, DisplayName: users[i].Username}
+			if err := tx.Create(profile).Error; err != nil {
+				return fmt.Errorf("failed to create profile of user %q: %w", users[i].Email, err)
+			}
+		}
+		return nil
+	})
+}
+
+// CreateAuditLog records an audit log entry
+func (s *UserStore) CreateAuditLog(log *models.AuditLog) error {
+	return s.db.DB.Create(log).Error
+}
@@ -0,0 +1,121 @@
+package store
+
+import (
+	"errors"
+	"time"
+
+	"go-vibe-friend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type WebAuthnStore struct {
+	db *Database
+}
+
+func NewWebAuthnStore(db *Database) *WebAuthnStore {
+	return &WebAuthnStore{db: db}
+}
+
+// CreateCredential stores a newly registered credential
+func (s *WebAuthnStore) CreateCredential(credential *models.WebAuthnCredential) error {
+	return s.db.DB.Create(credential).Error
+}
+
+// GetCredentialByCredentialID returns the credential with the given
+// base64url credential ID, or nil
+func (s *WebAuthnStore) GetCredentialByCredentialID(credentialID string) (*models.WebAuthnCredential, error) {
+	var credential models.WebAuthnCredential
+	err := s.db.DB.Where("credential_id = ?", credentialID).First(&credential).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &credential, err
+}
+
+// GetUserCredential returns one of the user's credentials, or nil
+func (s *WebAuthnStore) GetUserCredential(userID, id uint) (*models.WebAuthnCredential, error) {
+	var credential models.WebAuthnCredential
+	err := s.db.DB.Where("id = ? AND user_id = ?", id, userID).First(&credential).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &credential, err
+}
+
+// ListCredentials returns the user's credentials, oldest first
+func (s *WebAuthnStore) ListCredentials(userID uint) ([]models.WebAuthnCredential, error) {
+	var credentials []models.WebAuthnCredential
+	err := s.db.DB.Where("user_id = ?", userID).Order("created_at ASC").Find(&credentials).Error
+	return credentials, err
+}
+
+// RenameCredential renames one of the user's credentials and reports whether
+// it exists
+func (s *WebAuthnStore) RenameCredential(userID, id uint, name string) (bool, error) {
+	result := s.db.DB.Model(&models.WebAuthnCredential{}).
+		Where("id = ? AND user_id = ?", id, userID).
+		Update("name", name)
+	return result.RowsAffected > 0, result.Error
+}
+
+// DeleteCredential deletes one of the user's credentials and reports whether
+// it existed
+func (s *WebAuthnStore) DeleteCredential(userID, id uint) (bool, error) {
+	result := s.db.DB.Where("id = ? AND user_id = ?", id, userID).Delete(&models.WebAuthnCredential{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// RecordUse stores the sign count and backup state of a successful login.
+// The update only applies if the sign count is still oldCount, so two
+// concurrent logins cannot both move the counter; it reports whether it applied.
+func (s *WebAuthnStore) RecordUse(id uint, oldCount, newCount uint32, backupState bool) (bool, error) {
+	result := s.db.DB.Model(&models.WebAuthnCredential{}).
+		Where("id = ? AND sign_count = ?", id, oldCount).
+		Updates(map[string]interface{}{
+			"sign_count":   newCount,
+			"backup_state": backupState,
+			"last_used_at": time.Now(),
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// MarkCloneDetected flags a credential whose sign count went backwards
+func (s *WebAuthnStore) MarkCloneDetected(id uint) error {
+	return s.db.DB.Model(&models.WebAuthnCredential{}).
+		Where("id = ?", id).
+		Update("clone_detected", true).Error
+}
+
+// CreateChallenge stores a registration or login challenge
+func (s *WebAuthnStore) CreateChallenge(challenge *models.WebAuthnChallenge) error {
+	return s.db.DB.Create(challenge).Error
+}
+
+// ConsumeChallenge deletes the challenge with the given hash and returns it,
+// or nil if there is none. Deleting first means each challenge is used once,
+// even by concurrent requests.
+func (s *WebAuthnStore) ConsumeChallenge(challengeHash string) (*models.WebAuthnChallenge, error) {
+	var challenge models.WebAuthnChallenge
+	err := s.db.DB.Where("challenge_hash = ?", challengeHash).First(&challenge).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := s.db.DB.Delete(&models.WebAuthnChallenge{}, challenge.ID)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &challenge, nil
+}
+
+// CleanupExpiredChallenges deletes expired challenges
+func (s *WebAuthnStore) CleanupExpiredChallenges() error {
+	return s.db.DB.Where("expires_at <= ?", time.Now()).Delete(&models.WebAuthnChallenge{}).Error
+}
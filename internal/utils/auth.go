@@ -3,6 +3,7 @@ package utils
 import (
 	"crypto/rand"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"time"
 
@@ -10,7 +11,11 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
-var jwtSecret = []byte("your-secret-key-change-this-in-production")
+func init() {
+	// iat/exp carry milliseconds so a token can be told apart from a
+	// revocation watermark set in the same second
+	jwt.TimePrecision = time.Millisecond
+}
 
 type Claims struct {
 	UserID   uint   `json:"user_id"`
@@ -28,45 +33,53 @@ func CheckPassword(password, hash string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 }
 
+// GenerateJWT 使用当前签名密钥签发访问令牌，令牌头部的 kid 标明所用密钥，
+// jti 用于单独撤销该令牌
 func GenerateJWT(userID uint, username, role string) (string, error) {
-	expirationTime := time.Now().Add(15 * time.Minute) // 15分钟有效期
+	ks, err := currentKeySet()
+	if err != nil {
+		return "", err
+	}
+
+	tokenID, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
 	claims := &Claims{
 		UserID:   userID,
 		Username: username,
 		Role:     role,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        tokenID,
+			Issuer:    ks.issuer,
+			ExpiresAt: jwt.NewNumericDate(now.Add(ks.accessTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	return ks.sign(claims)
 }
 
 func ValidateJWT(tokenString string) (uint, error) {
-	claims := &Claims{}
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return jwtSecret, nil
-	})
-
+	claims, err := ValidateJWTWithClaims(tokenString)
 	if err != nil {
 		return 0, err
 	}
 
-	if !token.Valid {
-		return 0, errors.New("invalid token")
-	}
-
 	return claims.UserID, nil
 }
 
+// ValidateJWTWithClaims 使用 kid 对应的密钥校验令牌并返回其声明
 func ValidateJWTWithClaims(tokenString string) (*Claims, error) {
-	claims := &Claims{}
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return jwtSecret, nil
-	})
+	ks, err := currentKeySet()
+	if err != nil {
+		return nil, err
+	}
 
+	claims := &Claims{}
+	token, err := ks.parse(tokenString, claims)
 	if err != nil {
 		return nil, err
 	}
@@ -78,6 +91,15 @@ func ValidateJWTWithClaims(tokenString string) (*Claims, error) {
 	return claims, nil
 }
 
+// newTokenID 生成访问令牌的 jti
+func newTokenID() (string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
 // GenerateRefreshToken 生成刷新令牌
 func GenerateRefreshToken() (string, error) {
 	bytes := make([]byte, 32)
@@ -0,0 +1,414 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"go-vibe-friend/internal/config"
+)
+
+const (
+	// defaultAccessTTL is the access token lifetime when none is configured
+	defaultAccessTTL = 15 * time.Minute
+	// minHMACSecretLength is the shortest HS256 secret accepted, in bytes
+	minHMACSecretLength = 32
+	// minRSAKeyBits is the smallest RSA modulus accepted
+	minRSAKeyBits = 2048
+)
+
+// ErrNoJWTKeys is returned by LoadKeySet when the config defines no keys
+var ErrNoJWTKeys = errors.New("no JWT signing keys configured")
+
+// keySet is the key set used by GenerateJWT and the Validate functions
+var keySet atomic.Pointer[KeySet]
+
+// KeySet holds the keys access tokens are signed and verified with. Tokens
+// are signed with a single key and carry its ID in the kid header; any key
+// in the set verifies the tokens it signed.
+type KeySet struct {
+	signing   *jwtKey
+	keys      map[string]*jwtKey
+	methods   []string // algorithms accepted on validation
+	issuer    string
+	accessTTL time.Duration
+}
+
+type jwtKey struct {
+	id        string
+	method    jwt.SigningMethod
+	signKey   interface{} // nil for verification-only keys
+	verifyKey interface{}
+}
+
+// SetKeySet makes ks the key set used to sign and validate tokens
+func SetKeySet(ks *KeySet) {
+	keySet.Store(ks)
+}
+
+// currentKeySet returns the configured key set
+func currentKeySet() (*KeySet, error) {
+	ks := keySet.Load()
+	if ks == nil {
+		return nil, ErrNoJWTKeys
+	}
+	return ks, nil
+}
+
+// LoadKeySet reads the keys configured in cfg. It returns ErrNoJWTKeys when
+// cfg defines no keys at all.
+func LoadKeySet(cfg config.JWTConfig) (*KeySet, error) {
+	ks := newKeySet(cfg)
+
+	for _, keyCfg := range cfg.Keys {
+		key, err := parseKeyConfig(keyCfg)
+		if err != nil {
+			return nil, fmt.Errorf("jwt key %q: %w", keyCfg.ID, err)
+		}
+		if err := ks.add(key); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.KeysDir != "" {
+		paths, err := filepath.Glob(filepath.Join(cfg.KeysDir, "*.pem"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			id := strings.TrimSuffix(filepath.Base(path), ".pem")
+			key, err := parsePEMKey(id, "", data)
+			if err != nil {
+				return nil, fmt.Errorf("jwt key file %s: %w", path, err)
+			}
+			if err := ks.add(key); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if cfg.Secret != "" {
+		key, err := hmacKey("default", cfg.Secret)
+		if err != nil {
+			return nil, fmt.Errorf("jwt secret: %w", err)
+		}
+		if err := ks.add(key); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(ks.keys) == 0 {
+		return nil, ErrNoJWTKeys
+	}
+	if err := ks.selectSigningKey(cfg.SigningKey); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+// NewEphemeralKeySet generates a random Ed25519 key that only lives as long
+// as the process. Tokens it signs become invalid on restart and are not
+// accepted by other replicas, so it is only meant for development.
+func NewEphemeralKeySet(cfg config.JWTConfig) (*KeySet, error) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, err
+	}
+
+	ks := newKeySet(cfg)
+	key := &jwtKey{
+		id:        "ephemeral-" + hex.EncodeToString(suffix),
+		method:    jwt.SigningMethodEdDSA,
+		signKey:   private,
+		verifyKey: public,
+	}
+	if err := ks.add(key); err != nil {
+		return nil, err
+	}
+	ks.signing = key
+	return ks, nil
+}
+
+func newKeySet(cfg config.JWTConfig) *KeySet {
+	accessTTL := cfg.AccessTTL
+	if accessTTL <= 0 {
+		accessTTL = defaultAccessTTL
+	}
+	return &KeySet{
+		keys:      make(map[string]*jwtKey),
+		issuer:    cfg.Issuer,
+		accessTTL: accessTTL,
+	}
+}
+
+func (ks *KeySet) add(key *jwtKey) error {
+	if key.id == "" {
+		return errors.New("jwt key id is required")
+	}
+	if _, ok := ks.keys[key.id]; ok {
+		return fmt.Errorf("duplicate jwt key id %q", key.id)
+	}
+	ks.keys[key.id] = key
+
+	alg := key.method.Alg()
+	for _, method := range ks.methods {
+		if method == alg {
+			return nil
+		}
+	}
+	ks.methods = append(ks.methods, alg)
+	return nil
+}
+
+// selectSigningKey picks the key with the given ID, or the only key that
+// can sign when id is empty
+func (ks *KeySet) selectSigningKey(id string) error {
+	if id != "" {
+		key, ok := ks.keys[id]
+		if !ok {
+			return fmt.Errorf("jwt signing key %q is not configured", id)
+		}
+		if key.signKey == nil {
+			return fmt.Errorf("jwt signing key %q has no private key", id)
+		}
+		ks.signing = key
+		return nil
+	}
+
+	var candidates []string
+	for kid, key := range ks.keys {
+		if key.signKey != nil {
+			candidates = append(candidates, kid)
+			ks.signing = key
+		}
+	}
+	switch len(candidates) {
+	case 0:
+		return errors.New("no jwt key can sign tokens, configure a private key or secret")
+	case 1:
+		return nil
+	default:
+		sort.Strings(candidates)
+		return fmt.Errorf("several jwt keys can sign tokens (%s), set jwt.signing_key", strings.Join(candidates, ", "))
+	}
+}
+
+// SigningKeyID returns the kid of the key new tokens are signed with
+func (ks *KeySet) SigningKeyID() string {
+	return ks.signing.id
+}
+
+// SigningAlgorithm returns the algorithm new tokens are signed with
+func (ks *KeySet) SigningAlgorithm() string {
+	return ks.signing.method.Alg()
+}
+
+// sign signs claims with the signing key and sets the kid header
+func (ks *KeySet) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.signing.method, claims)
+	token.Header["kid"] = ks.signing.id
+	return token.SignedString(ks.signing.signKey)
+}
+
+// parse validates tokenString against the key named by its kid header.
+// Tokens without a kid are only accepted when the set has a single key.
+func (ks *KeySet) parse(tokenString string, claims jwt.Claims) (*jwt.Token, error) {
+	options := []jwt.ParserOption{jwt.WithValidMethods(ks.methods)}
+	if ks.issuer != "" {
+		options = append(options, jwt.WithIssuer(ks.issuer))
+	}
+
+	return jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		var key *jwtKey
+		if kid, ok := token.Header["kid"].(string); ok {
+			key = ks.keys[kid]
+		} else if len(ks.keys) == 1 {
+			for _, only := range ks.keys {
+				key = only
+			}
+		}
+		if key == nil {
+			return nil, errors.New("unknown signing key")
+		}
+		// Each key is bound to one algorithm, so a token cannot be verified
+		// with a key meant for another algorithm
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, fmt.Errorf("unexpected signing algorithm %s", token.Method.Alg())
+		}
+		return key.verifyKey, nil
+	}, options...)
+}
+
+// JWK is a public key in JSON Web Key format (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`   // RSA modulus
+	E   string `json:"e,omitempty"`   // RSA exponent
+	Crv string `json:"crv,omitempty"` // OKP curve
+	X   string `json:"x,omitempty"`   // OKP public key
+}
+
+// JWKS is a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys of the asymmetric keys in the set, ordered by
+// kid. HS256 secrets are never published.
+func (ks *KeySet) JWKS() JWKS {
+	ids := make([]string, 0, len(ks.keys))
+	for kid := range ks.keys {
+		ids = append(ids, kid)
+	}
+	sort.Strings(ids)
+
+	set := JWKS{Keys: []JWK{}}
+	for _, kid := range ids {
+		key := ks.keys[kid]
+		jwk := JWK{Kid: kid, Use: "sig", Alg: key.method.Alg()}
+		switch public := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+// GetJWKS returns the public keys of the configured key set
+func GetJWKS() JWKS {
+	ks := keySet.Load()
+	if ks == nil {
+		return JWKS{Keys: []JWK{}}
+	}
+	return ks.JWKS()
+}
+
+// parseKeyConfig builds a key from its config entry
+func parseKeyConfig(cfg config.JWTKeyConfig) (*jwtKey, error) {
+	if strings.EqualFold(cfg.Algorithm, "HS256") || (cfg.Algorithm == "" && cfg.Secret != "") {
+		return hmacKey(cfg.ID, cfg.Secret)
+	}
+
+	data, err := pemData(cfg.PrivateKey, cfg.PrivateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		if data, err = pemData(cfg.PublicKey, cfg.PublicKeyFile); err != nil {
+			return nil, err
+		}
+	}
+	if data == nil {
+		return nil, errors.New("a secret, private key or public key is required")
+	}
+	return parsePEMKey(cfg.ID, cfg.Algorithm, data)
+}
+
+// pemData returns the inline PEM or the contents of the file, or nil if both are empty
+func pemData(inline, file string) ([]byte, error) {
+	if inline != "" {
+		return []byte(inline), nil
+	}
+	if file != "" {
+		return os.ReadFile(file)
+	}
+	return nil, nil
+}
+
+func hmacKey(id, secret string) (*jwtKey, error) {
+	if len(secret) < minHMACSecretLength {
+		return nil, fmt.Errorf("HS256 secret must be at least %d bytes", minHMACSecretLength)
+	}
+	return &jwtKey{
+		id:        id,
+		method:    jwt.SigningMethodHS256,
+		signKey:   []byte(secret),
+		verifyKey: []byte(secret),
+	}, nil
+}
+
+// parsePEMKey parses an RSA or Ed25519 key in PEM format. Private keys can
+// sign and verify, public keys only verify. The algorithm follows from the
+// key type; a configured algorithm must match it.
+func parsePEMKey(id, algorithm string, data []byte) (*jwtKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &jwtKey{id: id}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.method, key.signKey, key.verifyKey = jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.method, key.verifyKey = jwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		key.method, key.signKey, key.verifyKey = jwt.SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.method, key.verifyKey = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, fmt.Errorf("unsupported key type %T, use an RSA or Ed25519 key", parsed)
+	}
+
+	if public, ok := key.verifyKey.(*rsa.PublicKey); ok && public.N.BitLen() < minRSAKeyBits {
+		return nil, fmt.Errorf("RSA key must be at least %d bits", minRSAKeyBits)
+	}
+	if algorithm != "" && !strings.EqualFold(algorithm, key.method.Alg()) {
+		return nil, fmt.Errorf("algorithm %s does not match the %s key", algorithm, key.method.Alg())
+	}
+	return key, nil
+}
@@ -0,0 +1,261 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// COSE algorithm identifiers of the supported credential public keys
+const (
+	COSEAlgES256 int64 = -7
+	COSEAlgEdDSA int64 = -8
+	COSEAlgRS256 int64 = -257
+)
+
+// Authenticator data flags (WebAuthn §6.1)
+const (
+	webAuthnFlagUserPresent    = 0x01
+	webAuthnFlagUserVerified   = 0x04
+	webAuthnFlagBackupEligible = 0x08
+	webAuthnFlagBackupState    = 0x10
+	webAuthnFlagAttestedData   = 0x40
+	webAuthnFlagExtensionData  = 0x80
+)
+
+// COSE key parameters (RFC 9053)
+const (
+	coseKeyType  = 1
+	coseKeyAlg   = 3
+	coseKeyCurve = -1 // OKP and EC2
+	coseKeyX     = -2 // OKP and EC2
+	coseKeyY     = -3 // EC2
+	coseKeyN     = -1 // RSA
+	coseKeyE     = -2 // RSA
+
+	coseKeyTypeOKP = 1
+	coseKeyTypeEC2 = 2
+	coseKeyTypeRSA = 3
+
+	coseCurveP256    = 1
+	coseCurveEd25519 = 6
+)
+
+// ErrInvalidWebAuthnData 认证器或浏览器返回的数据格式错误
+var ErrInvalidWebAuthnData = errors.New("invalid webauthn data")
+
+// CollectedClientData 浏览器生成的 clientDataJSON
+type CollectedClientData struct {
+	Type        string `json:"type"` // webauthn.create 或 webauthn.get
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+// ParseClientData 解析 clientDataJSON
+func ParseClientData(raw []byte) (*CollectedClientData, error) {
+	var clientData CollectedClientData
+	if err := json.Unmarshal(raw, &clientData); err != nil {
+		return nil, fmt.Errorf("%w: client data: %v", ErrInvalidWebAuthnData, err)
+	}
+	if clientData.Type == "" || clientData.Challenge == "" || clientData.Origin == "" {
+		return nil, fmt.Errorf("%w: client data is incomplete", ErrInvalidWebAuthnData)
+	}
+	return &clientData, nil
+}
+
+// AuthenticatorData 认证器数据，注册时还包含新凭据的ID和公钥
+type AuthenticatorData struct {
+	RPIDHash  []byte
+	Flags     byte
+	SignCount uint32
+
+	AAGUID       []byte
+	CredentialID []byte
+	PublicKey    []byte // COSE 编码的凭据公钥
+}
+
+func (d *AuthenticatorData) UserPresent() bool    { return d.Flags&webAuthnFlagUserPresent != 0 }
+func (d *AuthenticatorData) UserVerified() bool   { return d.Flags&webAuthnFlagUserVerified != 0 }
+func (d *AuthenticatorData) BackupEligible() bool { return d.Flags&webAuthnFlagBackupEligible != 0 }
+func (d *AuthenticatorData) BackupState() bool    { return d.Flags&webAuthnFlagBackupState != 0 }
+
+// ParseAuthenticatorData 解析认证器数据
+func ParseAuthenticatorData(raw []byte) (*AuthenticatorData, error) {
+	if len(raw) < 37 {
+		return nil, fmt.Errorf("%w: authenticator data is too short", ErrInvalidWebAuthnData)
+	}
+	data := &AuthenticatorData{
+		RPIDHash:  raw[:32],
+		Flags:     raw[32],
+		SignCount: binary.BigEndian.Uint32(raw[33:37]),
+	}
+	rest := raw[37:]
+
+	if data.Flags&webAuthnFlagAttestedData != 0 {
+		// aaguid (16) | credentialIdLength (2) | credentialId | credentialPublicKey
+		if len(rest) < 18 {
+			return nil, fmt.Errorf("%w: attested credential data is too short", ErrInvalidWebAuthnData)
+		}
+		data.AAGUID = rest[:16]
+		idLen := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if idLen == 0 || idLen > 1023 || len(rest) < idLen {
+			return nil, fmt.Errorf("%w: invalid credential ID length", ErrInvalidWebAuthnData)
+		}
+		data.CredentialID = rest[:idLen]
+		rest = rest[idLen:]
+
+		// The public key is a CBOR map of unknown length, decode it to find its end
+		_, n, err := cborDecode(rest)
+		if err != nil {
+			return nil, fmt.Errorf("%w: credential public key: %v", ErrInvalidWebAuthnData, err)
+		}
+		data.PublicKey = rest[:n]
+		rest = rest[n:]
+	}
+
+	if data.Flags&webAuthnFlagExtensionData != 0 {
+		_, n, err := cborDecode(rest)
+		if err != nil {
+			return nil, fmt.Errorf("%w: extensions: %v", ErrInvalidWebAuthnData, err)
+		}
+		rest = rest[n:]
+	}
+
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("%w: trailing bytes in authenticator data", ErrInvalidWebAuthnData)
+	}
+	return data, nil
+}
+
+// ParseAttestationObject 解析注册时返回的 attestationObject，返回认证器数据和证明格式
+func ParseAttestationObject(raw []byte) ([]byte, string, error) {
+	v, n, err := cborDecode(raw)
+	if err != nil || n != len(raw) {
+		return nil, "", fmt.Errorf("%w: attestation object is not valid CBOR", ErrInvalidWebAuthnData)
+	}
+	m, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return nil, "", fmt.Errorf("%w: attestation object is not a map", ErrInvalidWebAuthnData)
+	}
+	format, _ := m["fmt"].(string)
+	authData, _ := m["authData"].([]byte)
+	if format == "" || len(authData) == 0 {
+		return nil, "", fmt.Errorf("%w: attestation object is incomplete", ErrInvalidWebAuthnData)
+	}
+	return authData, format, nil
+}
+
+// COSEKeyAlgorithm 返回 COSE 公钥声明的算法
+func COSEKeyAlgorithm(coseKey []byte) (int64, error) {
+	_, alg, err := parseCOSEKey(coseKey)
+	return alg, err
+}
+
+// VerifyWebAuthnSignature 校验认证器对 authenticatorData || SHA-256(clientDataJSON) 的签名
+func VerifyWebAuthnSignature(coseKey, authData, clientDataJSON, signature []byte) error {
+	key, alg, err := parseCOSEKey(coseKey)
+	if err != nil {
+		return err
+	}
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := make([]byte, 0, len(authData)+len(clientDataHash))
+	signed = append(signed, authData...)
+	signed = append(signed, clientDataHash[:]...)
+
+	var ok bool
+	switch alg {
+	case COSEAlgES256:
+		digest := sha256.Sum256(signed)
+		ok = ecdsa.VerifyASN1(key.(*ecdsa.PublicKey), digest[:], signature)
+	case COSEAlgEdDSA:
+		ok = ed25519.Verify(key.(ed25519.PublicKey), signed, signature)
+	case COSEAlgRS256:
+		digest := sha256.Sum256(signed)
+		ok = rsa.VerifyPKCS1v15(key.(*rsa.PublicKey), crypto.SHA256, digest[:], signature) == nil
+	}
+	if !ok {
+		return errors.New("webauthn signature verification failed")
+	}
+	return nil
+}
+
+// parseCOSEKey 将 COSE 公钥转换为 Go 公钥，只支持 ES256、EdDSA 和 RS256
+func parseCOSEKey(raw []byte) (crypto.PublicKey, int64, error) {
+	v, n, err := cborDecode(raw)
+	if err != nil || n != len(raw) {
+		return nil, 0, fmt.Errorf("%w: public key is not valid CBOR", ErrInvalidWebAuthnData)
+	}
+	m, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return nil, 0, fmt.Errorf("%w: public key is not a map", ErrInvalidWebAuthnData)
+	}
+	kty, _ := m[int64(coseKeyType)].(int64)
+	alg, _ := m[int64(coseKeyAlg)].(int64)
+
+	switch {
+	case kty == coseKeyTypeEC2 && alg == COSEAlgES256:
+		crv, _ := m[int64(coseKeyCurve)].(int64)
+		x, _ := m[int64(coseKeyX)].([]byte)
+		y, _ := m[int64(coseKeyY)].([]byte)
+		if crv != coseCurveP256 || len(x) != 32 || len(y) != 32 {
+			return nil, 0, fmt.Errorf("%w: invalid P-256 public key", ErrInvalidWebAuthnData)
+		}
+		// crypto/ecdh rejects points that are not on the curve
+		point := append(append([]byte{0x04}, x...), y...)
+		if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+			return nil, 0, fmt.Errorf("%w: invalid P-256 public key", ErrInvalidWebAuthnData)
+		}
+		return &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}, alg, nil
+
+	case kty == coseKeyTypeOKP && alg == COSEAlgEdDSA:
+		crv, _ := m[int64(coseKeyCurve)].(int64)
+		x, _ := m[int64(coseKeyX)].([]byte)
+		if crv != coseCurveEd25519 || len(x) != ed25519.PublicKeySize {
+			return nil, 0, fmt.Errorf("%w: invalid Ed25519 public key", ErrInvalidWebAuthnData)
+		}
+		return ed25519.PublicKey(x), alg, nil
+
+	case kty == coseKeyTypeRSA && alg == COSEAlgRS256:
+		nBytes, _ := m[int64(coseKeyN)].([]byte)
+		eBytes, _ := m[int64(coseKeyE)].([]byte)
+		if len(eBytes) == 0 || len(eBytes) > 4 {
+			return nil, 0, fmt.Errorf("%w: invalid RSA public key", ErrInvalidWebAuthnData)
+		}
+		e := new(big.Int).SetBytes(eBytes)
+		key := &rsa.PublicKey{N: new(big.Int).SetBytes(nBytes), E: int(e.Int64())}
+		if key.N.BitLen() < minRSAKeyBits || key.E < 3 || key.E%2 == 0 {
+			return nil, 0, fmt.Errorf("%w: RSA public key is too weak", ErrInvalidWebAuthnData)
+		}
+		return key, alg, nil
+	}
+
+	return nil, 0, fmt.Errorf("%w: unsupported public key algorithm %d", ErrInvalidWebAuthnData, alg)
+}
+
+// DecodeBase64URL 解码 WebAuthn JSON 中的 base64url 字段，兼容带填充的写法
+func DecodeBase64URL(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+// EncodeBase64URL 按 WebAuthn JSON 的格式编码二进制字段
+func EncodeBase64URL(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,367 @@
+package utils
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// XLSXWriter 基于 archive/zip 按行写入 xlsx 文件。
+// 字符串以内联字符串写入，不需要共享字符串表，内存占用与行数无关。
+// 工作表按顺序写入，调用 AddSheet 后之前的工作表不能再写入。
+type XLSXWriter struct {
+	zw     *zip.Writer
+	sheets []string
+	sheet  *bufio.Writer
+	rows   int
+	closed bool
+}
+
+// 单元格样式，对应 styles.xml 中 cellXfs 的下标
+const (
+	xlsxStyleDefault  = 0
+	xlsxStyleHeader   = 1
+	xlsxStyleDateTime = 2
+	xlsxStyleDate     = 3
+)
+
+// xlsxMaxSheetName Excel 工作表名称的最大长度
+const xlsxMaxSheetName = 31
+
+// xlsxEpoch Excel 日期序列号的起点（1900 日期系统）
+var xlsxEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// ErrXLSXClosed 写入已关闭的 xlsx 文件
+var ErrXLSXClosed = errors.New("xlsx writer is closed")
+
+// NewXLSXWriter 创建写入 w 的 xlsx 文件，写完后必须调用 Close
+func NewXLSXWriter(w io.Writer) *XLSXWriter {
+	return &XLSXWriter{zw: zip.NewWriter(w)}
+}
+
+// AddSheet 开始一个新的工作表，名称中 Excel 不允许的字符会被替换
+func (x *XLSXWriter) AddSheet(name string) error {
+	if x.closed {
+		return ErrXLSXClosed
+	}
+	if err := x.endSheet(); err != nil {
+		return err
+	}
+
+	x.sheets = append(x.sheets, x.uniqueSheetName(name))
+	entry, err := x.zw.Create(fmt.Sprintf("xl/worksheets/sheet%d.xml", len(x.sheets)))
+	if err != nil {
+		return err
+	}
+	x.sheet = bufio.NewWriter(entry)
+	x.rows = 0
+	_, err = x.sheet.WriteString(xml.Header + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	return err
+}
+
+// WriteHeader 写入加粗并带底色的表头行
+func (x *XLSXWriter) WriteHeader(headers []string) error {
+	cells := make([]interface{}, len(headers))
+	for i, header := range headers {
+		cells[i] = header
+	}
+	return x.writeRow(cells, xlsxStyleHeader)
+}
+
+// WriteRow 写入一行。字符串写为文本，整数和浮点数写为数字，time.Time 写为日期时间，
+// bool 写为布尔值，nil 和 nil 指针写为空单元格，其他类型按 fmt 格式化为文本
+func (x *XLSXWriter) WriteRow(cells []interface{}) error {
+	return x.writeRow(cells, xlsxStyleDefault)
+}
+
+func (x *XLSXWriter) writeRow(cells []interface{}, style int) error {
+	if x.closed {
+		return ErrXLSXClosed
+	}
+	if x.sheet == nil {
+		if err := x.AddSheet("Sheet1"); err != nil {
+			return err
+		}
+	}
+
+	x.rows++
+	w := x.sheet
+	fmt.Fprintf(w, `<row r="%d">`, x.rows)
+	for i, value := range cells {
+		if err := writeXLSXCell(w, xlsxCellRef(i, x.rows), value, style); err != nil {
+			return err
+		}
+	}
+	_, err := w.WriteString(`</row>`)
+	return err
+}
+
+// Close 结束当前工作表并写入工作簿结构，不会关闭底层的 io.Writer
+func (x *XLSXWriter) Close() error {
+	if x.closed {
+		return nil
+	}
+	if len(x.sheets) == 0 {
+		// 工作簿至少需要一个工作表
+		if err := x.AddSheet("Sheet1"); err != nil {
+			return err
+		}
+	}
+	if err := x.endSheet(); err != nil {
+		return err
+	}
+	x.closed = true
+
+	parts := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", x.contentTypes()},
+		{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`},
+		{"xl/workbook.xml", x.workbook()},
+		{"xl/_rels/workbook.xml.rels", x.workbookRels()},
+		{"xl/styles.xml", xlsxStyles},
+	}
+	for _, part := range parts {
+		entry, err := x.zw.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(entry, part.content); err != nil {
+			return err
+		}
+	}
+	return x.zw.Close()
+}
+
+func (x *XLSXWriter) endSheet() error {
+	if x.sheet == nil {
+		return nil
+	}
+	if _, err := x.sheet.WriteString(`</sheetData></worksheet>`); err != nil {
+		return err
+	}
+	err := x.sheet.Flush()
+	x.sheet = nil
+	return err
+}
+
+// uniqueSheetName 替换 Excel 不允许的字符，截断到 31 个字符并避免重名
+func (x *XLSXWriter) uniqueSheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(name))
+	if name == "" {
+		name = "Sheet"
+	}
+
+	base := truncateRunes(name, xlsxMaxSheetName)
+	candidate := base
+	for n := 2; x.hasSheet(candidate); n++ {
+		suffix := fmt.Sprintf(" (%d)", n)
+		candidate = truncateRunes(base, xlsxMaxSheetName-len(suffix)) + suffix
+	}
+	return candidate
+}
+
+func (x *XLSXWriter) hasSheet(name string) bool {
+	for _, sheet := range x.sheets {
+		if strings.EqualFold(sheet, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func truncateRunes(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max])
+}
+
+func (x *XLSXWriter) contentTypes() string {
+	var b strings.Builder
+	b.WriteString(xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">`)
+	b.WriteString(`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>`)
+	b.WriteString(`<Default Extension="xml" ContentType="application/xml"/>`)
+	b.WriteString(`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`)
+	for i := range x.sheets {
+		fmt.Fprintf(&b, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i+1)
+	}
+	b.WriteString(`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	b.WriteString(`</Types>`)
+	return b.String()
+}
+
+func (x *XLSXWriter) workbook() string {
+	var b strings.Builder
+	b.WriteString(xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	for i, name := range x.sheets {
+		fmt.Fprintf(&b, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, xlsxEscape(name), i+1, i+1)
+	}
+	b.WriteString(`</sheets></workbook>`)
+	return b.String()
+}
+
+func (x *XLSXWriter) workbookRels() string {
+	var b strings.Builder
+	b.WriteString(xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for i := range x.sheets {
+		fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i+1, i+1)
+	}
+	fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, len(x.sheets)+1)
+	b.WriteString(`</Relationships>`)
+	return b.String()
+}
+
+// xlsxStyles 默认样式、表头（加粗、浅蓝底色）、日期时间和日期
+const xlsxStyles = xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<numFmts count="2"><numFmt numFmtId="164" formatCode="yyyy-mm-dd hh:mm:ss"/><numFmt numFmtId="165" formatCode="yyyy-mm-dd"/></numFmts>` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="3"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill>` +
+	`<fill><patternFill patternType="solid"><fgColor rgb="FFD9E1F2"/><bgColor indexed="64"/></patternFill></fill></fills>` +
+	`<borders count="2"><border><left/><right/><top/><bottom/><diagonal/></border>` +
+	`<border><left/><right/><top/><bottom style="thin"><color auto="1"/></bottom><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="4">` +
+	`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="2" borderId="1" xfId="0" applyFont="1" applyFill="1" applyBorder="1"/>` +
+	`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="165" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`</cellXfs>` +
+	`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+	`</styleSheet>`
+
+// xlsxCellRef 返回从 0 开始的列号和从 1 开始的行号对应的单元格引用，如 A1、AB12
+func xlsxCellRef(col, row int) string {
+	name := ""
+	for col++; col > 0; col = (col - 1) / 26 {
+		name = string(rune('A'+(col-1)%26)) + name
+	}
+	return name + strconv.Itoa(row)
+}
+
+// XLSXDateSerial 将时间转换为 Excel 日期序列号，保留时间本身的时区的钟面时间
+func XLSXDateSerial(t time.Time) float64 {
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	return wall.Sub(xlsxEpoch).Hours() / 24
+}
+
+func writeXLSXCell(w *bufio.Writer, ref string, value interface{}, style int) error {
+	numeric := func(s string) error {
+		if style != xlsxStyleDefault {
+			_, err := fmt.Fprintf(w, `<c r="%s" s="%d"><v>%s</v></c>`, ref, style, s)
+			return err
+		}
+		_, err := fmt.Fprintf(w, `<c r="%s"><v>%s</v></c>`, ref, s)
+		return err
+	}
+	text := func(s string) error {
+		styleAttr := ""
+		if style != xlsxStyleDefault {
+			styleAttr = fmt.Sprintf(` s="%d"`, style)
+		}
+		space := ""
+		if s != strings.TrimSpace(s) {
+			space = ` xml:space="preserve"`
+		}
+		_, err := fmt.Fprintf(w, `<c r="%s" t="inlineStr"%s><is><t%s>%s</t></is></c>`, ref, styleAttr, space, xlsxEscape(s))
+		return err
+	}
+	date := func(t time.Time, dateStyle int) error {
+		if style == xlsxStyleDefault {
+			style = dateStyle
+		}
+		return numeric(strconv.FormatFloat(XLSXDateSerial(t), 'f', -1, 64))
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string:
+		return text(v)
+	case []byte:
+		return text(string(v))
+	case bool:
+		b := "0"
+		if v {
+			b = "1"
+		}
+		_, err := fmt.Fprintf(w, `<c r="%s" t="b"><v>%s</v></c>`, ref, b)
+		return err
+	case int:
+		return numeric(strconv.FormatInt(int64(v), 10))
+	case int8:
+		return numeric(strconv.FormatInt(int64(v), 10))
+	case int16:
+		return numeric(strconv.FormatInt(int64(v), 10))
+	case int32:
+		return numeric(strconv.FormatInt(int64(v), 10))
+	case int64:
+		return numeric(strconv.FormatInt(v, 10))
+	case uint:
+		return numeric(strconv.FormatUint(uint64(v), 10))
+	case uint8:
+		return numeric(strconv.FormatUint(uint64(v), 10))
+	case uint16:
+		return numeric(strconv.FormatUint(uint64(v), 10))
+	case uint32:
+		return numeric(strconv.FormatUint(uint64(v), 10))
+	case uint64:
+		return numeric(strconv.FormatUint(v, 10))
+	case float32:
+		return writeXLSXFloat(float64(v), numeric, text)
+	case float64:
+		return writeXLSXFloat(v, numeric, text)
+	case time.Time:
+		if v.IsZero() {
+			return nil
+		}
+		return date(v, xlsxStyleDateTime)
+	case *time.Time:
+		if v == nil || v.IsZero() {
+			return nil
+		}
+		return date(*v, xlsxStyleDateTime)
+	case XLSXDate:
+		if time.Time(v).IsZero() {
+			return nil
+		}
+		return date(time.Time(v), xlsxStyleDate)
+	case fmt.Stringer:
+		return text(v.String())
+	default:
+		return text(fmt.Sprint(v))
+	}
+}
+
+// XLSXDate 只显示日期部分的时间值
+type XLSXDate time.Time
+
+func writeXLSXFloat(v float64, numeric, text func(string) error) error {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return text(strconv.FormatFloat(v, 'f', -1, 64))
+	}
+	return numeric(strconv.FormatFloat(v, 'f', -1, 64))
+}
+
+// xlsxEscape 转义 XML 特殊字符，XML 中不允许的控制字符会被替换
+func xlsxEscape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,59 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go-vibe-friend/internal/service"
+)
+
+// exportTimeout limits how long a single export may run
+const exportTimeout = 30 * time.Minute
+
+// RegisterExportHandlers registers the handler that runs data exports
+// submitted through the admin API
+func RegisterExportHandlers(registry *Registry, exportService *service.ExportService) {
+	registry.Register(service.JobTypeExport, func(ctx context.Context, task *Task) (string, error) {
+		req, err := service.ExportRequestFromPayload(task.Payload)
+		if err != nil {
+			return "", err
+		}
+
+		result, err := exportService.ExportData(ctx, task.Job.UserID, &task.Job.ID, req, percentProgress(ctx, task))
+		if err != nil {
+			return "", err
+		}
+
+		data, err := json.Marshal(result)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode export result: %w", err)
+		}
+		return string(data), nil
+	}, WithTimeout(exportTimeout))
+}
+
+// percentProgress returns a progress callback reporting whole percentages
+// only, leaving 100 for completion; once ctx is done, because the job was
+// cancelled or timed out, it returns ctx's error to abort the work
+func percentProgress(ctx context.Context, task *Task) func(done, total int) error {
+	lastPercent := -1
+	return func(done, total int) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if total <= 0 {
+			return nil
+		}
+		percent := done * 100 / total
+		if percent > 99 {
+			percent = 99
+		}
+		if percent == lastPercent {
+			return nil
+		}
+		lastPercent = percent
+		return task.SetProgress(percent)
+	}
+}
@@ -0,0 +1,32 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go-vibe-friend/internal/service"
+)
+
+// importTimeout limits how long a single user import may run
+const importTimeout = 30 * time.Minute
+
+// RegisterImportHandlers registers the handler that runs user imports too
+// large to process within the admin API request
+func RegisterImportHandlers(registry *Registry, importService *service.UserImportService) {
+	registry.Register(service.JobTypeUserImport, func(ctx context.Context, task *Task) (string, error) {
+		// Validation reports progress up to 99; creating the users and
+		// sending invitations finish the job
+		report, err := importService.RunImportJob(ctx, task.Payload, percentProgress(ctx, task))
+		if err != nil {
+			return "", err
+		}
+
+		data, err := json.Marshal(report)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode import report: %w", err)
+		}
+		return string(data), nil
+	}, WithTimeout(importTimeout))
+}
@@ -0,0 +1,144 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+
+	"go-vibe-friend/internal/service"
+	"go-vibe-friend/internal/store"
+)
+
+// Job types of the built-in maintenance jobs
+const (
+	JobTypeCleanupEmailTokens = "cleanup_email_tokens"
+	JobTypeCleanupSessions    = "cleanup_sessions"
+	JobTypeCleanupFiles       = "cleanup_files"
+	JobTypeCleanupExports     = "cleanup_exports"
+	JobTypeCleanupIdempotency = "cleanup_idempotency_keys"
+	JobTypeCleanupRevocations = "cleanup_token_revocations"
+	JobTypeCleanupChallenges  = "cleanup_two_factor_challenges"
+	JobTypeCleanupWebAuthn    = "cleanup_webauthn_challenges"
+)
+
+// defaultFileRetentionDays is how long soft-deleted files are kept
+const defaultFileRetentionDays = 30
+
+// RegisterMaintenanceHandlers registers the handlers for the built-in cleanup jobs
+func RegisterMaintenanceHandlers(registry *Registry, s *store.Store, exportService *service.ExportService) {
+	registry.Register(JobTypeCleanupEmailTokens, func(ctx context.Context, task *Task) (string, error) {
+		if err := s.Email.CleanupExpiredTokens(); err != nil {
+			return "", err
+		}
+		return "expired email tokens removed", nil
+	})
+
+	registry.Register(JobTypeCleanupSessions, func(ctx context.Context, task *Task) (string, error) {
+		if err := store.NewSessionStore(s.DB).CleanExpiredSessions(); err != nil {
+			return "", err
+		}
+		return "expired sessions removed", nil
+	})
+
+	registry.Register(JobTypeCleanupFiles, func(ctx context.Context, task *Task) (string, error) {
+		days := defaultFileRetentionDays
+		if v, ok := task.Payload["days"].(float64); ok && v > 0 {
+			days = int(v)
+		}
+		if err := s.File.CleanupExpiredFiles(days); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("deleted files older than %d days removed", days), nil
+	})
+
+	registry.Register(JobTypeCleanupExports, func(ctx context.Context, task *Task) (string, error) {
+		removed, err := exportService.CleanupExpiredExports(ctx)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%d expired exports removed", removed), nil
+	})
+
+	registry.Register(JobTypeCleanupIdempotency, func(ctx context.Context, task *Task) (string, error) {
+		if err := store.NewDatabaseIdempotencyStore(s.DB).CleanupExpired(); err != nil {
+			return "", err
+		}
+		return "expired idempotency keys removed", nil
+	})
+
+	registry.Register(JobTypeCleanupRevocations, func(ctx context.Context, task *Task) (string, error) {
+		if err := store.NewDatabaseTokenRevocationStore(s.DB).CleanupExpired(); err != nil {
+			return "", err
+		}
+		return "expired token revocations removed", nil
+	})
+
+	registry.Register(JobTypeCleanupChallenges, func(ctx context.Context, task *Task) (string, error) {
+		if err := s.TwoFactor.CleanupExpiredChallenges(); err != nil {
+			return "", err
+		}
+		return "expired two-factor login challenges removed", nil
+	})
+
+	registry.Register(JobTypeCleanupWebAuthn, func(ctx context.Context, task *Task) (string, error) {
+		if err := s.WebAuthn.CleanupExpiredChallenges(); err != nil {
+			return "", err
+		}
+		return "expired passkey challenges removed", nil
+	})
+}
+
+// MaintenanceSchedules returns the default recurring schedules for the
+// built-in cleanup jobs; administrators can change or disable them later
+func MaintenanceSchedules() []service.ScheduleRequest {
+	return []service.ScheduleRequest{
+		{
+			Name:        "cleanup-email-tokens",
+			Description: "清理过期的邮箱验证和密码重置令牌",
+			CronExpr:    "0 * * * *",
+			JobType:     JobTypeCleanupEmailTokens,
+		},
+		{
+			Name:        "cleanup-sessions",
+			Description: "清理过期的登录会话",
+			CronExpr:    "15 * * * *",
+			JobType:     JobTypeCleanupSessions,
+		},
+		{
+			Name:        "cleanup-files",
+			Description: "清理已删除超过保留期的文件记录",
+			CronExpr:    "30 3 * * *",
+			JobType:     JobTypeCleanupFiles,
+			Payload:     map[string]interface{}{"days": defaultFileRetentionDays},
+		},
+		{
+			Name:        "cleanup-exports",
+			Description: "从对象存储中删除过期的导出文件",
+			CronExpr:    "45 * * * *",
+			JobType:     JobTypeCleanupExports,
+		},
+		{
+			Name:        "cleanup-idempotency-keys",
+			Description: "清理数据库中过期的幂等键",
+			CronExpr:    "50 * * * *",
+			JobType:     JobTypeCleanupIdempotency,
+		},
+		{
+			Name:        "cleanup-token-revocations",
+			Description: "清理数据库中已过期令牌的撤销记录",
+			CronExpr:    "55 * * * *",
+			JobType:     JobTypeCleanupRevocations,
+		},
+		{
+			Name:        "cleanup-two-factor-challenges",
+			Description: "清理过期的两步验证登录挑战",
+			CronExpr:    "5 * * * *",
+			JobType:     JobTypeCleanupChallenges,
+		},
+		{
+			Name:        "cleanup-webauthn-challenges",
+			Description: "清理过期的通行密钥注册和登录质询",
+			CronExpr:    "10 * * * *",
+			JobType:     JobTypeCleanupWebAuthn,
+		},
+	}
+}
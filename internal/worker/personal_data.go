@@ -0,0 +1,53 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"go-vibe-friend/internal/service"
+)
+
+// personalDataTimeout limits how long building a personal data bundle may run
+const personalDataTimeout = 30 * time.Minute
+
+// RegisterPersonalDataHandlers registers the handler that builds the personal
+// data bundle a user requested for themselves and stores it with the exports
+func RegisterPersonalDataHandlers(registry *Registry, personalDataService *service.PersonalDataService) {
+	registry.Register(service.JobTypePersonalDataExport, func(ctx context.Context, task *Task) (string, error) {
+		// The bundle is built in a temporary file first so its size is known
+		// when it is uploaded
+		tmp, err := os.CreateTemp("", "personal-data-*.zip")
+		if err != nil {
+			return "", fmt.Errorf("failed to create temporary file: %w", err)
+		}
+		defer os.Remove(tmp.Name())
+		defer tmp.Close()
+
+		manifest, err := personalDataService.WriteBundle(ctx, tmp, task.Job.UserID, percentProgress(ctx, task))
+		if err != nil {
+			return "", err
+		}
+		size, err := tmp.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return "", err
+		}
+		if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+			return "", err
+		}
+
+		result, err := personalDataService.SaveBundle(ctx, task.Job, tmp, size, manifest)
+		if err != nil {
+			return "", err
+		}
+
+		data, err := json.Marshal(result)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode personal data result: %w", err)
+		}
+		return string(data), nil
+	}, WithTimeout(personalDataTimeout))
+}
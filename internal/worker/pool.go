@@ -0,0 +1,520 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"go-vibe-friend/internal/config"
+	"go-vibe-friend/internal/models"
+	"go-vibe-friend/internal/service"
+	"go-vibe-friend/internal/store"
+)
+
+// Pool runs a configurable number of workers per queue, executing the
+// registered handler for every job it dequeues. In reliable mode jobs are
+// leased rather than popped, so handlers must tolerate being run more than
+// once for the same job.
+type Pool struct {
+	queue      store.JobQueue
+	jobService *service.JobService
+	files      *service.FileService // stores job artifacts; nil disables them
+	registry   *Registry
+	cfg        config.WorkerConfig
+
+	// stopCtx stops the dequeue loops; jobCtx is only cancelled when a
+	// graceful shutdown runs out of time and running jobs must be aborted.
+	stopCtx   context.Context
+	stop      context.CancelFunc
+	jobCtx    context.Context
+	cancelJob context.CancelFunc
+	wg        sync.WaitGroup
+
+	// running holds the cancel functions of jobs executing in this process
+	runningMu sync.Mutex
+	running   map[uint]context.CancelCauseFunc
+}
+
+var (
+	errJobCancelled         = errors.New("job cancelled")
+	errJobTimedOut          = errors.New("job timed out")
+	errArtifactsUnavailable = errors.New("job artifacts are not available")
+)
+
+// NewPool creates a worker pool; call Start to begin processing. files may be
+// nil, in which case handlers cannot attach artifacts.
+func NewPool(queue store.JobQueue, jobService *service.JobService, files *service.FileService, registry *Registry, cfg config.WorkerConfig) *Pool {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+	if len(cfg.Queues) == 0 {
+		cfg.Queues = map[string]int{service.DefaultQueue: 1}
+	}
+	if cfg.VisibilityTimeout <= 0 {
+		cfg.VisibilityTimeout = 5 * time.Minute
+	}
+
+	stopCtx, stop := context.WithCancel(context.Background())
+	jobCtx, cancelJob := context.WithCancel(context.Background())
+
+	return &Pool{
+		queue:      queue,
+		jobService: jobService,
+		files:      files,
+		registry:   registry,
+		cfg:        cfg,
+		stopCtx:    stopCtx,
+		stop:       stop,
+		jobCtx:     jobCtx,
+		cancelJob:  cancelJob,
+		running:    make(map[uint]context.CancelCauseFunc),
+	}
+}
+
+// Start launches the workers for every configured queue
+func (p *Pool) Start() {
+	for queueName, concurrency := range p.cfg.Queues {
+		if concurrency <= 0 {
+			continue
+		}
+		for i := 0; i < concurrency; i++ {
+			p.wg.Add(1)
+			go p.runWorker(queueName, i)
+		}
+		log.Printf("Job worker started %d worker(s) for queue %q", concurrency, queueName)
+	}
+
+	p.wg.Add(1)
+	go p.runScheduler()
+
+	// Not part of wg: cancellations must keep working while Stop drains jobs
+	go p.watchCancellations()
+
+	if p.cfg.Reliable {
+		p.wg.Add(1)
+		go p.runReaper()
+	}
+}
+
+// Stop stops dequeuing new jobs and waits for running jobs to finish. If ctx
+// expires first, running jobs are cancelled and put back on their queue.
+func (p *Pool) Stop(ctx context.Context) error {
+	p.stop()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		p.cancelJob()
+		return nil
+	case <-ctx.Done():
+		p.cancelJob()
+		<-done
+		return fmt.Errorf("worker pool stopped before running jobs finished: %w", ctx.Err())
+	}
+}
+
+func (p *Pool) runWorker(queueName string, id int) {
+	defer p.wg.Done()
+
+	for {
+		select {
+		case <-p.stopCtx.Done():
+			return
+		default:
+		}
+
+		queued, err := p.dequeue(queueName)
+		if err != nil {
+			if !errors.Is(err, store.ErrQueueEmpty) {
+				log.Printf("Job worker %s#%d failed to dequeue: %v", queueName, id, err)
+				p.sleep(p.cfg.PollInterval)
+			}
+			continue
+		}
+
+		p.process(queueName, queued)
+	}
+}
+
+// dequeue fetches the next job using the configured delivery mode
+func (p *Pool) dequeue(queueName string) (*store.QueuedJob, error) {
+	// Skip jobs of users and job types at their concurrency limit so they do
+	// not hold up everyone else's jobs
+	filter, err := p.jobService.ReserveFilter()
+	if err != nil {
+		log.Printf("Job worker failed to check concurrency limits: %v", err)
+	}
+
+	if !p.cfg.Reliable {
+		queued, err := p.queue.DequeueBlocking(queueName, p.cfg.PollInterval)
+		if err != nil || !filter.Skips(queued) {
+			return queued, err
+		}
+		// A popped job cannot be skipped in place, so push it back with the
+		// same delay StartJob uses for jobs over the limit
+		queued.RunAt = time.Now().Add(limitRetryDelay)
+		if err := p.queue.Enqueue(queueName, *queued); err != nil {
+			log.Printf("Job worker dropped job %d: %v", queued.JobID, err)
+		}
+		return nil, store.ErrQueueEmpty
+	}
+
+	queued, err := p.queue.Reserve(queueName, p.cfg.VisibilityTimeout, filter)
+	if errors.Is(err, store.ErrQueueEmpty) {
+		p.sleep(p.cfg.PollInterval)
+	}
+	return queued, err
+}
+
+// ack marks a job as finished so it is not redelivered
+func (p *Pool) ack(queueName string, queued *store.QueuedJob) {
+	if !p.cfg.Reliable {
+		return
+	}
+	if err := p.queue.Ack(queueName, queued); err != nil {
+		log.Printf("Job worker failed to ack job %d: %v", queued.JobID, err)
+	}
+}
+
+// deadLetter moves a job straight to the dead-letter queue
+func (p *Pool) deadLetter(queueName string, queued *store.QueuedJob, errMsg string) {
+	p.ack(queueName, queued)
+	queued.LastError = errMsg
+	if err := p.queue.MoveToDeadLetter(queueName, *queued); err != nil {
+		log.Printf("Job worker failed to dead-letter job %d: %v", queued.JobID, err)
+	}
+}
+
+// retry schedules another attempt; ErrMaxRetriesExceeded means none are left
+// and the job was moved to the dead-letter queue
+func (p *Pool) retry(queueName string, queued *store.QueuedJob) error {
+	if p.cfg.Reliable {
+		return p.queue.Nack(queueName, queued)
+	}
+	return p.queue.RequeueJob(queueName, *queued)
+}
+
+// release puts a job back on the queue without counting the attempt
+func (p *Pool) release(queueName string, queued *store.QueuedJob) error {
+	if p.cfg.Reliable {
+		return p.queue.Release(queueName, queued)
+	}
+	return p.queue.Enqueue(queueName, *queued)
+}
+
+// limitRetryDelay is how long a job that hit a concurrency limit waits
+// before it is picked up again
+const limitRetryDelay = 5 * time.Second
+
+// promoteBatchSize limits how many delayed jobs are promoted per round trip
+const promoteBatchSize = 100
+
+// runScheduler periodically moves due delayed jobs to their ready queues
+func (p *Pool) runScheduler() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stopCtx.Done():
+			return
+		case <-ticker.C:
+			for queueName := range p.cfg.Queues {
+				p.promote(queueName)
+			}
+		}
+	}
+}
+
+func (p *Pool) promote(queueName string) {
+	for {
+		promoted, err := p.queue.PromoteDue(queueName, promoteBatchSize)
+		if err != nil {
+			log.Printf("Job scheduler failed for queue %q: %v", queueName, err)
+			return
+		}
+		if promoted < promoteBatchSize {
+			return
+		}
+	}
+}
+
+// runReaper periodically returns jobs with expired leases to their queues
+func (p *Pool) runReaper() {
+	defer p.wg.Done()
+
+	interval := p.cfg.ReapInterval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stopCtx.Done():
+			return
+		case <-ticker.C:
+			for queueName := range p.cfg.Queues {
+				p.reap(queueName)
+			}
+		}
+	}
+}
+
+func (p *Pool) reap(queueName string) {
+	reaped, err := p.queue.ReapExpired(queueName, func(queued store.QueuedJob, exhausted bool) {
+		job, err := p.jobService.GetJob(queued.JobID)
+		if err != nil || job == nil {
+			return
+		}
+		if exhausted {
+			p.saveState(job, p.jobService.FailJob(job, fmt.Sprintf("job lease expired after %d attempt(s)", queued.RetryCount+1)))
+			return
+		}
+		p.saveState(job, p.jobService.RetryJob(job, "job lease expired, requeued"))
+	})
+	if err != nil {
+		log.Printf("Job reaper failed for queue %q: %v", queueName, err)
+	}
+	if reaped > 0 {
+		log.Printf("Job reaper returned %d expired job(s) on queue %q", reaped, queueName)
+	}
+}
+
+// watchCancellations aborts running jobs when they are cancelled, on this
+// or any other replica
+func (p *Pool) watchCancellations() {
+	events, err := p.jobService.SubscribeCancellations(p.jobCtx)
+	if err != nil {
+		log.Printf("Job worker cannot receive cancellations, running jobs will finish: %v", err)
+		return
+	}
+
+	for event := range events {
+		p.runningMu.Lock()
+		cancel, ok := p.running[event.JobID]
+		p.runningMu.Unlock()
+		if ok {
+			log.Printf("Job worker cancelling job %d", event.JobID)
+			cancel(errJobCancelled)
+		}
+	}
+}
+
+// track registers a running job so it can be cancelled
+func (p *Pool) track(jobID uint, cancel context.CancelCauseFunc) func() {
+	p.runningMu.Lock()
+	p.running[jobID] = cancel
+	p.runningMu.Unlock()
+
+	return func() {
+		p.runningMu.Lock()
+		delete(p.running, jobID)
+		p.runningMu.Unlock()
+		cancel(nil)
+	}
+}
+
+// timeout returns the execution limit for a job type; config overrides the
+// timeout given at registration
+func (p *Pool) timeout(jobType string) time.Duration {
+	if timeout, ok := p.cfg.Timeouts[jobType]; ok {
+		return timeout
+	}
+	if timeout := p.registry.Timeout(jobType); timeout > 0 {
+		return timeout
+	}
+	return p.cfg.DefaultTimeout
+}
+
+// sleep waits for d or until the pool is stopped
+func (p *Pool) sleep(d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-p.stopCtx.Done():
+	}
+}
+
+func (p *Pool) process(queueName string, queued *store.QueuedJob) {
+	job, err := p.jobService.GetJob(queued.JobID)
+	if err != nil {
+		log.Printf("Job worker failed to load job %d: %v", queued.JobID, err)
+		if err := p.release(queueName, queued); err != nil {
+			log.Printf("Job worker dropped job %d: %v", queued.JobID, err)
+		}
+		return
+	}
+	if job == nil {
+		log.Printf("Job worker dropped queue entry %s: job %d no longer exists", queued.ID, queued.JobID)
+		p.ack(queueName, queued)
+		return
+	}
+	// A running job can only be delivered again after its lease expired
+	if job.Status != models.JobStatusPending && job.Status != models.JobStatusRunning {
+		log.Printf("Job worker skipped job %d with status %q", job.ID, job.Status)
+		p.ack(queueName, queued)
+		return
+	}
+
+	handler, ok := p.registry.Handler(job.JobType)
+	if !ok {
+		// Park the job so it can be replayed once a handler is deployed
+		errMsg := fmt.Sprintf("no handler registered for job type %q", job.JobType)
+		p.deadLetter(queueName, queued, errMsg)
+		p.saveState(job, p.jobService.FailJob(job, errMsg))
+		return
+	}
+
+	if err := p.jobService.StartJob(job); err != nil {
+		if errors.Is(err, service.ErrJobNotActive) {
+			// Cancelled between loading and starting
+			log.Printf("Job worker skipped job %d: %v", job.ID, err)
+			p.ack(queueName, queued)
+			return
+		}
+		if errors.Is(err, service.ErrConcurrencyLimit) {
+			// Another worker started a job for the same user or type first
+			queued.RunAt = time.Now().Add(limitRetryDelay)
+			if err := p.release(queueName, queued); err != nil {
+				log.Printf("Job worker dropped job %d: %v", job.ID, err)
+			}
+			return
+		}
+		log.Printf("Job worker failed to mark job %d running: %v", job.ID, err)
+		if err := p.release(queueName, queued); err != nil {
+			log.Printf("Job worker dropped job %d: %v", job.ID, err)
+		}
+		return
+	}
+
+	task := &Task{
+		Job:     job,
+		Payload: queued.Payload,
+		pool:    p,
+	}
+	if task.Payload == nil && job.Payload != "" {
+		if err := json.Unmarshal([]byte(job.Payload), &task.Payload); err != nil {
+			p.saveState(job, p.jobService.FailJob(job, fmt.Sprintf("invalid job payload: %v", err)))
+			p.ack(queueName, queued)
+			return
+		}
+	}
+
+	ctx, cancel := context.WithCancelCause(p.jobCtx)
+	untrack := p.track(job.ID, cancel)
+	timeout := p.timeout(job.JobType)
+	if timeout > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeoutCause(ctx, timeout, errJobTimedOut)
+		defer cancelTimeout()
+	}
+
+	stopHeartbeat := p.heartbeat(queueName, queued)
+	result, runErr := p.execute(ctx, handler, task)
+	stopHeartbeat()
+	cause := context.Cause(ctx)
+	untrack()
+
+	// The row was already marked cancelled by JobService.CancelJob
+	if errors.Is(cause, errJobCancelled) {
+		log.Printf("Job worker stopped cancelled job %d", job.ID)
+		p.ack(queueName, queued)
+		return
+	}
+
+	if runErr == nil {
+		p.saveState(job, p.jobService.CompleteJob(job, result))
+		p.ack(queueName, queued)
+		return
+	}
+
+	// Aborted by shutdown: put the job back without counting the attempt
+	if p.jobCtx.Err() != nil {
+		if err := p.release(queueName, queued); err != nil && !errors.Is(err, store.ErrLeaseLost) {
+			p.saveState(job, p.jobService.FailJob(job, fmt.Sprintf("interrupted by shutdown and could not be requeued: %v", err)))
+			return
+		}
+		p.saveState(job, p.jobService.RetryJob(job, "interrupted by shutdown"))
+		return
+	}
+
+	if errors.Is(cause, errJobTimedOut) {
+		p.saveState(job, p.jobService.FailJob(job, fmt.Sprintf("job timed out after %s", timeout)))
+		p.ack(queueName, queued)
+		return
+	}
+
+	queued.LastError = runErr.Error()
+	if err := p.retry(queueName, queued); err != nil {
+		if errors.Is(err, store.ErrLeaseLost) {
+			// The reaper already requeued the job and updated its row
+			log.Printf("Job worker lost lease on job %d: %v", job.ID, runErr)
+			return
+		}
+		p.saveState(job, p.jobService.FailJob(job, runErr.Error()))
+		return
+	}
+	p.saveState(job, p.jobService.RetryJob(job, runErr.Error()))
+}
+
+// heartbeat keeps extending the lease of a reliable job while it runs
+func (p *Pool) heartbeat(queueName string, queued *store.QueuedJob) func() {
+	if !p.cfg.Reliable {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(p.cfg.VisibilityTimeout / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := p.queue.ExtendLease(queueName, queued, p.cfg.VisibilityTimeout); err != nil {
+					log.Printf("Job worker failed to extend lease on job %d: %v", queued.JobID, err)
+				}
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+// execute runs the handler, converting panics into errors
+func (p *Pool) execute(ctx context.Context, handler HandlerFunc, task *Task) (result string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Job worker recovered from panic in job %d: %v\n%s", task.Job.ID, r, debug.Stack())
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+
+	return handler(ctx, task)
+}
+
+func (p *Pool) saveState(job *models.Job, err error) {
+	if errors.Is(err, service.ErrJobNotActive) {
+		log.Printf("Job worker left job %d unchanged: %v", job.ID, err)
+		return
+	}
+	if err != nil {
+		log.Printf("Job worker failed to update job %d: %v", job.ID, err)
+	}
+}
@@ -0,0 +1,110 @@
+package worker
+
+import (
+	"context"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"go-vibe-friend/internal/models"
+)
+
+// HandlerFunc executes a job and returns the text stored in models.Job.Result.
+// Returning an error marks the attempt as failed; the job is retried until
+// its MaxRetries are exhausted. ctx is cancelled when the job is cancelled,
+// times out or the pool shuts down, and handlers should return promptly.
+type HandlerFunc func(ctx context.Context, task *Task) (string, error)
+
+// Task is the unit of work handed to a HandlerFunc
+type Task struct {
+	Job     *models.Job
+	Payload map[string]interface{}
+
+	pool *Pool
+}
+
+// SetProgress reports job progress (0-100) back to the Job row
+func (t *Task) SetProgress(progress int) error {
+	return t.pool.jobService.UpdateProgress(t.Job, progress)
+}
+
+// AttachArtifact uploads an output file of the job to object storage under
+// jobs/<id>/ and links it to the Job row so the job owner can download it.
+// Attaching the same name again, e.g. on a retry, replaces the earlier file.
+// Pass -1 as size when it is not known in advance.
+func (t *Task) AttachArtifact(ctx context.Context, name, contentType string, r io.Reader, size int64) (*models.File, error) {
+	if t.pool.files == nil {
+		return nil, errArtifactsUnavailable
+	}
+	return t.pool.files.SaveJobArtifact(ctx, t.Job, name, contentType, r, size)
+}
+
+// HandlerOption customizes how a registered handler is run
+type HandlerOption func(*registration)
+
+// WithTimeout limits how long a single attempt of the job may run. Jobs that
+// exceed it are failed without retry. The worker.timeouts config overrides it.
+func WithTimeout(timeout time.Duration) HandlerOption {
+	return func(r *registration) {
+		r.timeout = timeout
+	}
+}
+
+type registration struct {
+	handler HandlerFunc
+	timeout time.Duration
+}
+
+// Registry maps job types to their handlers
+type Registry struct {
+	mu       sync.RWMutex
+	handlers map[string]registration
+}
+
+// NewRegistry creates an empty handler registry
+func NewRegistry() *Registry {
+	return &Registry{
+		handlers: make(map[string]registration),
+	}
+}
+
+// Register binds a handler to a job type, replacing any previous handler
+func (r *Registry) Register(jobType string, handler HandlerFunc, opts ...HandlerOption) {
+	reg := registration{handler: handler}
+	for _, opt := range opts {
+		opt(&reg)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[jobType] = reg
+}
+
+// Handler returns the handler registered for a job type
+func (r *Registry) Handler(jobType string) (HandlerFunc, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	reg, ok := r.handlers[jobType]
+	return reg.handler, ok
+}
+
+// Timeout returns the timeout registered for a job type, or 0 for none
+func (r *Registry) Timeout(jobType string) time.Duration {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.handlers[jobType].timeout
+}
+
+// JobTypes returns all registered job types in sorted order
+func (r *Registry) JobTypes() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	types := make([]string, 0, len(r.handlers))
+	for jobType := range r.handlers {
+		types = append(types, jobType)
+	}
+	sort.Strings(types)
+	return types
+}
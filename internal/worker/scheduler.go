@@ -0,0 +1,139 @@
+package worker
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"go-vibe-friend/internal/config"
+	"go-vibe-friend/internal/service"
+	"go-vibe-friend/internal/store"
+)
+
+// schedulerLockName is the Redis lock held by the replica that fires schedules
+const schedulerLockName = "scheduler"
+
+// Scheduler periodically submits due recurring jobs. When several replicas
+// run, the one holding the Redis leader lock fires the schedules; if Redis is
+// unavailable every replica ticks and the database claim in
+// ScheduleService.RunDueSchedules still ensures each run is submitted once.
+type Scheduler struct {
+	schedules *service.ScheduleService
+	lock      *store.RedisLockService
+	cfg       config.SchedulerConfig
+	owner     string
+
+	leader bool
+	stop   context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewScheduler creates a scheduler; lock may be nil when Redis is not available
+func NewScheduler(schedules *service.ScheduleService, lock *store.RedisLockService, cfg config.SchedulerConfig) *Scheduler {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 15 * time.Second
+	}
+	if cfg.LockTTL < 2*cfg.Interval {
+		cfg.LockTTL = 3 * cfg.Interval
+	}
+
+	return &Scheduler{
+		schedules: schedules,
+		lock:      lock,
+		cfg:       cfg,
+		owner:     newOwnerID(),
+	}
+}
+
+// Start begins checking for due schedules
+func (s *Scheduler) Start() {
+	ctx, stop := context.WithCancel(context.Background())
+	s.stop = stop
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(s.cfg.Interval)
+		defer ticker.Stop()
+
+		s.tick()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.tick()
+			}
+		}
+	}()
+}
+
+// Stop stops the scheduler and hands over leadership
+func (s *Scheduler) Stop() {
+	if s.stop == nil {
+		return
+	}
+	s.stop()
+	s.wg.Wait()
+
+	if s.lock != nil && s.leader {
+		if err := s.lock.Release(schedulerLockName, s.owner); err != nil {
+			log.Printf("Scheduler failed to release leader lock: %v", err)
+		}
+	}
+}
+
+func (s *Scheduler) tick() {
+	if !s.acquireLeadership() {
+		return
+	}
+
+	submitted, err := s.schedules.RunDueSchedules(time.Now())
+	if err != nil {
+		log.Printf("Scheduler failed to run due schedules: %v", err)
+	}
+	if submitted > 0 {
+		log.Printf("Scheduler submitted %d recurring job(s)", submitted)
+	}
+}
+
+// acquireLeadership takes or renews the leader lock. Without Redis every
+// replica acts as leader and relies on the database claim.
+func (s *Scheduler) acquireLeadership() bool {
+	if s.lock == nil {
+		return true
+	}
+
+	held, err := s.lock.Acquire(schedulerLockName, s.owner, s.cfg.LockTTL)
+	if err != nil {
+		log.Printf("Scheduler leader lock unavailable, falling back to database claims: %v", err)
+		s.leader = false
+		return true
+	}
+	if held != s.leader {
+		if held {
+			log.Printf("Scheduler %s became leader", s.owner)
+		} else {
+			log.Printf("Scheduler %s lost leadership", s.owner)
+		}
+	}
+	s.leader = held
+	return held
+}
+
+// newOwnerID identifies this process as a lock holder
+func newOwnerID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(b))
+}